@@ -976,12 +976,63 @@ func toAdminAPIErr(ctx context.Context, err error) APIError {
 				Description:    err.Error(),
 				HTTPStatusCode: http.StatusForbidden,
 			}
+		case errors.Is(err, errIAMEntryExists):
+			apiErr = APIError{
+				Code:           "XMinioIAMEntryExists",
+				Description:    err.Error(),
+				HTTPStatusCode: http.StatusConflict,
+			}
+		case errors.Is(err, errIAMSecretLookupDisabled):
+			apiErr = APIError{
+				Code:           "XMinioIAMSecretLookupDisabled",
+				Description:    err.Error(),
+				HTTPStatusCode: http.StatusForbidden,
+			}
+		case errors.Is(err, errIAMLockTimeout):
+			apiErr = APIError{
+				Code:           "XMinioIAMLockTimeout",
+				Description:    err.Error(),
+				HTTPStatusCode: http.StatusServiceUnavailable,
+			}
+		case errors.Is(err, errPolicyTooManyStatements):
+			apiErr = APIError{
+				Code:           "XMinioIAMPolicyTooManyStatements",
+				Description:    err.Error(),
+				HTTPStatusCode: http.StatusBadRequest,
+			}
+		case errors.Is(err, errSTSAccessKeyConflict):
+			apiErr = APIError{
+				Code:           "XMinioIAMAccessKeyConflict",
+				Description:    err.Error(),
+				HTTPStatusCode: http.StatusConflict,
+			}
+		case errors.Is(err, errPolicyInUse):
+			apiErr = APIError{
+				Code:           "XMinioIAMPolicyInUse",
+				Description:    err.Error(),
+				HTTPStatusCode: http.StatusConflict,
+			}
+		case errors.Is(err, errSTSRateLimited):
+			apiErr = APIError{
+				Code:           "XMinioIAMSTSRateLimited",
+				Description:    err.Error(),
+				HTTPStatusCode: http.StatusTooManyRequests,
+			}
+		case errors.Is(err, errIAMReadOnly):
+			apiErr = APIError{
+				Code:           "XMinioIAMReadOnly",
+				Description:    err.Error(),
+				HTTPStatusCode: http.StatusForbidden,
+			}
 		case errors.Is(err, errIAMNotInitialized):
 			apiErr = APIError{
 				Code:           "XMinioIAMNotInitialized",
 				Description:    err.Error(),
 				HTTPStatusCode: http.StatusServiceUnavailable,
 			}
+		case errors.Is(err, errInvalidArgument):
+			// Keep the description of wrapped errors.
+			apiErr = errorCodes.ToAPIErrWithErr(ErrAdminInvalidArgument, err)
 		case errors.Is(err, crypto.ErrKESKeyExists):
 			apiErr = APIError{
 				Code:           "XMinioKMSKeyExists",
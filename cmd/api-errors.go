@@ -365,6 +365,7 @@ const (
 	ErrAccountNotEligible
 	ErrAdminServiceAccountNotFound
 	ErrPostPolicyConditionInvalidFormat
+	ErrTooManyBuckets
 )
 
 type errorCodeMap map[APIErrorCode]APIError
@@ -1755,6 +1756,11 @@ var errorCodes = errorCodeMap{
 		Description:    "Invalid according to Policy: Policy Condition failed",
 		HTTPStatusCode: http.StatusForbidden,
 	},
+	ErrTooManyBuckets: {
+		Code:           "TooManyBuckets",
+		Description:    "You have attempted to create more buckets than allowed",
+		HTTPStatusCode: http.StatusBadRequest,
+	},
 	// Add your error structure here.
 }
 
@@ -1781,14 +1787,24 @@ func toAPIErrorCode(ctx context.Context, err error) (apiErr APIErrorCode) {
 		apiErr = ErrAdminInvalidArgument
 	case errNoSuchUser:
 		apiErr = ErrAdminNoSuchUser
+	case errInvalidSecret:
+		apiErr = ErrSignatureDoesNotMatch
+	case errExpired, errAccountDisabled:
+		apiErr = ErrAccessDenied
 	case errNoSuchServiceAccount:
 		apiErr = ErrAdminServiceAccountNotFound
 	case errNoSuchGroup:
 		apiErr = ErrAdminNoSuchGroup
 	case errGroupNotEmpty:
 		apiErr = ErrAdminGroupNotEmpty
+	case errUserBucketQuotaExceeded:
+		apiErr = ErrTooManyBuckets
 	case errNoSuchPolicy:
 		apiErr = ErrAdminNoSuchPolicy
+	case errPolicyVersionConflict, errLastPolicyMapping:
+		apiErr = ErrPreconditionFailed
+	case errIAMTemporarilyUnavailable:
+		apiErr = ErrSlowDown
 	case errSignatureMismatch:
 		apiErr = ErrSignatureDoesNotMatch
 	case errInvalidRange:
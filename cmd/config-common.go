@@ -64,19 +64,26 @@ func deleteConfig(ctx context.Context, objAPI objectDeleter, configFile string)
 }
 
 func saveConfig(ctx context.Context, objAPI ObjectLayer, configFile string, data []byte) error {
+	_, err := saveConfigWithObjectInfo(ctx, objAPI, configFile, data)
+	return err
+}
+
+// saveConfigWithObjectInfo - same as saveConfig, additionally returns
+// the object info of the saved config file.
+func saveConfigWithObjectInfo(ctx context.Context, objAPI ObjectLayer, configFile string, data []byte) (ObjectInfo, error) {
 	hashReader, err := hash.NewReader(bytes.NewReader(data), int64(len(data)), "", getSHA256Hash(data), int64(len(data)))
 	if err != nil {
-		return err
+		return ObjectInfo{}, err
 	}
 
-	_, err = objAPI.PutObject(ctx, MinioMetaBucket, configFile, NewPutObjReader(hashReader), ObjectOptions{MaxParity: true})
+	oi, err := objAPI.PutObject(ctx, MinioMetaBucket, configFile, NewPutObjReader(hashReader), ObjectOptions{MaxParity: true})
 	if errors.As(err, &BucketNotFound{}) {
 		if err := objAPI.MakeBucketWithLocation(ctx, MinioMetaBucket, BucketOptions{}); err != nil {
-			return err
+			return ObjectInfo{}, err
 		}
-		_, err = objAPI.PutObject(ctx, MinioMetaBucket, configFile, NewPutObjReader(hashReader), ObjectOptions{})
+		oi, err = objAPI.PutObject(ctx, MinioMetaBucket, configFile, NewPutObjReader(hashReader), ObjectOptions{})
 	}
-	return err
+	return oi, err
 }
 
 func checkConfig(ctx context.Context, objAPI ObjectLayer, configFile string) error {
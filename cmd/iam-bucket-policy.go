@@ -0,0 +1,140 @@
+/*
+ * MinIO Cloud Storage, (C) 2021 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"github.com/minio/minio/pkg/bucket/policy"
+	iampolicy "github.com/minio/minio/pkg/iam/policy"
+)
+
+// BucketPolicyProvider - supplies the resource policies IsAllowed
+// evaluates along with identity policies, e.g. globalPolicySys.
+type BucketPolicyProvider interface {
+	// Get returns the policy of bucket, or an error such as
+	// BucketPolicyNotFound when there is none.
+	Get(bucket string) (*policy.Policy, error)
+}
+
+// bucketPolicyProviderHolder - keeps the provider in an atomic.Value,
+// which requires the same concrete type on every store.
+type bucketPolicyProviderHolder struct {
+	provider BucketPolicyProvider
+}
+
+// SetBucketPolicyProvider - makes IsAllowed evaluate the policies of
+// the target bucket like AWS does: an explicit deny in either the
+// identity or the bucket policy denies the request, otherwise an allow
+// in either of them suffices. A nil provider restores identity-only
+// evaluation. Decisions of OPA and of the owner are not affected.
+func (sys *IAMSys) SetBucketPolicyProvider(provider BucketPolicyProvider) {
+	sys.bucketPolicyProvider.Store(&bucketPolicyProviderHolder{provider: provider})
+}
+
+func (sys *IAMSys) getBucketPolicyProvider() BucketPolicyProvider {
+	h, _ := sys.bucketPolicyProvider.Load().(*bucketPolicyProviderHolder)
+	if h == nil {
+		return nil
+	}
+	return h.provider
+}
+
+// isAllowedWithBucketPolicy - same as isAllowed, combined with the
+// policy of the target bucket when a provider is set. The returned
+// policies are those of the identity when they allowed the request.
+func (sys *IAMSys) isAllowedWithBucketPolicy(args iampolicy.Args) (bool, []string) {
+	allowed, policies := sys.isAllowed(args)
+
+	provider := sys.getBucketPolicyProvider()
+	if provider == nil || globalPolicyOPA != nil || args.IsOwner || args.BucketName == "" {
+		return allowed, policies
+	}
+	bp, err := provider.Get(args.BucketName)
+	if err != nil || bp == nil {
+		return allowed, policies
+	}
+
+	bargs := policy.Args{
+		AccountName:     args.AccountName,
+		Groups:          args.Groups,
+		Action:          policy.Action(args.Action),
+		BucketName:      args.BucketName,
+		ConditionValues: args.ConditionValues,
+		ObjectName:      args.ObjectName,
+	}
+	for _, statement := range bp.Statements {
+		if statement.Effect == policy.Deny && !statement.IsAllowed(bargs) {
+			// Explicit deny by the bucket policy.
+			return false, nil
+		}
+	}
+	if allowed || args.DenyOnly {
+		return allowed, policies
+	}
+
+	// The identity policies didn't allow the request, the bucket
+	// policy may still do so for a valid account they don't
+	// explicitly deny it to.
+	if !sys.isValidAccount(args.AccountName) || !sys.mfaSatisfied(args) || sys.identityDenies(args) {
+		return false, nil
+	}
+	return bp.IsAllowed(bargs), nil
+}
+
+// isValidAccount - reports whether the account, and the user it
+// belongs to if known, exist and are enabled.
+func (sys *IAMSys) isValidAccount(accountName string) bool {
+	sys.Lock()
+	defer sys.Unlock()
+
+	cred, ok := sys.iamUsersMap[accountName]
+	if !ok || !cred.IsValid() {
+		return false
+	}
+	if parent, ok := sys.iamUsersMap[cred.ParentUser]; ok && !parent.IsValid() {
+		return false
+	}
+	return true
+}
+
+// identityDenies - reports whether a Deny statement of the identity
+// policies of the account matches args, on a best effort basis as the
+// session policies of service accounts are not considered.
+func (sys *IAMSys) identityDenies(args iampolicy.Args) bool {
+	policies := sys.evaluatedPolicies(args)
+	if err := sys.loadLazyPolicies(policies...); err != nil {
+		// Deny when the policies cannot be checked.
+		return true
+	}
+
+	sys.Lock()
+	defer sys.Unlock()
+
+	username := args.AccountName
+	if cred, ok := sys.iamUsersMap[args.AccountName]; ok && cred.ParentUser != "" {
+		username = cred.ParentUser
+	}
+	args = withUsername(args, username)
+	for _, name := range policies {
+		p, _ := sys.policyDoc(name)
+		for _, statement := range p.Statements {
+			if statement.Effect == policy.Deny && !statement.IsAllowed(args) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,85 @@
+/*
+ * MinIO Cloud Storage, (C) 2021 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+// IAMObjectType - type of the IAM object reported by an IAMChangeEvent.
+type IAMObjectType string
+
+// IAM object types.
+const (
+	IAMObjectUser          IAMObjectType = "user"
+	IAMObjectGroup         IAMObjectType = "group"
+	IAMObjectPolicy        IAMObjectType = "policy"
+	IAMObjectPolicyMapping IAMObjectType = "mapping"
+)
+
+// IAMChangeAction - kind of change reported by an IAMChangeEvent.
+type IAMChangeAction string
+
+// IAM change actions.
+const (
+	IAMChangeCreate IAMChangeAction = "create"
+	IAMChangeUpdate IAMChangeAction = "update"
+	IAMChangeDelete IAMChangeAction = "delete"
+)
+
+// IAMChangeEvent - describes a change of an IAM object applied to the
+// in-memory state of the server.
+type IAMChangeEvent struct {
+	ObjectType IAMObjectType
+	Name       string
+	Action     IAMChangeAction
+	// Set for policy mappings of groups.
+	IsGroup bool
+}
+
+// RegisterChangeHook - registers a function called after changes of
+// users, groups, policies and policy mappings are loaded from the
+// store, including objects found deleted on reload, and after users
+// and policies are deleted by this server. Hooks are called synchronously without holding any IAM locks,
+// slow hooks delay further loads.
+func (sys *IAMSys) RegisterChangeHook(hook func(ev IAMChangeEvent)) {
+	if hook == nil {
+		return
+	}
+
+	sys.Lock()
+	defer sys.Unlock()
+
+	sys.changeHooks = append(sys.changeHooks, hook)
+}
+
+// notifyChange - calls all registered hooks with the given event.
+// IMPORTANT: Must not be called with sys.Lock held.
+func (sys *IAMSys) notifyChange(ev IAMChangeEvent) {
+	sys.Lock()
+	hooks := sys.changeHooks
+	sys.Unlock()
+
+	for _, hook := range hooks {
+		hook(ev)
+	}
+}
+
+// changeAction - returns the action for an object which existed before
+// the change or not.
+func changeAction(existed bool) IAMChangeAction {
+	if existed {
+		return IAMChangeUpdate
+	}
+	return IAMChangeCreate
+}
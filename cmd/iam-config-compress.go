@@ -0,0 +1,72 @@
+/*
+ * MinIO Cloud Storage, (C) 2021 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+)
+
+// Compressed IAM config items start with this header followed by the
+// gzip stream of their JSON, and are encrypted like any other item
+// afterwards. Plain JSON never starts with a NUL byte, and the header
+// is long enough for encrypted items not to start with it by chance,
+// so compressed and uncompressed items can coexist in the store.
+//
+// Only canned policies are compressed, see envIAMCompressPolicies.
+// Policies listing many per-tenant resources compress very well, the
+// 100 statement policy of BenchmarkIAMPolicyParseCompressed is stored
+// in under 1KiB instead of ~17.5KiB, and decompressing adds ~2% to the
+// time of parsing it. Compare with BenchmarkIAMPolicyParse.
+//
+// Servers predating compression cannot read compressed items. During a
+// rolling upgrade compression must stay off until all servers run a
+// release supporting it, and before downgrading it must be turned off
+// and the compressed policies set again to store them uncompressed.
+var iamCompressedConfigHeader = []byte{0x00, 'I', 'A', 'M', 'G', 'Z', 0x00, 0x01}
+
+// compressIAMConfig - returns data compressed with the header.
+func compressIAMConfig(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.Write(iamCompressedConfigHeader)
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// isCompressedIAMConfig - reports whether data starts with the header
+// of compressed IAM config items.
+func isCompressedIAMConfig(data []byte) bool {
+	return bytes.HasPrefix(data, iamCompressedConfigHeader)
+}
+
+// decompressIAMConfig - returns the JSON of a compressed IAM config
+// item.
+func decompressIAMConfig(data []byte) ([]byte, error) {
+	r, err := gzip.NewReader(bytes.NewReader(data[len(iamCompressedConfigHeader):]))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return ioutil.ReadAll(r)
+}
@@ -0,0 +1,98 @@
+/*
+ * MinIO Cloud Storage, (C) 2021 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	iampolicy "github.com/minio/minio/pkg/iam/policy"
+)
+
+// testTenantsPolicy - returns a policy with one statement for each of
+// n tenant buckets.
+func testTenantsPolicy(n int) []byte {
+	var sb strings.Builder
+	sb.WriteString(`{"Version":"2012-10-17","Statement":[`)
+	for i := 0; i < n; i++ {
+		if i > 0 {
+			sb.WriteString(",")
+		}
+		fmt.Fprintf(&sb, `{"Sid":"tenant%03d","Effect":"Allow","Action":["s3:GetObject","s3:PutObject","s3:DeleteObject","s3:ListBucket"],"Resource":["arn:aws:s3:::tenant-%03d","arn:aws:s3:::tenant-%03d/*"]}`, i, i, i)
+	}
+	sb.WriteString("]}")
+	return []byte(sb.String())
+}
+
+func TestIAMConfigCompress(t *testing.T) {
+	data := testTenantsPolicy(100)
+	if isCompressedIAMConfig(data) {
+		t.Fatal("expected plain JSON not to be detected as compressed")
+	}
+
+	compressed, err := compressIAMConfig(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !isCompressedIAMConfig(compressed) {
+		t.Fatal("expected the compressed item to be detected as such")
+	}
+	if len(compressed) >= len(data)/10 {
+		t.Fatalf("expected %d bytes to compress to less than a tenth, got %d bytes", len(data), len(compressed))
+	}
+
+	decompressed, err := decompressIAMConfig(compressed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(decompressed, data) {
+		t.Fatal("expected the decompressed item to equal the original")
+	}
+}
+
+func BenchmarkIAMPolicyParse(b *testing.B) {
+	data := testTenantsPolicy(100)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := iampolicy.ParseConfig(bytes.NewReader(data)); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.ReportMetric(float64(len(data)), "stored-bytes")
+}
+
+func BenchmarkIAMPolicyParseCompressed(b *testing.B) {
+	compressed, err := compressIAMConfig(testTenantsPolicy(100))
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		data, err := decompressIAMConfig(compressed)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if _, err = iampolicy.ParseConfig(bytes.NewReader(data)); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.ReportMetric(float64(len(compressed)), "stored-bytes")
+}
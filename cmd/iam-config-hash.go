@@ -0,0 +1,114 @@
+/*
+ * MinIO Cloud Storage, (C) 2021 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"sync"
+)
+
+// iamConfigHashes - hashes identifying the content of the IAM config
+// items a store last saved, or loaded with loadIAMConfigIfChanged, by
+// path. The zero value is ready to use.
+type iamConfigHashes struct {
+	mu sync.Mutex
+	m  map[string]string
+}
+
+// iamConfigHash - returns the content hash of the plain JSON of an
+// IAM config item.
+func iamConfigHash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func (h *iamConfigHashes) get(path string) string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.m[path]
+}
+
+func (h *iamConfigHashes) set(path, hash string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.m == nil {
+		h.m = make(map[string]string)
+	}
+	h.m[path] = hash
+}
+
+func (h *iamConfigHashes) delete(path string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.m, path)
+}
+
+// loadIfChanged - decodes data into item unless its hash is the one
+// recorded for path, in which case errIAMConfigNotModified is
+// returned.
+func (h *iamConfigHashes) loadIfChanged(data []byte, item interface{}, path string) error {
+	hash := iamConfigHash(data)
+	if hash == h.get(path) {
+		return errIAMConfigNotModified
+	}
+	if err := json.Unmarshal(data, item); err != nil {
+		return iamConfigParseError{Path: path, Err: err}
+	}
+	h.set(path, hash)
+	return nil
+}
+
+// getPolicyDocIfChanged - same as getPolicyDoc, unless the policy is
+// unchanged since the store last saved or loaded it.
+func getPolicyDocIfChanged(ctx context.Context, store IAMStorageAPI, policy string) (PolicyDoc, error) {
+	var data json.RawMessage
+	if err := store.loadIAMConfigIfChanged(ctx, &data, getPolicyDocPath(policy)); err != nil {
+		if errors.Is(err, errConfigNotFound) {
+			return PolicyDoc{}, errNoSuchPolicy
+		}
+		return PolicyDoc{}, err
+	}
+	var d PolicyDoc
+	if err := d.parseJSON(data); err != nil {
+		return PolicyDoc{}, iamConfigParseError{Path: getPolicyDocPath(policy), Err: err}
+	}
+	return d, nil
+}
+
+// getUserIdentityIfChanged - same as getUserIdentity, unless the
+// identity is unchanged since the store last saved or loaded it.
+func getUserIdentityIfChanged(ctx context.Context, store IAMStorageAPI, user string, userType IAMUserType) (UserIdentity, error) {
+	var u UserIdentity
+	if err := store.loadIAMConfigIfChanged(ctx, &u, getUserIdentityPath(user, userType)); err != nil {
+		if errors.Is(err, errConfigNotFound) {
+			return UserIdentity{}, errNoSuchUser
+		}
+		return UserIdentity{}, err
+	}
+	if u.Credentials.IsExpired() {
+		// Let getUserIdentity purge it.
+		return store.getUserIdentity(ctx, user, userType)
+	}
+	if u.Credentials.AccessKey == "" {
+		u.Credentials.AccessKey = user
+	}
+	return u, nil
+}
@@ -0,0 +1,223 @@
+/*
+ * MinIO Cloud Storage, (C) 2021 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"time"
+
+	"github.com/minio/minio-go/v7/pkg/set"
+	"github.com/minio/minio/pkg/auth"
+	iampolicy "github.com/minio/minio/pkg/iam/policy"
+)
+
+// IAMConsistencyDiff - differences between the store and memory for
+// one type of IAM object, by name.
+type IAMConsistencyDiff struct {
+	// Present in the store but not in memory.
+	Missing []string `json:"missing,omitempty"`
+	// Present in memory but no longer in the store.
+	Stale []string `json:"stale,omitempty"`
+	// Present in both with different contents.
+	Modified []string `json:"modified,omitempty"`
+}
+
+// Empty - reports whether there are no differences.
+func (d IAMConsistencyDiff) Empty() bool {
+	return len(d.Missing) == 0 && len(d.Stale) == 0 && len(d.Modified) == 0
+}
+
+// IAMConsistencyReport - outcome of VerifyConsistency.
+type IAMConsistencyReport struct {
+	Time time.Time `json:"time"`
+
+	Policies            IAMConsistencyDiff `json:"policies"`
+	Users               IAMConsistencyDiff `json:"users"`
+	Groups              IAMConsistencyDiff `json:"groups"`
+	UserPolicyMappings  IAMConsistencyDiff `json:"userPolicyMappings"`
+	GroupPolicyMappings IAMConsistencyDiff `json:"groupPolicyMappings"`
+}
+
+// Consistent - reports whether memory matches the store.
+func (r IAMConsistencyReport) Consistent() bool {
+	return r.Policies.Empty() && r.Users.Empty() && r.Groups.Empty() &&
+		r.UserPolicyMappings.Empty() && r.GroupPolicyMappings.Empty()
+}
+
+// VerifyConsistency - loads the IAM sub-system from the store into
+// temporary maps and compares them with the in-memory state, which is
+// left untouched. Expired credentials are ignored on both sides.
+func (sys *IAMSys) VerifyConsistency(ctx context.Context) (IAMConsistencyReport, error) {
+	if !sys.Initialized() {
+		return IAMConsistencyReport{}, errServerNotInitialized
+	}
+
+	<-sys.configLoaded
+
+	report := IAMConsistencyReport{Time: UTCNow()}
+
+	iamUsersMap := make(map[string]auth.Credentials)
+	iamGroupsMap := make(map[string]GroupInfo)
+	iamUserPolicyMap := make(map[string]MappedPolicy)
+	iamGroupPolicyMap := make(map[string]MappedPolicy)
+	iamPolicyDocsMap := make(map[string]iampolicy.Policy)
+
+	// Skip prefixes whose bucket is not found, like Load does.
+	check := func(err error) error {
+		if err != nil && !errors.As(err, &BucketNotFound{}) {
+			return err
+		}
+		return nil
+	}
+
+	if err := func() error {
+		if err := sys.rlockStore(); err != nil {
+			return err
+		}
+		defer sys.store.runlock()
+
+		if err := check(sys.store.loadPolicyDocs(ctx, iamPolicyDocsMap)); err != nil {
+			return err
+		}
+		sys.rewriteAllResources(iamPolicyDocsMap)
+		setDefaultCannedPolicies(iamPolicyDocsMap)
+
+		if sys.usersSysType == MinIOUsersSysType {
+			if err := check(sys.store.loadUsers(ctx, regularUser, iamUsersMap)); err != nil {
+				return err
+			}
+			if err := check(sys.store.loadGroups(ctx, iamGroupsMap)); err != nil {
+				return err
+			}
+		}
+		if err := check(sys.store.loadMappedPolicies(ctx, regularUser, false, iamUserPolicyMap)); err != nil {
+			return err
+		}
+		if err := check(sys.store.loadMappedPolicies(ctx, regularUser, true, iamGroupPolicyMap)); err != nil {
+			return err
+		}
+		if err := check(sys.store.loadUsers(ctx, srvAccUser, iamUsersMap)); err != nil {
+			return err
+		}
+		if err := check(sys.store.loadUsers(ctx, stsUser, iamUsersMap)); err != nil {
+			return err
+		}
+		return check(sys.store.loadMappedPolicies(ctx, stsUser, false, iamUserPolicyMap))
+	}(); err != nil {
+		return IAMConsistencyReport{}, err
+	}
+
+	for k, v := range iamUsersMap {
+		if v.IsExpired() {
+			delete(iamUsersMap, k)
+			delete(iamUserPolicyMap, k)
+		}
+	}
+
+	if err := sys.loadLazyPolicyDocs(); err != nil {
+		return IAMConsistencyReport{}, err
+	}
+
+	sys.Lock()
+	defer sys.Unlock()
+
+	liveUsersMap := make(map[string]auth.Credentials, len(sys.iamUsersMap))
+	for k, v := range sys.iamUsersMap {
+		if !v.IsExpired() {
+			liveUsersMap[k] = v
+		}
+	}
+
+	policyNames := func(m map[string]iampolicy.Policy) set.StringSet {
+		s := set.NewStringSet()
+		for k := range m {
+			s.Add(k)
+		}
+		return s
+	}
+	report.Policies = diffIAMNames(policyNames(iamPolicyDocsMap), policyNames(sys.iamPolicyDocsMap), func(name string) bool {
+		return reflect.DeepEqual(iamPolicyDocsMap[name], sys.iamPolicyDocsMap[name])
+	})
+
+	userNames := func(m map[string]auth.Credentials) set.StringSet {
+		s := set.NewStringSet()
+		for k := range m {
+			s.Add(k)
+		}
+		return s
+	}
+	report.Users = diffIAMNames(userNames(iamUsersMap), userNames(liveUsersMap), func(name string) bool {
+		return equalStoredCredentials(iamUsersMap[name], liveUsersMap[name])
+	})
+
+	groupNames := func(m map[string]GroupInfo) set.StringSet {
+		s := set.NewStringSet()
+		for k := range m {
+			s.Add(k)
+		}
+		return s
+	}
+	report.Groups = diffIAMNames(groupNames(iamGroupsMap), groupNames(sys.iamGroupsMap), func(name string) bool {
+		a, b := iamGroupsMap[name], sys.iamGroupsMap[name]
+		return a.Status == b.Status && equalGroupMembers(a.Members, b.Members)
+	})
+
+	mappingNames := func(m map[string]MappedPolicy) set.StringSet {
+		s := set.NewStringSet()
+		for k := range m {
+			s.Add(k)
+		}
+		return s
+	}
+	report.UserPolicyMappings = diffIAMNames(mappingNames(iamUserPolicyMap), mappingNames(sys.iamUserPolicyMap), func(name string) bool {
+		return iamUserPolicyMap[name].policySet().Equals(sys.iamUserPolicyMap[name].policySet())
+	})
+	report.GroupPolicyMappings = diffIAMNames(mappingNames(iamGroupPolicyMap), mappingNames(sys.iamGroupPolicyMap), func(name string) bool {
+		return iamGroupPolicyMap[name].policySet().Equals(sys.iamGroupPolicyMap[name].policySet())
+	})
+
+	return report, nil
+}
+
+// equalStoredCredentials - compares the fields of two credentials
+// which are persisted in the store.
+func equalStoredCredentials(a, b auth.Credentials) bool {
+	return a.AccessKey == b.AccessKey &&
+		a.SecretKey == b.SecretKey &&
+		a.SessionToken == b.SessionToken &&
+		a.Status == b.Status &&
+		a.ParentUser == b.ParentUser &&
+		a.Expiration.Equal(b.Expiration)
+}
+
+// diffIAMNames - compares the names found in the store with the live
+// ones, equal is only called for names present on both sides.
+func diffIAMNames(stored, live set.StringSet, equal func(name string) bool) IAMConsistencyDiff {
+	var d IAMConsistencyDiff
+	for _, name := range stored.ToSlice() {
+		switch {
+		case !live.Contains(name):
+			d.Missing = append(d.Missing, name)
+		case !equal(name):
+			d.Modified = append(d.Modified, name)
+		}
+	}
+	d.Stale = live.Difference(stored).ToSlice()
+	return d
+}
@@ -0,0 +1,92 @@
+/*
+ * MinIO Cloud Storage, (C) 2021 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"context"
+
+	"github.com/minio/minio/cmd/logger"
+)
+
+// IAMCredentialType - type of the credentials reported by an
+// IAMCredentialEvent.
+type IAMCredentialType string
+
+// IAM credential types.
+const (
+	IAMCredentialUser           IAMCredentialType = "user"
+	IAMCredentialServiceAccount IAMCredentialType = "service-account"
+	IAMCredentialSTS            IAMCredentialType = "sts"
+)
+
+// IAMCredentialAction - lifecycle step reported by an IAMCredentialEvent.
+type IAMCredentialAction string
+
+// IAM credential actions.
+const (
+	IAMCredentialCreated IAMCredentialAction = "created"
+	IAMCredentialDeleted IAMCredentialAction = "deleted"
+)
+
+// IAMCredentialEvent - describes credentials which were created or
+// deleted on this server. It never carries any secret.
+type IAMCredentialEvent struct {
+	Action    IAMCredentialAction
+	Type      IAMCredentialType
+	AccessKey string
+	// Parent user of service accounts and STS credentials.
+	ParentUser string
+	// Access key of the requester, when known.
+	Actor string
+}
+
+// RegisterCredentialHook - registers a function called once credentials
+// were created or deleted through this server and the change was
+// persisted. Hooks are called synchronously without holding any IAM
+// locks.
+func (sys *IAMSys) RegisterCredentialHook(hook func(ev IAMCredentialEvent)) {
+	if hook == nil {
+		return
+	}
+
+	sys.Lock()
+	defer sys.Unlock()
+
+	sys.credentialHooks = append(sys.credentialHooks, hook)
+}
+
+// notifyCredential - calls all registered credential hooks with the
+// given event, the actor is taken from the request info of ctx.
+// IMPORTANT: Must not be called with sys.Lock held.
+func (sys *IAMSys) notifyCredential(ctx context.Context, ev IAMCredentialEvent) {
+	sys.Lock()
+	hooks := sys.credentialHooks
+	sys.Unlock()
+
+	if len(hooks) == 0 {
+		return
+	}
+
+	if ev.Actor == "" {
+		if reqInfo := logger.GetReqInfo(ctx); reqInfo != nil {
+			ev.Actor = reqInfo.AccessKey
+		}
+	}
+	for _, hook := range hooks {
+		hook(ev)
+	}
+}
@@ -0,0 +1,105 @@
+/*
+ * MinIO Cloud Storage, (C) 2021 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// CredentialPolicy - organizational rules for the access and secret
+// keys chosen by callers of CreateUser, NewServiceAccount and
+// SetUserSecretKey, checked on top of the built-in minimum lengths.
+// Generated keys are not subject to it. The zero value adds no rules.
+type CredentialPolicy struct {
+	MinAccessKeyLength int
+	MinSecretKeyLength int
+	// Characters allowed in access and secret keys, empty allows
+	// any character.
+	AccessKeyCharset string
+	SecretKeyCharset string
+	// Prefix required of new access keys.
+	AccessKeyPrefix string
+	// Minimum number of character classes, out of lowercase and
+	// uppercase letters, digits and others, in secret keys.
+	MinSecretKeyClasses int
+}
+
+// SetCredentialPolicy - replaces the policy new access and secret keys
+// must comply with. Existing credentials are not checked.
+func (sys *IAMSys) SetCredentialPolicy(p CredentialPolicy) {
+	sys.credentialPolicy.Store(p)
+}
+
+// getCredentialPolicy - returns the current credential policy.
+func (sys *IAMSys) getCredentialPolicy() CredentialPolicy {
+	p, _ := sys.credentialPolicy.Load().(CredentialPolicy)
+	return p
+}
+
+// validateAccessKey - checks a new access key against the policy.
+func (p CredentialPolicy) validateAccessKey(accessKey string) error {
+	if len(accessKey) < p.MinAccessKeyLength {
+		return fmt.Errorf("access key must be at least %d characters long: %w", p.MinAccessKeyLength, errInvalidArgument)
+	}
+	if !strings.HasPrefix(accessKey, p.AccessKeyPrefix) {
+		return fmt.Errorf("access key must start with %q: %w", p.AccessKeyPrefix, errInvalidArgument)
+	}
+	if i := strings.IndexFunc(accessKey, notInCharset(p.AccessKeyCharset)); i >= 0 {
+		return fmt.Errorf("access key must not contain %q: %w", accessKey[i:i+1], errInvalidArgument)
+	}
+	return nil
+}
+
+// validateSecretKey - checks a new secret key against the policy, the
+// secret key is never part of the returned error.
+func (p CredentialPolicy) validateSecretKey(secretKey string) error {
+	if len(secretKey) < p.MinSecretKeyLength {
+		return fmt.Errorf("secret key must be at least %d characters long: %w", p.MinSecretKeyLength, errInvalidArgument)
+	}
+	if strings.IndexFunc(secretKey, notInCharset(p.SecretKeyCharset)) >= 0 {
+		return fmt.Errorf("secret key must only contain characters of %q: %w", p.SecretKeyCharset, errInvalidArgument)
+	}
+	if p.MinSecretKeyClasses > 0 {
+		var lower, upper, digit, other int
+		for _, r := range secretKey {
+			switch {
+			case unicode.IsLower(r):
+				lower = 1
+			case unicode.IsUpper(r):
+				upper = 1
+			case unicode.IsDigit(r):
+				digit = 1
+			default:
+				other = 1
+			}
+		}
+		if lower+upper+digit+other < p.MinSecretKeyClasses {
+			return fmt.Errorf("secret key must mix at least %d of lowercase and uppercase letters, digits and other characters: %w", p.MinSecretKeyClasses, errInvalidArgument)
+		}
+	}
+	return nil
+}
+
+// notInCharset - returns a function reporting whether a character is
+// outside of charset, which never does for an empty charset.
+func notInCharset(charset string) func(r rune) bool {
+	return func(r rune) bool {
+		return charset != "" && !strings.ContainsRune(charset, r)
+	}
+}
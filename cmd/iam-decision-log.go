@@ -0,0 +1,122 @@
+/*
+ * MinIO Cloud Storage, (C) 2021 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/minio/minio/pkg/bucket/policy"
+	iampolicy "github.com/minio/minio/pkg/iam/policy"
+)
+
+// IAMDecision - an authorization decision made by IsAllowed.
+type IAMDecision struct {
+	Time        time.Time     `json:"time"`
+	AccountName string        `json:"accountName"`
+	Action      string        `json:"action"`
+	Resource    string        `json:"resource"`
+	Effect      policy.Effect `json:"effect"`
+	// Canned policies evaluated for the account, empty for the
+	// owner and when OPA decides.
+	Policies []string `json:"policies,omitempty"`
+}
+
+// IAMDecisionSampling - selects the decisions passed to the decision
+// logger. A rate of N logs one in N decisions, 0 logs none.
+type IAMDecisionSampling struct {
+	AllowRate uint64
+	DenyRate  uint64
+}
+
+type iamDecisionLogger struct {
+	// Keep the counters first to ensure 64-bit alignment.
+	allows uint64
+	denies uint64
+
+	sampling IAMDecisionSampling
+	fn       func(IAMDecision)
+}
+
+// sampled - counts the decision and reports whether it is to be logged.
+func (l *iamDecisionLogger) sampled(allowed bool) bool {
+	if allowed {
+		return l.sampling.AllowRate > 0 && atomic.AddUint64(&l.allows, 1)%l.sampling.AllowRate == 0
+	}
+	return l.sampling.DenyRate > 0 && atomic.AddUint64(&l.denies, 1)%l.sampling.DenyRate == 0
+}
+
+// SetDecisionLogger - registers fn to be called with the decisions of
+// IsAllowed selected by sampling, replacing any previous logger. A nil
+// fn disables decision logging. fn is called synchronously on the
+// request path without holding any IAM locks.
+func (sys *IAMSys) SetDecisionLogger(fn func(IAMDecision), sampling IAMDecisionSampling) {
+	if fn == nil {
+		sys.decisionLogger.Store((*iamDecisionLogger)(nil))
+		return
+	}
+	sys.decisionLogger.Store(&iamDecisionLogger{sampling: sampling, fn: fn})
+}
+
+// logDecision - passes the decision for args to the decision logger, if
+// any and if it is sampled.
+func (sys *IAMSys) logDecision(args iampolicy.Args, allowed bool) {
+	l, _ := sys.decisionLogger.Load().(*iamDecisionLogger)
+	if l == nil || !l.sampled(allowed) {
+		return
+	}
+
+	resource := args.BucketName
+	if args.ObjectName != "" {
+		resource += SlashSeparator + args.ObjectName
+	}
+	d := IAMDecision{
+		Time:        UTCNow(),
+		AccountName: args.AccountName,
+		Action:      string(args.Action),
+		Resource:    iampolicy.ResourceARNPrefix + resource,
+		Effect:      policy.Deny,
+	}
+	if allowed {
+		d.Effect = policy.Allow
+	}
+	if globalPolicyOPA == nil && !args.IsOwner {
+		d.Policies = sys.evaluatedPolicies(args)
+	}
+	l.fn(d)
+}
+
+// evaluatedPolicies - returns the canned policies IsAllowed evaluates
+// for the account of args, on a best effort basis.
+func (sys *IAMSys) evaluatedPolicies(args iampolicy.Args) []string {
+	if ok, parentUser, err := sys.IsTempUser(args.AccountName); err == nil && ok {
+		if sys.usersSysType == LDAPUsersSysType {
+			policies, _ := sys.PolicyDBGet(parentUser, false, args.Groups...)
+			return policies
+		}
+		if ps, found := args.GetPolicies(iamPolicyClaimNameOpenID()); found {
+			return ps.ToSlice()
+		}
+		return nil
+	}
+	if ok, parentUser, err := sys.IsServiceAccount(args.AccountName); err == nil && ok {
+		policies, _ := sys.PolicyDBGet(parentUser, false, args.Groups...)
+		return policies
+	}
+	policies, _ := sys.PolicyDBGet(args.AccountName, false, args.Groups...)
+	return policies
+}
@@ -0,0 +1,675 @@
+/*
+ * MinIO Cloud Storage, (C) 2021 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/minio/minio-go/v7/pkg/set"
+	"github.com/minio/minio/pkg/auth"
+	iampolicy "github.com/minio/minio/pkg/iam/policy"
+)
+
+const (
+	// File inside an IAM export archive describing the archive.
+	iamExportFormatFile = "iam-export.json"
+
+	iamExportFormatVersion1 = 1
+)
+
+type iamExportFormat struct {
+	Version int `json:"version"`
+}
+
+// maxIAMImportSize - maximum size of an IAM export archive accepted by
+// ImportIAM, the whole archive is read into memory.
+const maxIAMImportSize = 64 << 20
+
+// iamExportPath - an item of an IAM export archive, identified by the
+// store prefix of its type and its name.
+type iamExportPath struct {
+	prefix string
+	name   string
+	// store path of the item, rebuilt from prefix and name.
+	path string
+}
+
+// parseIAMExportPath - parses the path of an IAM export archive entry.
+// Only the paths the store path helpers build for valid names are
+// accepted, anything else could write outside of the IAM prefixes.
+func parseIAMExportPath(p string) (iamExportPath, error) {
+	invalid := fmt.Errorf("unexpected entry %s in IAM export: %w", p, errInvalidArgument)
+
+	var e iamExportPath
+	switch {
+	case strings.HasPrefix(p, iamConfigPolicyDBPrefix):
+		mappings := []struct {
+			prefix   string
+			userType IAMUserType
+			isGroup  bool
+		}{
+			{iamConfigPolicyDBUsersPrefix, regularUser, false},
+			{iamConfigPolicyDBSTSUsersPrefix, stsUser, false},
+			{iamConfigPolicyDBServiceAccountsPrefix, srvAccUser, false},
+			{iamConfigPolicyDBGroupsPrefix, regularUser, true},
+		}
+		for _, m := range mappings {
+			if strings.HasPrefix(p, m.prefix) {
+				e.prefix = m.prefix
+				e.name = strings.TrimSuffix(strings.TrimPrefix(p, m.prefix), ".json")
+				e.path = getMappedPolicyPath(e.name, m.userType, m.isGroup)
+				break
+			}
+		}
+	case strings.HasPrefix(p, iamConfigPoliciesPrefix):
+		e.prefix = iamConfigPoliciesPrefix
+		e.name = strings.TrimSuffix(strings.TrimPrefix(p, e.prefix), SlashSeparator+iamPolicyFile)
+		e.path = getPolicyDocPath(e.name)
+	case strings.HasPrefix(p, iamConfigUsersPrefix):
+		e.prefix = iamConfigUsersPrefix
+		e.name = strings.TrimSuffix(strings.TrimPrefix(p, e.prefix), SlashSeparator+iamIdentityFile)
+		e.path = getUserIdentityPath(e.name, regularUser)
+	case strings.HasPrefix(p, iamConfigServiceAccountsPrefix):
+		e.prefix = iamConfigServiceAccountsPrefix
+		e.name = strings.TrimSuffix(strings.TrimPrefix(p, e.prefix), SlashSeparator+iamIdentityFile)
+		e.path = getUserIdentityPath(e.name, srvAccUser)
+	case strings.HasPrefix(p, iamConfigSTSPrefix):
+		e.prefix = iamConfigSTSPrefix
+		e.name = strings.TrimSuffix(strings.TrimPrefix(p, e.prefix), SlashSeparator+iamIdentityFile)
+		e.path = getUserIdentityPath(e.name, stsUser)
+	case strings.HasPrefix(p, iamConfigGroupsPrefix):
+		e.prefix = iamConfigGroupsPrefix
+		e.name = strings.TrimSuffix(strings.TrimPrefix(p, e.prefix), SlashSeparator+iamGroupMembersFile)
+		e.path = getGroupInfoPath(e.name)
+	}
+
+	if e.name == "" || checkIAMName(e.name) != nil || e.path != p {
+		return iamExportPath{}, invalid
+	}
+	return e, nil
+}
+
+// iamExportEntry - a single item of an IAM export archive, stored at
+// the same path and with the same JSON encoding as in the IAM store.
+type iamExportEntry struct {
+	path string
+	item interface{}
+	ttl  int64
+
+	// reports whether the entry is already present in memory,
+	// must be called with sys.Lock held.
+	exists func() bool
+}
+
+// ExportIAM - serializes all policies, users, groups, service accounts
+// and policy mappings into a zip archive which can be restored with
+// ImportIAM. Built-in canned policies which were not modified are not
+// exported.
+func (sys *IAMSys) ExportIAM(ctx context.Context) (io.Reader, error) {
+	if !sys.Initialized() {
+		return nil, errServerNotInitialized
+	}
+
+	// The resources of in-memory policies may be rewritten, export the
+	// stored ones instead.
+	var policies map[string]iampolicy.Policy
+	if rewrite, _ := sys.resourceRewriter.Load().(IAMResourceRewriter); rewrite != nil {
+		policies = make(map[string]iampolicy.Policy)
+		if err := sys.rlockStore(); err != nil {
+			return nil, err
+		}
+		err := sys.store.loadPolicyDocs(ctx, policies)
+		sys.store.runlock()
+		if err != nil {
+			return nil, err
+		}
+		setDefaultCannedPolicies(policies)
+	}
+
+	if policies == nil {
+		if err := sys.loadLazyPolicyDocs(); err != nil {
+			return nil, err
+		}
+	}
+
+	sys.Lock()
+	if policies == nil {
+		policies = sys.iamPolicyDocsMap
+	}
+	entries, err := sys.exportEntries(policies)
+	sys.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	writeJSON := func(name string, item interface{}) error {
+		data, err := json.Marshal(item)
+		if err != nil {
+			return err
+		}
+		w, err := zw.CreateHeader(&zip.FileHeader{
+			Name:     name,
+			Method:   zip.Deflate,
+			Modified: UTCNow(),
+		})
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(data)
+		return err
+	}
+
+	if err = writeJSON(iamExportFormatFile, iamExportFormat{Version: iamExportFormatVersion1}); err != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		if err = ctx.Err(); err != nil {
+			return nil, err
+		}
+		if err = writeJSON(e.path, e.item); err != nil {
+			return nil, err
+		}
+	}
+	if err = zw.Close(); err != nil {
+		return nil, err
+	}
+	return &buf, nil
+}
+
+// exportEntries - collects all the entries to export from the given
+// policies and the in-memory maps.
+// IMPORTANT: Assumes that sys.Lock is held by caller.
+func (sys *IAMSys) exportEntries(policies map[string]iampolicy.Policy) ([]iamExportEntry, error) {
+	var entries []iamExportEntry
+
+	defaults := make(map[string]iampolicy.Policy)
+	setDefaultCannedPolicies(defaults)
+	for name, p := range policies {
+		if d, ok := defaults[name]; ok && p.Equals(d) {
+			continue
+		}
+		entries = append(entries, iamExportEntry{path: getPolicyDocPath(name), item: p})
+	}
+
+	for name, cred := range sys.iamUsersMap {
+		userType := regularUser
+		switch {
+		case cred.IsTemp():
+			userType = stsUser
+		case cred.IsServiceAccount():
+			userType = srvAccUser
+		}
+		u := newUserIdentity(cred)
+		u.MFARequired = sys.iamMFARequired.Contains(name)
+		u.Tags = sys.iamUserTags[name]
+		entries = append(entries, iamExportEntry{path: getUserIdentityPath(name, userType), item: u})
+	}
+
+	for name, gi := range sys.iamGroupsMap {
+		entries = append(entries, iamExportEntry{path: getGroupInfoPath(name), item: gi})
+	}
+
+	for name, mp := range sys.iamUserPolicyMap {
+		userType := regularUser
+		if sys.iamUsersMap[name].IsTemp() {
+			userType = stsUser
+		}
+		entries = append(entries, iamExportEntry{path: getMappedPolicyPath(name, userType, false), item: mp})
+	}
+
+	for name, mp := range sys.iamGroupPolicyMap {
+		entries = append(entries, iamExportEntry{path: getMappedPolicyPath(name, regularUser, true), item: mp})
+	}
+
+	return entries, nil
+}
+
+// ImportIAM - restores an archive created by ExportIAM. All entries are
+// written through the store under a single lock and IAM is reloaded
+// afterwards. Entries which already exist are rejected unless overwrite
+// is set, STS credentials which expired in the meantime are skipped
+// along with their policy mappings.
+func (sys *IAMSys) ImportIAM(ctx context.Context, r io.Reader, overwrite bool) error {
+	if !sys.Initialized() {
+		return errServerNotInitialized
+	}
+
+	if sys.readOnly {
+		return errIAMReadOnly
+	}
+
+	data, err := ioutil.ReadAll(io.LimitReader(r, maxIAMImportSize+1))
+	if err != nil {
+		return err
+	}
+	if len(data) > maxIAMImportSize {
+		return fmt.Errorf("IAM export exceeds %d bytes: %w", maxIAMImportSize, errInvalidArgument)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return err
+	}
+
+	readJSON := func(f *zip.File, item interface{}) error {
+		rc, err := f.Open()
+		if err != nil {
+			return err
+		}
+		defer rc.Close()
+		return json.NewDecoder(rc).Decode(item)
+	}
+
+	var format iamExportFormat
+	for _, f := range zr.File {
+		if f.Name == iamExportFormatFile {
+			if err = readJSON(f, &format); err != nil {
+				return err
+			}
+			break
+		}
+	}
+	if format.Version != iamExportFormatVersion1 {
+		return fmt.Errorf("unsupported IAM export format version %d: %w", format.Version, errInvalidArgument)
+	}
+
+	var entries, stsMappings []iamExportEntry
+	expiredSTS := set.NewStringSet()
+	stsExpiry := make(map[string]time.Time)
+	for _, f := range zr.File {
+		if f.Name == iamExportFormatFile {
+			continue
+		}
+		var ep iamExportPath
+		if ep, err = parseIAMExportPath(f.Name); err != nil {
+			return err
+		}
+		name := ep.name
+		switch ep.prefix {
+		case iamConfigPolicyDBUsersPrefix, iamConfigPolicyDBSTSUsersPrefix,
+			iamConfigPolicyDBServiceAccountsPrefix, iamConfigPolicyDBGroupsPrefix:
+			var mp MappedPolicy
+			if err = readJSON(f, &mp); err != nil {
+				return err
+			}
+			e := iamExportEntry{path: ep.path, item: mp}
+			switch ep.prefix {
+			case iamConfigPolicyDBGroupsPrefix:
+				e.exists = func() bool { _, ok := sys.iamGroupPolicyMap[name]; return ok }
+			default:
+				e.exists = func() bool { _, ok := sys.iamUserPolicyMap[name]; return ok }
+			}
+			if ep.prefix == iamConfigPolicyDBSTSUsersPrefix {
+				stsMappings = append(stsMappings, e)
+				continue
+			}
+			entries = append(entries, e)
+		case iamConfigPoliciesPrefix:
+			var p iampolicy.Policy
+			if err = readJSON(f, &p); err != nil {
+				return err
+			}
+			if err = sys.validatePolicy(p); err != nil {
+				return err
+			}
+			entries = append(entries, iamExportEntry{path: ep.path, item: p, exists: func() bool {
+				_, ok := sys.iamPolicyDocsMap[name]
+				return ok
+			}})
+		case iamConfigUsersPrefix, iamConfigServiceAccountsPrefix, iamConfigSTSPrefix:
+			var u UserIdentity
+			if err = readJSON(f, &u); err != nil {
+				return err
+			}
+			e := iamExportEntry{path: ep.path, item: u, exists: func() bool {
+				_, ok := sys.iamUsersMap[name]
+				return ok
+			}}
+			if u.Credentials.IsExpired() {
+				if ep.prefix == iamConfigSTSPrefix {
+					expiredSTS.Add(name)
+				}
+				continue
+			}
+			if ep.prefix == iamConfigSTSPrefix {
+				stsExpiry[name] = u.Credentials.Expiration
+				e.ttl = int64(u.Credentials.Expiration.Sub(UTCNow()).Seconds())
+			}
+			entries = append(entries, e)
+		case iamConfigGroupsPrefix:
+			var gi GroupInfo
+			if err = readJSON(f, &gi); err != nil {
+				return err
+			}
+			entries = append(entries, iamExportEntry{path: ep.path, item: gi.normalize(), exists: func() bool {
+				_, ok := sys.iamGroupsMap[name]
+				return ok
+			}})
+		}
+	}
+
+	for _, e := range stsMappings {
+		name := strings.TrimSuffix(path.Base(e.path), ".json")
+		if expiredSTS.Contains(name) {
+			continue
+		}
+		if exp, ok := stsExpiry[name]; ok {
+			e.ttl = int64(exp.Sub(UTCNow()).Seconds())
+		}
+		entries = append(entries, e)
+	}
+
+	if err = sys.lockStore(); err != nil {
+		return err
+	}
+	if !overwrite {
+		sys.Lock()
+		for _, e := range entries {
+			if e.exists() {
+				sys.Unlock()
+				sys.store.unlock()
+				return fmt.Errorf("%w: %s", errIAMEntryExists, e.path)
+			}
+		}
+		sys.Unlock()
+	}
+
+	for _, e := range entries {
+		var opts []options
+		if e.ttl > 0 {
+			opts = append(opts, options{ttl: e.ttl})
+		}
+		if err = sys.store.saveIAMConfig(ctx, e.item, e.path, opts...); err != nil {
+			sys.store.unlock()
+			return err
+		}
+	}
+	sys.store.unlock()
+
+	return sys.store.loadAll(ctx, sys)
+}
+
+// userExportVersion1 - version of UserExport.
+const userExportVersion1 = 1
+
+// redactedSecret - replaces secrets in exports without secrets.
+const redactedSecret = "*REDACTED*"
+
+// UserExport - the complete IAM configuration of a single user, as
+// returned by ExportUser.
+type UserExport struct {
+	Version  int          `json:"version"`
+	Identity UserIdentity `json:"identity"`
+	// Policies mapped to the user.
+	Policies []string `json:"policies,omitempty"`
+	// Groups the user is a member of.
+	Groups []string `json:"groups,omitempty"`
+	// Policies mapped to the groups of the user, by group.
+	GroupPolicies map[string][]string `json:"groupPolicies,omitempty"`
+	// Documents of all the policies above which exist.
+	PolicyDocs map[string]iampolicy.Policy `json:"policyDocs,omitempty"`
+
+	ServiceAccounts []UserExportCredential `json:"serviceAccounts,omitempty"`
+	STSUsers        []UserExportCredential `json:"stsUsers,omitempty"`
+}
+
+// UserExportCredential - a service account or temporary user of a user
+// in a UserExport.
+type UserExportCredential struct {
+	Identity UserIdentity `json:"identity"`
+	// Policies mapped to temporary users.
+	Policies []string `json:"policies,omitempty"`
+	// Policy embedded in service accounts.
+	EmbeddedPolicy *iampolicy.Policy `json:"embeddedPolicy,omitempty"`
+}
+
+// exportUserOpts - options of ExportUserWithOpts.
+type exportUserOpts struct {
+	// include secret keys and session tokens instead of redacting
+	// them.
+	withSecrets bool
+}
+
+// ExportUser - returns the IAM configuration of a user along with its
+// service accounts and temporary users, with all secrets redacted.
+func (sys *IAMSys) ExportUser(accessKey string) (UserExport, error) {
+	return sys.ExportUserWithOpts(accessKey, exportUserOpts{})
+}
+
+// ExportUserWithOpts - same as ExportUser with options.
+func (sys *IAMSys) ExportUserWithOpts(accessKey string, opts exportUserOpts) (UserExport, error) {
+	if !sys.Initialized() {
+		return UserExport{}, errServerNotInitialized
+	}
+
+	if sys.usersSysType != MinIOUsersSysType {
+		return UserExport{}, errIAMActionNotAllowed
+	}
+
+	accessKey = sys.normalizeAccessKey(accessKey)
+
+	<-sys.configLoaded
+
+	if err := sys.loadLazyPolicyDocs(); err != nil {
+		return UserExport{}, err
+	}
+
+	sys.Lock()
+	defer sys.Unlock()
+
+	cred, ok := sys.iamUsersMap[accessKey]
+	if !ok || cred.IsTemp() || cred.IsServiceAccount() {
+		return UserExport{}, errNoSuchUser
+	}
+
+	export := UserExport{
+		Version:       userExportVersion1,
+		Identity:      sys.exportIdentity(accessKey, cred, opts),
+		Policies:      sys.iamUserPolicyMap[accessKey].toSlice(),
+		Groups:        sys.iamUserGroupMemberships[accessKey].ToSlice(),
+		GroupPolicies: make(map[string][]string),
+		PolicyDocs:    make(map[string]iampolicy.Policy),
+	}
+
+	policies := set.CreateStringSet(export.Policies...)
+	for _, group := range export.Groups {
+		if ps := sys.iamGroupPolicyMap[group].toSlice(); len(ps) > 0 {
+			export.GroupPolicies[group] = ps
+			for _, p := range ps {
+				policies.Add(p)
+			}
+		}
+	}
+
+	for k, v := range sys.iamUsersMap {
+		if v.ParentUser != accessKey {
+			continue
+		}
+		switch {
+		case v.IsServiceAccount():
+			export.ServiceAccounts = append(export.ServiceAccounts, UserExportCredential{
+				Identity:       sys.exportIdentity(k, v, opts),
+				EmbeddedPolicy: getEmbeddedPolicy(v),
+			})
+		case v.IsTemp():
+			c := UserExportCredential{
+				Identity: sys.exportIdentity(k, v, opts),
+				Policies: sys.iamUserPolicyMap[k].toSlice(),
+			}
+			for _, p := range c.Policies {
+				policies.Add(p)
+			}
+			export.STSUsers = append(export.STSUsers, c)
+		}
+	}
+	sort.Slice(export.ServiceAccounts, func(i, j int) bool {
+		return export.ServiceAccounts[i].Identity.Credentials.AccessKey < export.ServiceAccounts[j].Identity.Credentials.AccessKey
+	})
+	sort.Slice(export.STSUsers, func(i, j int) bool {
+		return export.STSUsers[i].Identity.Credentials.AccessKey < export.STSUsers[j].Identity.Credentials.AccessKey
+	})
+
+	for _, p := range policies.ToSlice() {
+		if doc, ok := sys.policyDoc(p); ok {
+			export.PolicyDocs[p] = doc
+		}
+	}
+
+	return export, nil
+}
+
+// exportIdentity - returns the identity of the given credentials as
+// stored, with its secrets redacted unless requested.
+// IMPORTANT: Assumes that sys.Lock is held by caller.
+func (sys *IAMSys) exportIdentity(accessKey string, cred auth.Credentials, opts exportUserOpts) UserIdentity {
+	u := newUserIdentity(cred)
+	if r, ok := sys.iamSecretRotations[accessKey]; ok {
+		u.PreviousSecretKey = r.previousSecretKey
+		u.RotationExpiry = r.expiry
+	}
+	u.SecretKeys = append([]string(nil), sys.iamAdditionalSecretKeys[accessKey]...)
+	u.MFARequired = sys.iamMFARequired.Contains(accessKey)
+	u.Tags = sys.iamUserTags[accessKey]
+	if opts.withSecrets {
+		return u
+	}
+
+	u.Credentials.SecretKey = redactedSecret
+	if u.Credentials.SessionToken != "" {
+		u.Credentials.SessionToken = redactedSecret
+	}
+	if u.PreviousSecretKey != "" {
+		u.PreviousSecretKey = redactedSecret
+	}
+	for i := range u.SecretKeys {
+		u.SecretKeys[i] = redactedSecret
+	}
+	return u
+}
+
+// Prefix of the pseudo-ARNs of canned policies in AWS style exports.
+const awsPolicyARNPrefix = "arn:minio:iam:::policy/"
+
+// AWSAttachedPolicy - a canned policy attached to a principal, in the
+// shape of AWS IAM's AttachedManagedPolicies entries.
+type AWSAttachedPolicy struct {
+	PolicyName string `json:"PolicyName"`
+	PolicyArn  string `json:"PolicyArn"`
+}
+
+// AWSUserPolicyMappings - policies attached to a user, and the groups
+// the user is a member of.
+type AWSUserPolicyMappings struct {
+	UserName                string              `json:"UserName"`
+	GroupList               []string            `json:"GroupList"`
+	AttachedManagedPolicies []AWSAttachedPolicy `json:"AttachedManagedPolicies"`
+}
+
+// AWSGroupPolicyMappings - policies attached to a group.
+type AWSGroupPolicyMappings struct {
+	GroupName               string              `json:"GroupName"`
+	AttachedManagedPolicies []AWSAttachedPolicy `json:"AttachedManagedPolicies"`
+}
+
+// AWSPolicyMappings - policy mappings in the shape of the user and
+// group lists of AWS IAM's GetAccountAuthorizationDetails.
+type AWSPolicyMappings struct {
+	UserDetailList  []AWSUserPolicyMappings  `json:"UserDetailList"`
+	GroupDetailList []AWSGroupPolicyMappings `json:"GroupDetailList"`
+}
+
+// awsAttachedPolicies - returns the policies of mp as AWS style
+// attachments, sorted by name.
+func awsAttachedPolicies(mp MappedPolicy) []AWSAttachedPolicy {
+	attached := []AWSAttachedPolicy{}
+	for _, name := range mp.policySet().ToSlice() {
+		attached = append(attached, AWSAttachedPolicy{
+			PolicyName: name,
+			PolicyArn:  awsPolicyARNPrefix + name,
+		})
+	}
+	return attached
+}
+
+// ExportPolicyMappingsAWS - serializes the policy mappings of users and
+// groups, along with group memberships, into AWS IAM style JSON where
+// canned policies are referred to by pseudo-ARNs. Mappings of service
+// accounts and STS credentials are not exported.
+func (sys *IAMSys) ExportPolicyMappingsAWS() ([]byte, error) {
+	if !sys.Initialized() {
+		return nil, errServerNotInitialized
+	}
+
+	<-sys.configLoaded
+
+	sys.Lock()
+	users := set.NewStringSet()
+	for user := range sys.iamUserPolicyMap {
+		if cred, ok := sys.iamUsersMap[user]; ok && (cred.IsTemp() || cred.IsServiceAccount()) {
+			continue
+		}
+		users.Add(user)
+	}
+	for user := range sys.iamUserGroupMemberships {
+		if cred, ok := sys.iamUsersMap[user]; ok && (cred.IsTemp() || cred.IsServiceAccount()) {
+			continue
+		}
+		users.Add(user)
+	}
+
+	mappings := AWSPolicyMappings{
+		UserDetailList:  []AWSUserPolicyMappings{},
+		GroupDetailList: []AWSGroupPolicyMappings{},
+	}
+	for _, user := range users.ToSlice() {
+		groups := []string{}
+		if memberOf, ok := sys.iamUserGroupMemberships[user]; ok {
+			groups = append(groups, memberOf.ToSlice()...)
+		}
+		mappings.UserDetailList = append(mappings.UserDetailList, AWSUserPolicyMappings{
+			UserName:                user,
+			GroupList:               groups,
+			AttachedManagedPolicies: awsAttachedPolicies(sys.iamUserPolicyMap[user]),
+		})
+	}
+
+	groups := make([]string, 0, len(sys.iamGroupPolicyMap))
+	for group := range sys.iamGroupPolicyMap {
+		groups = append(groups, group)
+	}
+	sort.Strings(groups)
+	for _, group := range groups {
+		mappings.GroupDetailList = append(mappings.GroupDetailList, AWSGroupPolicyMappings{
+			GroupName:               group,
+			AttachedManagedPolicies: awsAttachedPolicies(sys.iamGroupPolicyMap[group]),
+		})
+	}
+	sys.Unlock()
+
+	return json.MarshalIndent(mappings, "", "  ")
+}
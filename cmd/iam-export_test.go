@@ -0,0 +1,181 @@
+/*
+ * MinIO Cloud Storage, (C) 2021 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/minio/minio/pkg/madmin"
+)
+
+func TestIAMExportImport(t *testing.T) {
+	ctx := context.Background()
+
+	src := newTestIAMSys(t)
+	setTestPolicy(t, src, "tenant", testTenantPolicy)
+
+	var err error
+	if err = src.CreateUser("alice", madmin.UserInfo{
+		SecretKey:  "alicesecretkey",
+		PolicyName: "tenant",
+		Status:     madmin.AccountEnabled,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err = src.AddUsersToGroup("tenants", []string{"alice"}); err != nil {
+		t.Fatal(err)
+	}
+	if err = src.PolicyDBSet("tenants", "tenant", true); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := src.ExportIAM(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dst := newTestIAMSys(t)
+	if err = dst.ImportIAM(ctx, r, false); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = dst.InfoPolicy("tenant"); err != nil {
+		t.Fatalf("policy not imported: %v", err)
+	}
+	u, err := dst.GetUserInfo("alice")
+	if err != nil {
+		t.Fatalf("user not imported: %v", err)
+	}
+	if u.PolicyName != "tenant" || u.Status != madmin.AccountEnabled {
+		t.Fatalf("unexpected user after import: %+v", u)
+	}
+	gd, err := dst.GetGroupDescription("tenants")
+	if err != nil {
+		t.Fatalf("group not imported: %v", err)
+	}
+	if len(gd.Members) != 1 || gd.Members[0] != "alice" || gd.Policy != "tenant" {
+		t.Fatalf("unexpected group after import: %+v", gd)
+	}
+
+	// Importing again without overwrite must not replace anything.
+	if r, err = src.ExportIAM(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err = dst.ImportIAM(ctx, r, false); !errors.Is(err, errIAMEntryExists) {
+		t.Fatalf("expected %v, got %v", errIAMEntryExists, err)
+	}
+}
+
+func TestIAMExportDefaultPolicies(t *testing.T) {
+	sys := newTestIAMSys(t)
+	setTestPolicy(t, sys, "tenant", testTenantPolicy)
+
+	// Unmodified default policies are never exported, whatever the
+	// order their sets are iterated in.
+	for i := 0; i < 20; i++ {
+		sys.Lock()
+		entries, err := sys.exportEntries(sys.iamPolicyDocsMap)
+		sys.Unlock()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, e := range entries {
+			if strings.HasPrefix(e.path, iamConfigPoliciesPrefix) && e.path != getPolicyDocPath("tenant") {
+				t.Fatalf("expected the default policy %s not to be exported", e.path)
+			}
+		}
+	}
+}
+
+func TestIAMImportEntryNames(t *testing.T) {
+	testCases := []struct {
+		name  string
+		valid bool
+	}{
+		{"config/iam/users/alice/identity.json", true},
+		{"config/iam/service-accounts/SVCKEY/identity.json", true},
+		{"config/iam/sts/STSKEY/identity.json", true},
+		{"config/iam/groups/tenants/members.json", true},
+		{"config/iam/policies/tenant/policy.json", true},
+		{"config/iam/policydb/users/alice.json", true},
+		{"config/iam/policydb/sts-users/STSKEY.json", true},
+		{"config/iam/policydb/service-accounts/SVCKEY.json", true},
+		{"config/iam/policydb/groups/tenants.json", true},
+
+		{"config/iam/users/../../x/identity.json", false},
+		{"config/iam/users/../format.json", false},
+		{"config/iam/users/alice/../bob/identity.json", false},
+		{"config/iam/users/a/b/identity.json", false},
+		{"config/iam/users/alice/other.json", false},
+		{"config/iam/users//identity.json", false},
+		{"config/iam/policies/../../../bucket/policy.json", false},
+		{"config/iam/policydb/users/../../format.json", false},
+		{"config/iam/policydb/other/alice.json", false},
+		{"config/iam/format.json", false},
+		{"buckets/bucket/.metadata.bin", false},
+	}
+
+	for i, testCase := range testCases {
+		p, err := parseIAMExportPath(testCase.name)
+		if testCase.valid {
+			if err != nil {
+				t.Fatalf("test %v: unexpected error: %v", i+1, err)
+			}
+			if p.path != testCase.name {
+				t.Fatalf("test %v: expected path %s, got %s", i+1, testCase.name, p.path)
+			}
+			continue
+		}
+		if !errors.Is(err, errInvalidArgument) {
+			t.Fatalf("test %v: expected %v, got %v", i+1, errInvalidArgument, err)
+		}
+	}
+}
+
+func TestIAMImportMaliciousEntry(t *testing.T) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, data := range map[string]string{
+		iamExportFormatFile:                      `{"version":1}`,
+		"config/iam/users/../../x/identity.json": `{"version":1,"credentials":{"accessKey":"x","secretKey":"xsecretkey"}}`,
+	} {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = w.Write([]byte(data)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	sys := newTestIAMSys(t)
+	if err := sys.ImportIAM(context.Background(), &buf, true); !errors.Is(err, errInvalidArgument) {
+		t.Fatalf("expected %v, got %v", errInvalidArgument, err)
+	}
+	var u UserIdentity
+	if err := sys.store.loadIAMConfig(context.Background(), &u, "config/x/identity.json"); err == nil {
+		t.Fatal("malicious entry was written to the store")
+	}
+}
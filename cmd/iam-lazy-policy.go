@@ -0,0 +1,182 @@
+/*
+ * MinIO Cloud Storage, (C) 2021 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/minio/minio-go/v7/pkg/set"
+	"github.com/minio/minio/cmd/logger"
+	iampolicy "github.com/minio/minio/pkg/iam/policy"
+)
+
+// loadPolicyDocsFrom - loads the canned policies of store into m. When
+// lazyPolicyLoad is set only their names are read, mapped to empty
+// policies, and returned to be loaded by policyDoc on first use.
+func (sys *IAMSys) loadPolicyDocsFrom(ctx context.Context, store IAMStorageAPI, m map[string]iampolicy.Policy) (set.StringSet, error) {
+	lazyPolicies := set.NewStringSet()
+	if !sys.lazyPolicyLoad {
+		return lazyPolicies, store.loadPolicyDocs(ctx, m)
+	}
+
+	if err := store.loadPolicyNames(ctx, lazyPolicies); err != nil {
+		return set.NewStringSet(), err
+	}
+	for name := range lazyPolicies {
+		m[name] = iampolicy.Policy{}
+	}
+	return lazyPolicies, nil
+}
+
+// lazyPolicyLoads - reads of lazily loaded policy documents in
+// progress, concurrent requests for the same policy share one read.
+type lazyPolicyLoads struct {
+	mu    sync.Mutex
+	loads map[string]*lazyPolicyLoad
+}
+
+type lazyPolicyLoad struct {
+	done chan struct{}
+	err  error
+}
+
+// do - calls fn unless a call for the same name is in progress, in
+// which case its result is waited for and returned instead.
+func (l *lazyPolicyLoads) do(name string, fn func() error) error {
+	l.mu.Lock()
+	if load, ok := l.loads[name]; ok {
+		l.mu.Unlock()
+		<-load.done
+		return load.err
+	}
+	if l.loads == nil {
+		l.loads = make(map[string]*lazyPolicyLoad)
+	}
+	load := &lazyPolicyLoad{done: make(chan struct{})}
+	l.loads[name] = load
+	l.mu.Unlock()
+
+	load.err = fn()
+
+	l.mu.Lock()
+	delete(l.loads, name)
+	l.mu.Unlock()
+	close(load.done)
+	return load.err
+}
+
+// loadLazyPolicyDoc - reads the document of a policy whose name only
+// was loaded. The store is read under the store read lock, sys.Lock
+// must not be held by the caller.
+func (sys *IAMSys) loadLazyPolicyDoc(name string) error {
+	if err := sys.rlockStore(); err != nil {
+		return err
+	}
+	m := make(map[string]iampolicy.Policy, 1)
+	err := sys.store.loadPolicyDoc(context.Background(), name, m)
+	sys.store.runlock()
+
+	sys.Lock()
+	defer sys.Unlock()
+
+	if !sys.iamLazyPolicies.Contains(name) {
+		// Set, deleted or reloaded meanwhile.
+		return nil
+	}
+	if err == errNoSuchPolicy {
+		// Deleted since the names were loaded.
+		delete(sys.iamPolicyDocsMap, name)
+		sys.iamLazyPolicies.Remove(name)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("unable to load canned policy %s: %w", name, err)
+	}
+	sys.iamPolicyDocsMap[name] = sys.rewriteResources(m[name])
+	sys.iamLazyPolicies.Remove(name)
+	return nil
+}
+
+// loadLazyPolicies - reads the documents of the given policies whose
+// names only were loaded, so that policyDoc finds them, returning the
+// first error. sys.Lock must not be held by the caller.
+func (sys *IAMSys) loadLazyPolicies(names ...string) error {
+	sys.Lock()
+	var pending []string
+	for _, name := range names {
+		if sys.iamLazyPolicies.Contains(name) {
+			pending = append(pending, name)
+		}
+	}
+	sys.Unlock()
+
+	var firstErr error
+	for _, name := range pending {
+		err := sys.lazyPolicyLoads.do(name, func() error {
+			return sys.loadLazyPolicyDoc(name)
+		})
+		if err != nil {
+			logger.LogIf(GlobalContext, err)
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	return firstErr
+}
+
+// loadLazyPolicyDocs - reads the documents of all policies whose names
+// only were loaded, returning the first error. sys.Lock must not be
+// held by the caller.
+func (sys *IAMSys) loadLazyPolicyDocs() error {
+	sys.Lock()
+	names := sys.iamLazyPolicies.ToSlice()
+	sys.Unlock()
+
+	return sys.loadLazyPolicies(names...)
+}
+
+// policyDoc - returns the canned policy with the given name. A policy
+// whose document was not read yet, see loadLazyPolicies, is reported
+// as missing. IMPORTANT: Assumes that sys.Lock is held by caller.
+func (sys *IAMSys) policyDoc(name string) (iampolicy.Policy, bool) {
+	p, ok := sys.iamPolicyDocsMap[name]
+	if !ok || sys.iamLazyPolicies.Contains(name) {
+		return iampolicy.Policy{}, false
+	}
+
+	// Policies are combined and evaluated outside of sys.Lock, limit
+	// the capacity so that appending statements never writes to the
+	// backing array of the stored policy.
+	p.Statements = p.Statements[:len(p.Statements):len(p.Statements)]
+	return p, true
+}
+
+// LoadAllPolicyDocs - reads the documents of all canned policies not
+// loaded yet when MINIO_IAM_LAZY_POLICY_LOAD is on, so that no request
+// waits for them later. It does nothing otherwise.
+func (sys *IAMSys) LoadAllPolicyDocs() error {
+	if !sys.Initialized() {
+		return errServerNotInitialized
+	}
+
+	<-sys.configLoaded
+
+	return sys.loadLazyPolicyDocs()
+}
@@ -0,0 +1,105 @@
+/*
+ * MinIO Cloud Storage, (C) 2021 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// LoadReportFailure - an IAM store path which could not be loaded.
+type LoadReportFailure struct {
+	Path string
+	Err  string
+}
+
+// LoadReport - outcome of a full load of the IAM sub-system from
+// the store. Counts are taken before expired credentials are purged.
+type LoadReport struct {
+	Time time.Time
+
+	Policies            int
+	Users               int
+	ServiceAccounts     int
+	STSUsers            int
+	Groups              int
+	UserPolicyMappings  int
+	GroupPolicyMappings int
+
+	// Paths which failed to load, including prefixes which were
+	// skipped because the bucket was not found.
+	Failures []LoadReportFailure
+	// Error which aborted the load, if any.
+	Err string
+}
+
+// iamConfigParseError - an IAM config item which was read from the
+// store but could not be decoded.
+type iamConfigParseError struct {
+	Path string
+	Err  error
+}
+
+func (e iamConfigParseError) Error() string {
+	return fmt.Sprintf("unable to parse IAM config %s: %v", e.Path, e.Err)
+}
+
+func (e iamConfigParseError) Unwrap() error {
+	return e.Err
+}
+
+// addFailure - records err for the items listed under prefix, using
+// the exact path instead when the item could not be parsed. Errors
+// for a missing bucket are recorded but not returned, like Load
+// tolerates them.
+func (r *LoadReport) addFailure(prefix string, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	path := prefix
+	var perr iamConfigParseError
+	if errors.As(err, &perr) {
+		path = perr.Path
+	}
+	r.Failures = append(r.Failures, LoadReportFailure{Path: path, Err: err.Error()})
+
+	if errors.As(err, &BucketNotFound{}) {
+		return nil
+	}
+	return err
+}
+
+// LastLoadReport - returns the report of the most recent full load of
+// the IAM sub-system, false if there was none yet.
+func (sys *IAMSys) LastLoadReport() (LoadReport, bool) {
+	if !sys.Initialized() {
+		return LoadReport{}, false
+	}
+
+	sys.Lock()
+	defer sys.Unlock()
+
+	if sys.lastLoadReport == nil {
+		return LoadReport{}, false
+	}
+
+	report := *sys.lastLoadReport
+	report.Failures = append([]LoadReportFailure(nil), report.Failures...)
+	return report, true
+}
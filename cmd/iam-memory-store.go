@@ -0,0 +1,438 @@
+/*
+ * MinIO Cloud Storage, (C) 2021 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/minio/minio-go/v7/pkg/set"
+	"github.com/minio/minio/pkg/auth"
+	iampolicy "github.com/minio/minio/pkg/iam/policy"
+)
+
+// IAMMemoryStore implements IAMStorageAPI keeping all items in memory,
+// meant for tests of the IAM sub-system without an object layer. Items
+// are stored JSON encoded at the same paths as in IAMObjectStore.
+type IAMMemoryStore struct {
+	rwLock sync.RWMutex
+
+	// protects items, independently of rwLock which callers hold
+	// across several operations.
+	mu    sync.Mutex
+	items map[string]iamMemoryItem
+
+	hashes iamConfigHashes
+}
+
+type iamMemoryItem struct {
+	data []byte
+	// zero if the item never expires.
+	expiry time.Time
+}
+
+func (it iamMemoryItem) isExpired() bool {
+	return !it.expiry.IsZero() && !UTCNow().Before(it.expiry)
+}
+
+// NewInMemoryIAMStore - returns an empty in-memory IAM store.
+func NewInMemoryIAMStore() *IAMMemoryStore {
+	return &IAMMemoryStore{items: make(map[string]iamMemoryItem)}
+}
+
+// iamMemoryLocker - RWLocker on top of a sync.RWMutex.
+type iamMemoryLocker struct {
+	mu *sync.RWMutex
+}
+
+func (l iamMemoryLocker) GetLock(ctx context.Context, timeout *DynamicTimeout) (context.Context, error) {
+	l.mu.Lock()
+	return ctx, nil
+}
+
+func (l iamMemoryLocker) Unlock() {
+	l.mu.Unlock()
+}
+
+func (l iamMemoryLocker) GetRLock(ctx context.Context, timeout *DynamicTimeout) (context.Context, error) {
+	l.mu.RLock()
+	return ctx, nil
+}
+
+func (l iamMemoryLocker) RUnlock() {
+	l.mu.RUnlock()
+}
+
+func (iamMS *IAMMemoryStore) newNSLock(bucket string, objects ...string) RWLocker {
+	return iamMemoryLocker{mu: &sync.RWMutex{}}
+}
+
+// lock - the lock is local to this process and only held for the
+// duration of an IAM operation, so it never times out.
+func (iamMS *IAMMemoryStore) lock(ctx context.Context) error {
+	iamMS.rwLock.Lock()
+	return nil
+}
+
+func (iamMS *IAMMemoryStore) unlock() {
+	iamMS.rwLock.Unlock()
+}
+
+func (iamMS *IAMMemoryStore) rlock(ctx context.Context) error {
+	iamMS.rwLock.RLock()
+	return nil
+}
+
+func (iamMS *IAMMemoryStore) runlock() {
+	iamMS.rwLock.RUnlock()
+}
+
+func (iamMS *IAMMemoryStore) migrateBackendFormat(context.Context) error {
+	return nil
+}
+
+func (iamMS *IAMMemoryStore) saveIAMConfig(ctx context.Context, item interface{}, itemPath string, opts ...options) error {
+	data, err := json.Marshal(item)
+	if err != nil {
+		return err
+	}
+
+	it := iamMemoryItem{data: data}
+	if len(opts) > 0 && opts[0].ttl > 0 {
+		it.expiry = UTCNow().Add(time.Duration(opts[0].ttl) * time.Second)
+	}
+
+	iamMS.mu.Lock()
+	defer iamMS.mu.Unlock()
+	iamMS.items[itemPath] = it
+	iamMS.hashes.set(itemPath, iamConfigHash(data))
+	return nil
+}
+
+func (iamMS *IAMMemoryStore) loadIAMConfig(ctx context.Context, item interface{}, itemPath string) error {
+	iamMS.mu.Lock()
+	it, ok := iamMS.items[itemPath]
+	if ok && it.isExpired() {
+		delete(iamMS.items, itemPath)
+		ok = false
+	}
+	iamMS.mu.Unlock()
+
+	if !ok {
+		return errConfigNotFound
+	}
+	if err := json.Unmarshal(it.data, item); err != nil {
+		return iamConfigParseError{Path: itemPath, Err: err}
+	}
+	return nil
+}
+
+func (iamMS *IAMMemoryStore) loadIAMConfigIfChanged(ctx context.Context, item interface{}, itemPath string) error {
+	iamMS.mu.Lock()
+	it, ok := iamMS.items[itemPath]
+	if ok && it.isExpired() {
+		delete(iamMS.items, itemPath)
+		ok = false
+	}
+	iamMS.mu.Unlock()
+
+	if !ok {
+		return errConfigNotFound
+	}
+	return iamMS.hashes.loadIfChanged(it.data, item, itemPath)
+}
+
+func (iamMS *IAMMemoryStore) deleteIAMConfig(ctx context.Context, itemPath string) error {
+	iamMS.hashes.delete(itemPath)
+
+	iamMS.mu.Lock()
+	defer iamMS.mu.Unlock()
+
+	it, ok := iamMS.items[itemPath]
+	if !ok || it.isExpired() {
+		delete(iamMS.items, itemPath)
+		return errConfigNotFound
+	}
+	delete(iamMS.items, itemPath)
+	return nil
+}
+
+// listItems - returns the unexpired items under the prefix, relative
+// to it, in sorted order like listIAMConfigItems.
+func (iamMS *IAMMemoryStore) listItems(prefix string) []string {
+	iamMS.mu.Lock()
+	defer iamMS.mu.Unlock()
+
+	var items []string
+	for p, it := range iamMS.items {
+		if strings.HasPrefix(p, prefix) && !it.isExpired() {
+			items = append(items, strings.TrimPrefix(p, prefix))
+		}
+	}
+	sort.Strings(items)
+	return items
+}
+
+func (iamMS *IAMMemoryStore) getPolicyDoc(ctx context.Context, policy string) (PolicyDoc, error) {
+	var data json.RawMessage
+	if err := iamMS.loadIAMConfig(ctx, &data, getPolicyDocPath(policy)); err != nil {
+		if err == errConfigNotFound {
+			return PolicyDoc{}, errNoSuchPolicy
+		}
+		return PolicyDoc{}, err
+	}
+	var d PolicyDoc
+	if err := d.parseJSON(data); err != nil {
+		return PolicyDoc{}, iamConfigParseError{Path: getPolicyDocPath(policy), Err: err}
+	}
+	return d, nil
+}
+
+func (iamMS *IAMMemoryStore) loadPolicyDoc(ctx context.Context, policy string, m map[string]iampolicy.Policy) error {
+	d, err := iamMS.getPolicyDoc(ctx, policy)
+	if err != nil {
+		return err
+	}
+	m[policy] = d.Policy
+	return nil
+}
+
+func (iamMS *IAMMemoryStore) loadPolicyDocs(ctx context.Context, m map[string]iampolicy.Policy) error {
+	for _, item := range iamMS.listItems(iamConfigPoliciesPrefix) {
+		if item == iamPolicyUsageFile {
+			continue
+		}
+		if err := iamMS.loadPolicyDoc(ctx, path.Dir(item), m); err != nil && err != errNoSuchPolicy {
+			return err
+		}
+	}
+	return nil
+}
+
+func (iamMS *IAMMemoryStore) loadPolicyNames(ctx context.Context, names set.StringSet) error {
+	for _, item := range iamMS.listItems(iamConfigPoliciesPrefix) {
+		if item == iamPolicyUsageFile {
+			continue
+		}
+		names.Add(path.Dir(item))
+	}
+	return nil
+}
+
+func (iamMS *IAMMemoryStore) getUserIdentity(ctx context.Context, user string, userType IAMUserType) (UserIdentity, error) {
+	var u UserIdentity
+	if err := iamMS.loadIAMConfig(ctx, &u, getUserIdentityPath(user, userType)); err != nil {
+		if errors.Is(err, errConfigNotFound) {
+			return UserIdentity{}, errNoSuchUser
+		}
+		return UserIdentity{}, err
+	}
+
+	if u.Credentials.IsExpired() {
+		// Delete expired identity - ignoring errors here.
+		iamMS.deleteIAMConfig(ctx, getUserIdentityPath(user, userType))
+		iamMS.deleteIAMConfig(ctx, getMappedPolicyPath(user, userType, false))
+		return UserIdentity{}, nil
+	}
+
+	if u.Credentials.AccessKey == "" {
+		u.Credentials.AccessKey = user
+	}
+	return u, nil
+}
+
+func (iamMS *IAMMemoryStore) getUserCredentials(ctx context.Context, user string, userType IAMUserType) (auth.Credentials, error) {
+	u, err := iamMS.getUserIdentity(ctx, user, userType)
+	return u.Credentials, err
+}
+
+func (iamMS *IAMMemoryStore) loadUser(ctx context.Context, user string, userType IAMUserType, m map[string]auth.Credentials) error {
+	credentials, err := iamMS.getUserCredentials(ctx, user, userType)
+	if err == nil {
+		m[user] = credentials
+	}
+	return err
+}
+
+func (iamMS *IAMMemoryStore) loadUsers(ctx context.Context, userType IAMUserType, m map[string]auth.Credentials) error {
+	identities := make(map[string]UserIdentity)
+	if err := iamMS.loadUserIdentities(ctx, userType, identities); err != nil {
+		return err
+	}
+	for user, u := range identities {
+		m[user] = u.Credentials
+	}
+	return nil
+}
+
+func (iamMS *IAMMemoryStore) loadUserIdentities(ctx context.Context, userType IAMUserType, m map[string]UserIdentity) error {
+	var basePrefix string
+	switch userType {
+	case srvAccUser:
+		basePrefix = iamConfigServiceAccountsPrefix
+	case stsUser:
+		basePrefix = iamConfigSTSPrefix
+	default:
+		basePrefix = iamConfigUsersPrefix
+	}
+
+	for _, item := range iamMS.listItems(basePrefix) {
+		userName := path.Dir(item)
+		u, err := iamMS.getUserIdentity(ctx, userName, userType)
+		if err != nil {
+			if errors.Is(err, errNoSuchUser) {
+				continue
+			}
+			return err
+		}
+		m[userName] = u
+	}
+	return nil
+}
+
+func (iamMS *IAMMemoryStore) getGroupInfo(ctx context.Context, group string) (GroupInfo, error) {
+	var g GroupInfo
+	if err := iamMS.loadIAMConfig(ctx, &g, getGroupInfoPath(group)); err != nil {
+		if errors.Is(err, errConfigNotFound) {
+			return GroupInfo{}, errNoSuchGroup
+		}
+		return GroupInfo{}, err
+	}
+	return g.normalize(), nil
+}
+
+func (iamMS *IAMMemoryStore) loadGroup(ctx context.Context, group string, m map[string]GroupInfo) error {
+	g, err := iamMS.getGroupInfo(ctx, group)
+	if err != nil {
+		return err
+	}
+	m[group] = g
+	return nil
+}
+
+func (iamMS *IAMMemoryStore) loadGroups(ctx context.Context, m map[string]GroupInfo) error {
+	for _, item := range iamMS.listItems(iamConfigGroupsPrefix) {
+		if err := iamMS.loadGroup(ctx, path.Dir(item), m); err != nil && !errors.Is(err, errNoSuchGroup) {
+			return err
+		}
+	}
+	return nil
+}
+
+func (iamMS *IAMMemoryStore) getMappedPolicy(ctx context.Context, name string, userType IAMUserType, isGroup bool) (MappedPolicy, error) {
+	var p MappedPolicy
+	if err := iamMS.loadIAMConfig(ctx, &p, getMappedPolicyPath(name, userType, isGroup)); err != nil {
+		if errors.Is(err, errConfigNotFound) {
+			return MappedPolicy{}, errNoSuchPolicy
+		}
+		return MappedPolicy{}, err
+	}
+	return p, nil
+}
+
+func (iamMS *IAMMemoryStore) loadMappedPolicy(ctx context.Context, name string, userType IAMUserType, isGroup bool, m map[string]MappedPolicy) error {
+	p, err := iamMS.getMappedPolicy(ctx, name, userType, isGroup)
+	if err == nil {
+		m[name] = p
+	}
+	return err
+}
+
+func (iamMS *IAMMemoryStore) loadMappedPolicies(ctx context.Context, userType IAMUserType, isGroup bool, m map[string]MappedPolicy) error {
+	var basePath string
+	if isGroup {
+		basePath = iamConfigPolicyDBGroupsPrefix
+	} else {
+		switch userType {
+		case srvAccUser:
+			basePath = iamConfigPolicyDBServiceAccountsPrefix
+		case stsUser:
+			basePath = iamConfigPolicyDBSTSUsersPrefix
+		default:
+			basePath = iamConfigPolicyDBUsersPrefix
+		}
+	}
+	for _, item := range iamMS.listItems(basePath) {
+		name := strings.TrimSuffix(item, ".json")
+		if err := iamMS.loadMappedPolicy(ctx, name, userType, isGroup, m); err != nil && err != errNoSuchPolicy {
+			return err
+		}
+	}
+	return nil
+}
+
+func (iamMS *IAMMemoryStore) loadAll(ctx context.Context, sys *IAMSys) error {
+	return sys.Load(ctx, iamMS)
+}
+
+func (iamMS *IAMMemoryStore) savePolicyDoc(ctx context.Context, policyName string, d PolicyDoc) error {
+	return iamMS.saveIAMConfig(ctx, d, getPolicyDocPath(policyName))
+}
+
+func (iamMS *IAMMemoryStore) saveMappedPolicy(ctx context.Context, name string, userType IAMUserType, isGroup bool, mp MappedPolicy, opts ...options) error {
+	return iamMS.saveIAMConfig(ctx, mp, getMappedPolicyPath(name, userType, isGroup), opts...)
+}
+
+func (iamMS *IAMMemoryStore) saveUserIdentity(ctx context.Context, name string, userType IAMUserType, u UserIdentity, opts ...options) error {
+	return iamMS.saveIAMConfig(ctx, u, getUserIdentityPath(name, userType), opts...)
+}
+
+func (iamMS *IAMMemoryStore) saveGroupInfo(ctx context.Context, name string, gi GroupInfo) error {
+	return iamMS.saveIAMConfig(ctx, gi.normalize(), getGroupInfoPath(name))
+}
+
+func (iamMS *IAMMemoryStore) deletePolicyDoc(ctx context.Context, name string) error {
+	err := iamMS.deleteIAMConfig(ctx, getPolicyDocPath(name))
+	if errors.Is(err, errConfigNotFound) {
+		err = errNoSuchPolicy
+	}
+	return err
+}
+
+func (iamMS *IAMMemoryStore) deleteMappedPolicy(ctx context.Context, name string, userType IAMUserType, isGroup bool) error {
+	err := iamMS.deleteIAMConfig(ctx, getMappedPolicyPath(name, userType, isGroup))
+	if errors.Is(err, errConfigNotFound) {
+		err = errNoSuchPolicy
+	}
+	return err
+}
+
+func (iamMS *IAMMemoryStore) deleteUserIdentity(ctx context.Context, name string, userType IAMUserType) error {
+	err := iamMS.deleteIAMConfig(ctx, getUserIdentityPath(name, userType))
+	if errors.Is(err, errConfigNotFound) {
+		err = errNoSuchUser
+	}
+	return err
+}
+
+func (iamMS *IAMMemoryStore) deleteGroupInfo(ctx context.Context, name string) error {
+	err := iamMS.deleteIAMConfig(ctx, getGroupInfoPath(name))
+	if errors.Is(err, errConfigNotFound) {
+		err = errNoSuchGroup
+	}
+	return err
+}
+
+// watch - nothing to watch, all changes go through this process.
+func (iamMS *IAMMemoryStore) watch(context.Context, *IAMSys) {
+}
@@ -0,0 +1,115 @@
+/*
+ * MinIO Cloud Storage, (C) 2021 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"context"
+
+	iampolicy "github.com/minio/minio/pkg/iam/policy"
+)
+
+// Claim listing the authentication methods of a credential, see
+// RFC 8176, and the method which marks it as MFA verified.
+const (
+	iamAMRClaim = "amr"
+	iamMFAAMR   = "mfa"
+)
+
+// SetUserMFARequired - sets whether requests of a user, and of its
+// service accounts and STS credentials, are denied unless their
+// credentials carry an MFA verified claim.
+func (sys *IAMSys) SetUserMFARequired(accessKey string, required bool) error {
+	if !sys.Initialized() {
+		return errServerNotInitialized
+	}
+
+	if sys.readOnly {
+		return errIAMReadOnly
+	}
+
+	accessKey = sys.normalizeAccessKey(accessKey)
+
+	if sys.usersSysType != MinIOUsersSysType {
+		return errIAMActionNotAllowed
+	}
+
+	if err := sys.lockStore(); err != nil {
+		return err
+	}
+	defer sys.store.unlock()
+
+	u, err := sys.store.getUserIdentity(context.Background(), accessKey, regularUser)
+	if err != nil {
+		return err
+	}
+	if u.MFARequired == required {
+		return nil
+	}
+	u.MFARequired = required
+	if err = sys.store.saveUserIdentity(context.Background(), accessKey, regularUser, u); err != nil {
+		return err
+	}
+
+	sys.Lock()
+	defer sys.Unlock()
+
+	sys.setMFARequired(accessKey, u)
+	return nil
+}
+
+// setMFARequired - records whether the given identity requires MFA.
+// IMPORTANT: Assumes that sys.Lock is held by caller.
+func (sys *IAMSys) setMFARequired(accessKey string, u UserIdentity) {
+	if u.MFARequired {
+		sys.iamMFARequired.Add(accessKey)
+		return
+	}
+	sys.iamMFARequired.Remove(accessKey)
+}
+
+// mfaSatisfied - reports whether the request of args is MFA verified
+// when the account, or the user it belongs to, requires it.
+func (sys *IAMSys) mfaSatisfied(args iampolicy.Args) bool {
+	sys.RLock()
+	required := sys.iamMFARequired.Contains(args.AccountName)
+	if cred, ok := sys.iamUsersMap[args.AccountName]; !required && ok && cred.ParentUser != "" {
+		required = sys.iamMFARequired.Contains(cred.ParentUser)
+	}
+	sys.RUnlock()
+
+	if !required {
+		return true
+	}
+
+	switch amr := args.Claims[iamAMRClaim].(type) {
+	case string:
+		return amr == iamMFAAMR
+	case []interface{}:
+		for _, v := range amr {
+			if s, ok := v.(string); ok && s == iamMFAAMR {
+				return true
+			}
+		}
+	case []string:
+		for _, s := range amr {
+			if s == iamMFAAMR {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -21,15 +21,18 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"os"
 	"path"
 	"strings"
 	"time"
 	"unicode/utf8"
 
+	"github.com/minio/minio-go/v7/pkg/set"
 	"github.com/minio/minio/cmd/config"
 	"github.com/minio/minio/cmd/logger"
 	"github.com/minio/minio/pkg/auth"
+	"github.com/minio/minio/pkg/env"
 	iampolicy "github.com/minio/minio/pkg/iam/policy"
 	"github.com/minio/minio/pkg/kms"
 	"github.com/minio/minio/pkg/madmin"
@@ -39,6 +42,14 @@ import (
 type IAMObjectStore struct {
 	rwLock RWLocker
 	objAPI ObjectLayer
+
+	// content hashes and object ETags of the items last saved or
+	// loaded with loadIAMConfigIfChanged, see loadIAMConfigIfChanged.
+	hashes iamConfigHashes
+	etags  iamConfigHashes
+
+	// store canned policies compressed, see envIAMCompressPolicies.
+	compressPolicyDocs bool
 }
 
 func (iamOS *IAMObjectStore) newNSLock(bucket string, objects ...string) RWLocker {
@@ -46,13 +57,24 @@ func (iamOS *IAMObjectStore) newNSLock(bucket string, objects ...string) RWLocke
 }
 
 func newIAMObjectStore(objAPI ObjectLayer) *IAMObjectStore {
-	return &IAMObjectStore{objAPI: objAPI, rwLock: objAPI.NewNSLock(MinioMetaBucket, MinioMetaLockFile)}
+	compress, err := config.ParseBool(env.Get(envIAMCompressPolicies, config.EnableOff))
+	if err != nil {
+		logger.LogIf(GlobalContext, fmt.Errorf("invalid value for %s: %w", envIAMCompressPolicies, err))
+	}
+	return &IAMObjectStore{
+		objAPI:             objAPI,
+		rwLock:             objAPI.NewNSLock(MinioMetaBucket, MinioMetaLockFile),
+		compressPolicyDocs: compress,
+	}
 }
 
-func (iamOS *IAMObjectStore) lock() {
+func (iamOS *IAMObjectStore) lock(ctx context.Context) error {
 	for {
-		if _, err := iamOS.rwLock.GetLock(context.Background(), globalGetLockConfigTimeout); err == nil {
-			return
+		if _, err := iamOS.rwLock.GetLock(ctx, globalGetLockConfigTimeout); err == nil {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return errIAMLockTimeout
 		}
 	}
 }
@@ -61,10 +83,13 @@ func (iamOS *IAMObjectStore) unlock() {
 	iamOS.rwLock.Unlock()
 }
 
-func (iamOS *IAMObjectStore) rlock() {
+func (iamOS *IAMObjectStore) rlock(ctx context.Context) error {
 	for {
-		if _, err := iamOS.rwLock.GetRLock(context.Background(), globalGetLockConfigTimeout); err == nil {
-			return
+		if _, err := iamOS.rwLock.GetRLock(ctx, globalGetLockConfigTimeout); err == nil {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return errIAMLockTimeout
 		}
 	}
 }
@@ -219,6 +244,13 @@ func (iamOS *IAMObjectStore) saveIAMConfig(ctx context.Context, item interface{}
 	if err != nil {
 		return err
 	}
+	hash := iamConfigHash(data)
+	if len(opts) > 0 && opts[0].compress {
+		data, err = compressIAMConfig(data)
+		if err != nil {
+			return err
+		}
+	}
 	if GlobalKMS != nil {
 		data, err = config.EncryptBytes(GlobalKMS, data, kms.Context{
 			MinioMetaBucket: path.Join(MinioMetaBucket, objPath),
@@ -227,14 +259,84 @@ func (iamOS *IAMObjectStore) saveIAMConfig(ctx context.Context, item interface{}
 			return err
 		}
 	}
-	return saveConfig(ctx, iamOS.objAPI, objPath, data)
+	oi, err := saveConfigWithObjectInfo(ctx, iamOS.objAPI, objPath, data)
+	if err != nil {
+		iamOS.hashes.delete(objPath)
+		iamOS.etags.delete(objPath)
+		return err
+	}
+	iamOS.hashes.set(objPath, hash)
+	iamOS.setETag(objPath, oi.ETag)
+	return nil
 }
 
 func (iamOS *IAMObjectStore) loadIAMConfig(ctx context.Context, item interface{}, objPath string) error {
-	data, err := readConfig(ctx, iamOS.objAPI, objPath)
+	data, err := iamOS.readIAMConfig(ctx, objPath)
 	if err != nil {
 		return err
 	}
+	if err = json.Unmarshal(data, item); err != nil {
+		return iamConfigParseError{Path: objPath, Err: err}
+	}
+	return nil
+}
+
+// loadIAMConfigIfChanged - compares the ETag of the object with the
+// one recorded when the store last saved or loaded it, so unchanged
+// items cost a stat instead of reading the whole object. Backends
+// without usable ETags, like FS for .minio.sys objects, fall back to
+// reading the object and comparing its content hash.
+func (iamOS *IAMObjectStore) loadIAMConfigIfChanged(ctx context.Context, item interface{}, objPath string) error {
+	oi, err := iamOS.objAPI.GetObjectInfo(ctx, MinioMetaBucket, objPath, ObjectOptions{})
+	if err != nil {
+		// Treat object not found as config not found.
+		if isErrObjectNotFound(err) || isErrBucketNotFound(err) {
+			return errConfigNotFound
+		}
+		return err
+	}
+	if isIAMConfigETag(oi.ETag) && oi.ETag == iamOS.etags.get(objPath) {
+		return errIAMConfigNotModified
+	}
+
+	// The object may change after the stat, recording the older ETag
+	// then only costs another read next time.
+	data, err := iamOS.readIAMConfig(ctx, objPath)
+	if err != nil {
+		return err
+	}
+	err = iamOS.hashes.loadIfChanged(data, item, objPath)
+	if err == nil || errors.Is(err, errIAMConfigNotModified) {
+		iamOS.setETag(objPath, oi.ETag)
+	}
+	return err
+}
+
+// isIAMConfigETag - reports whether etag identifies the content of an
+// IAM config object, FS returns defaultEtag for objects without fs.json.
+func isIAMConfigETag(etag string) bool {
+	return etag != "" && etag != defaultEtag
+}
+
+func (iamOS *IAMObjectStore) setETag(objPath, etag string) {
+	if isIAMConfigETag(etag) {
+		iamOS.etags.set(objPath, etag)
+	} else {
+		iamOS.etags.delete(objPath)
+	}
+}
+
+// readIAMConfig - returns the decrypted and decompressed JSON of an IAM
+// config item.
+func (iamOS *IAMObjectStore) readIAMConfig(ctx context.Context, objPath string) ([]byte, error) {
+	data, err := readConfig(ctx, iamOS.objAPI, objPath)
+	if err != nil {
+		return nil, err
+	}
+	if isCompressedIAMConfig(data) {
+		// Compressed but not encrypted.
+		return decompressIAMConfig(data)
+	}
 	if !utf8.Valid(data) {
 		if GlobalKMS != nil {
 			data, err = config.DecryptBytes(GlobalKMS, data, kms.Context{
@@ -243,33 +345,50 @@ func (iamOS *IAMObjectStore) loadIAMConfig(ctx context.Context, item interface{}
 			if err != nil {
 				data, err = madmin.DecryptData(globalActiveCred.String(), bytes.NewReader(data))
 				if err != nil {
-					return err
+					return nil, err
 				}
 			}
 		} else {
 			data, err = madmin.DecryptData(globalActiveCred.String(), bytes.NewReader(data))
 			if err != nil {
-				return err
+				return nil, err
 			}
 		}
 	}
-	return json.Unmarshal(data, item)
+	if isCompressedIAMConfig(data) {
+		return decompressIAMConfig(data)
+	}
+	return data, nil
 }
 
 func (iamOS *IAMObjectStore) deleteIAMConfig(ctx context.Context, path string) error {
+	iamOS.hashes.delete(path)
+	iamOS.etags.delete(path)
 	return deleteConfig(ctx, iamOS.objAPI, path)
 }
 
-func (iamOS *IAMObjectStore) loadPolicyDoc(ctx context.Context, policy string, m map[string]iampolicy.Policy) error {
-	var p iampolicy.Policy
-	err := iamOS.loadIAMConfig(ctx, &p, getPolicyDocPath(policy))
+func (iamOS *IAMObjectStore) getPolicyDoc(ctx context.Context, policy string) (PolicyDoc, error) {
+	var data json.RawMessage
+	err := iamOS.loadIAMConfig(ctx, &data, getPolicyDocPath(policy))
 	if err != nil {
 		if err == errConfigNotFound {
-			return errNoSuchPolicy
+			return PolicyDoc{}, errNoSuchPolicy
 		}
+		return PolicyDoc{}, err
+	}
+	var d PolicyDoc
+	if err = d.parseJSON(data); err != nil {
+		return PolicyDoc{}, iamConfigParseError{Path: getPolicyDocPath(policy), Err: err}
+	}
+	return d, nil
+}
+
+func (iamOS *IAMObjectStore) loadPolicyDoc(ctx context.Context, policy string, m map[string]iampolicy.Policy) error {
+	d, err := iamOS.getPolicyDoc(ctx, policy)
+	if err != nil {
 		return err
 	}
-	m[policy] = p
+	m[policy] = d.Policy
 	return nil
 }
 
@@ -279,6 +398,10 @@ func (iamOS *IAMObjectStore) loadPolicyDocs(ctx context.Context, m map[string]ia
 			return item.Err
 		}
 
+		if item.Item == iamPolicyUsageFile {
+			continue
+		}
+
 		policyName := path.Dir(item.Item)
 		if err := iamOS.loadPolicyDoc(ctx, policyName, m); err != nil && err != errNoSuchPolicy {
 			return err
@@ -287,28 +410,49 @@ func (iamOS *IAMObjectStore) loadPolicyDocs(ctx context.Context, m map[string]ia
 	return nil
 }
 
-func (iamOS *IAMObjectStore) getUserCredentials(ctx context.Context, user string, userType IAMUserType) (auth.Credentials, error) {
+func (iamOS *IAMObjectStore) loadPolicyNames(ctx context.Context, names set.StringSet) error {
+	for item := range listIAMConfigItems(ctx, iamOS.objAPI, iamConfigPoliciesPrefix) {
+		if item.Err != nil {
+			return item.Err
+		}
+
+		if item.Item == iamPolicyUsageFile {
+			continue
+		}
+
+		names.Add(path.Dir(item.Item))
+	}
+	return nil
+}
+
+func (iamOS *IAMObjectStore) getUserIdentity(ctx context.Context, user string, userType IAMUserType) (UserIdentity, error) {
 	var u UserIdentity
 	err := iamOS.loadIAMConfig(ctx, &u, getUserIdentityPath(user, userType))
 	if err != nil {
 		if errors.Is(err, errConfigNotFound) {
-			return auth.Credentials{}, errNoSuchUser
+			return UserIdentity{}, errNoSuchUser
 		}
-		return auth.Credentials{}, err
+		return UserIdentity{}, err
 	}
 
 	if u.Credentials.IsExpired() {
 		// Delete expired identity - ignoring errors here.
 		iamOS.deleteIAMConfig(ctx, getUserIdentityPath(user, userType))
 		iamOS.deleteIAMConfig(ctx, getMappedPolicyPath(user, userType, false))
-		return auth.Credentials{}, nil
+		return UserIdentity{}, nil
 	}
 
 	if u.Credentials.AccessKey == "" {
 		u.Credentials.AccessKey = user
 	}
-	return u.Credentials, nil
+	return u, nil
+}
+
+func (iamOS *IAMObjectStore) getUserCredentials(ctx context.Context, user string, userType IAMUserType) (auth.Credentials, error) {
+	u, err := iamOS.getUserIdentity(ctx, user, userType)
+	return u.Credentials, err
 }
+
 func (iamOS *IAMObjectStore) loadUser(ctx context.Context, user string, userType IAMUserType, m map[string]auth.Credentials) error {
 	credentials, err := iamOS.getUserCredentials(ctx, user, userType)
 	if err == nil {
@@ -318,6 +462,17 @@ func (iamOS *IAMObjectStore) loadUser(ctx context.Context, user string, userType
 }
 
 func (iamOS *IAMObjectStore) loadUsers(ctx context.Context, userType IAMUserType, m map[string]auth.Credentials) error {
+	identities := make(map[string]UserIdentity)
+	if err := iamOS.loadUserIdentities(ctx, userType, identities); err != nil {
+		return err
+	}
+	for user, u := range identities {
+		m[user] = u.Credentials
+	}
+	return nil
+}
+
+func (iamOS *IAMObjectStore) loadUserIdentities(ctx context.Context, userType IAMUserType, m map[string]UserIdentity) error {
 	var basePrefix string
 	switch userType {
 	case srvAccUser:
@@ -334,9 +489,14 @@ func (iamOS *IAMObjectStore) loadUsers(ctx context.Context, userType IAMUserType
 		}
 
 		userName := path.Dir(item.Item)
-		if err := iamOS.loadUser(ctx, userName, userType, m); err != nil && !errors.Is(err, errNoSuchUser) {
+		u, err := iamOS.getUserIdentity(ctx, userName, userType)
+		if err != nil {
+			if errors.Is(err, errNoSuchUser) {
+				continue
+			}
 			return err
 		}
+		m[userName] = u
 	}
 	return nil
 }
@@ -350,7 +510,7 @@ func (iamOS *IAMObjectStore) getGroupInfo(ctx context.Context, group string) (Gr
 		}
 		return GroupInfo{}, err
 	}
-	return g, err
+	return g.normalize(), err
 }
 
 func (iamOS *IAMObjectStore) loadGroup(ctx context.Context, group string, m map[string]GroupInfo) error {
@@ -428,8 +588,8 @@ func (iamOS *IAMObjectStore) loadAll(ctx context.Context, sys *IAMSys) error {
 	return sys.Load(ctx, iamOS)
 }
 
-func (iamOS *IAMObjectStore) savePolicyDoc(ctx context.Context, policyName string, p iampolicy.Policy) error {
-	return iamOS.saveIAMConfig(ctx, &p, getPolicyDocPath(policyName))
+func (iamOS *IAMObjectStore) savePolicyDoc(ctx context.Context, policyName string, d PolicyDoc) error {
+	return iamOS.saveIAMConfig(ctx, d, getPolicyDocPath(policyName), options{compress: iamOS.compressPolicyDocs})
 }
 
 func (iamOS *IAMObjectStore) saveMappedPolicy(ctx context.Context, name string, userType IAMUserType, isGroup bool, mp MappedPolicy, opts ...options) error {
@@ -441,7 +601,7 @@ func (iamOS *IAMObjectStore) saveUserIdentity(ctx context.Context, name string,
 }
 
 func (iamOS *IAMObjectStore) saveGroupInfo(ctx context.Context, name string, gi GroupInfo) error {
-	return iamOS.saveIAMConfig(ctx, gi, getGroupInfoPath(name))
+	return iamOS.saveIAMConfig(ctx, gi.normalize(), getGroupInfoPath(name))
 }
 
 func (iamOS *IAMObjectStore) deletePolicyDoc(ctx context.Context, name string) error {
@@ -525,11 +685,36 @@ func (iamOS *IAMObjectStore) watch(ctx context.Context, sys *IAMSys) {
 			interval = duration
 		}
 	}
+	if sys.refreshInterval > 0 {
+		interval = sys.refreshInterval
+	}
+
+	// Reloads failing with retriable errors, e.g. while the writer
+	// node is being restarted, are retried sooner with an increasing
+	// delay up to the interval.
+	timer := time.NewTimer(interval)
+	defer timer.Stop()
 
+	retryDelay := time.Second
 	for {
-		time.Sleep(interval)
-		if err := iamOS.loadAll(ctx, sys); err != nil {
-			logger.LogIf(ctx, err)
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
 		}
+
+		err := iamOS.loadAll(ctx, sys)
+		if err != nil && configRetriableErrors(err) {
+			logger.Info("Unable to refresh IAM sub-system, retrying in %s.. possible cause (%v)", retryDelay, err)
+			timer.Reset(retryDelay)
+			if retryDelay *= 2; retryDelay > interval {
+				retryDelay = interval
+			}
+			continue
+		}
+		logger.LogIf(ctx, err)
+
+		retryDelay = time.Second
+		timer.Reset(interval)
 	}
 }
@@ -0,0 +1,156 @@
+/*
+ * MinIO Cloud Storage, (C) 2021 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/minio/minio/pkg/auth"
+)
+
+// countingObjectLayer - counts the objects read from the object layer.
+type countingObjectLayer struct {
+	ObjectLayer
+	reads int
+}
+
+func (l *countingObjectLayer) GetObjectNInfo(ctx context.Context, bucket, object string, rs *HTTPRangeSpec, h http.Header, lockType LockType, opts ObjectOptions) (*GetObjectReader, error) {
+	l.reads++
+	return l.ObjectLayer.GetObjectNInfo(ctx, bucket, object, rs, h, lockType, opts)
+}
+
+func TestIAMObjectStoreLoadIfChanged(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	obj, fsDir, err := prepareFS()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(fsDir)
+
+	// FS has no ETags for .minio.sys objects, items are read and
+	// compared by content.
+	testIAMObjectStoreLoadIfChanged(ctx, t, obj, 1, 3)
+
+	objErasure, fsDirs, err := prepareErasure16(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer removeRoots(fsDirs)
+
+	// Erasure ETags spare reading unchanged items.
+	testIAMObjectStoreLoadIfChanged(ctx, t, objErasure, 0, 1)
+}
+
+// testIAMObjectStoreLoadIfChanged - loads an item saved by the store
+// itself and one changed by another server, then checks the number
+// of objects read for the unchanged and for all loads.
+func testIAMObjectStoreLoadIfChanged(ctx context.Context, t *testing.T, obj ObjectLayer, unchangedReads, totalReads int) {
+	t.Helper()
+
+	objAPI := &countingObjectLayer{ObjectLayer: obj}
+	store := newIAMObjectStore(objAPI)
+	other := newIAMObjectStore(obj)
+
+	path := getUserQuotasPath()
+	var q userQuotas
+	if err := store.loadIAMConfigIfChanged(ctx, &q, path); !errors.Is(err, errConfigNotFound) {
+		t.Fatalf("expected %v, got %v", errConfigNotFound, err)
+	}
+
+	// Items the store saved itself are not modified.
+	if err := store.saveIAMConfig(ctx, userQuotas{Version: userQuotasVersion1}, path); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.loadIAMConfigIfChanged(ctx, &q, path); !errors.Is(err, errIAMConfigNotModified) {
+		t.Fatalf("expected %v, got %v", errIAMConfigNotModified, err)
+	}
+	if objAPI.reads != unchangedReads {
+		t.Fatalf("expected %d reads, got %d", unchangedReads, objAPI.reads)
+	}
+
+	// Items changed by another server are loaded once.
+	quotas := userQuotas{
+		Version: userQuotasVersion1,
+		Quotas:  map[string]UserQuota{"alice": {MaxBuckets: 1}},
+	}
+	if err := other.saveIAMConfig(ctx, quotas, path); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.loadIAMConfigIfChanged(ctx, &q, path); err != nil {
+		t.Fatal(err)
+	}
+	if q.Quotas["alice"].MaxBuckets != 1 {
+		t.Fatalf("expected the changed quotas, got %v", q.Quotas)
+	}
+	if err := store.loadIAMConfigIfChanged(ctx, &q, path); !errors.Is(err, errIAMConfigNotModified) {
+		t.Fatalf("expected %v, got %v", errIAMConfigNotModified, err)
+	}
+	if objAPI.reads != totalReads {
+		t.Fatalf("expected %d reads, got %d", totalReads, objAPI.reads)
+	}
+}
+
+func TestIAMObjectStoreRefreshInterval(t *testing.T) {
+	obj, fsDir, err := prepareFS()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(fsDir)
+
+	os.Setenv(envIAMReadThroughRefreshInterval, "50ms")
+	defer os.Unsetenv(envIAMReadThroughRefreshInterval)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sys := NewIAMSys()
+	sys.InitStoreWith(newIAMObjectStore(obj))
+	if sys.refreshInterval != 50*time.Millisecond {
+		t.Fatalf("expected a refresh interval of 50ms, got %s", sys.refreshInterval)
+	}
+	if err = sys.store.loadAll(ctx, sys); err != nil {
+		t.Fatal(err)
+	}
+	go sys.store.watch(ctx, sys)
+
+	// Users created by another server show up after the interval.
+	other := newIAMObjectStore(obj)
+	u := newUserIdentity(auth.Credentials{AccessKey: "alice", SecretKey: "alicesecretkey", Status: "on"})
+	if err = other.saveUserIdentity(ctx, "alice", regularUser, u); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		sys.Lock()
+		_, ok := sys.iamUsersMap["alice"]
+		sys.Unlock()
+		if ok {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("expected alice to be loaded by the periodic refresh")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
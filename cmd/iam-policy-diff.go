@@ -0,0 +1,171 @@
+/*
+ * MinIO Cloud Storage, (C) 2021 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"encoding/json"
+	"sort"
+
+	"github.com/minio/minio/pkg/bucket/policy"
+	"github.com/minio/minio/pkg/bucket/policy/condition"
+	iampolicy "github.com/minio/minio/pkg/iam/policy"
+)
+
+// PolicyPermission - a single action allowed or denied on a resource
+// by a policy statement. Statements are compared as the set of the
+// permissions they grant, so that moving an action between statements
+// is not reported as a change.
+type PolicyPermission struct {
+	Effect   policy.Effect `json:"effect"`
+	Action   string        `json:"action"`
+	Resource string        `json:"resource,omitempty"`
+	// Conditions of the statement in JSON, if any.
+	Conditions string `json:"conditions,omitempty"`
+}
+
+// PolicyDiff - outcome of DiffPolicies.
+type PolicyDiff struct {
+	// Permissions only granted or denied by the second policy.
+	Added []PolicyPermission `json:"added,omitempty"`
+	// Permissions only granted or denied by the first policy.
+	Removed []PolicyPermission `json:"removed,omitempty"`
+}
+
+// Empty - reports whether both policies are equivalent.
+func (d PolicyDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0
+}
+
+// DiffPolicies - compares the canned policies a and b, returning the
+// permissions b adds to and removes from a, sorted.
+func (sys *IAMSys) DiffPolicies(a, b string) (PolicyDiff, error) {
+	if !sys.Initialized() {
+		return PolicyDiff{}, errServerNotInitialized
+	}
+
+	<-sys.configLoaded
+
+	if err := sys.loadLazyPolicies(a, b); err != nil {
+		return PolicyDiff{}, err
+	}
+
+	sys.Lock()
+	pa, okA := sys.policyDoc(a)
+	pb, okB := sys.policyDoc(b)
+	sys.Unlock()
+	if !okA || !okB {
+		return PolicyDiff{}, errNoSuchPolicy
+	}
+
+	permsA, err := policyPermissions(pa)
+	if err != nil {
+		return PolicyDiff{}, err
+	}
+	permsB, err := policyPermissions(pb)
+	if err != nil {
+		return PolicyDiff{}, err
+	}
+
+	var d PolicyDiff
+	for perm := range permsB {
+		if _, ok := permsA[perm]; !ok {
+			d.Added = append(d.Added, perm)
+		}
+	}
+	for perm := range permsA {
+		if _, ok := permsB[perm]; !ok {
+			d.Removed = append(d.Removed, perm)
+		}
+	}
+	sortPolicyPermissions(d.Added)
+	sortPolicyPermissions(d.Removed)
+	return d, nil
+}
+
+// policyPermissions - returns the set of permissions of p.
+func policyPermissions(p iampolicy.Policy) (map[PolicyPermission]struct{}, error) {
+	perms := make(map[PolicyPermission]struct{})
+	for _, st := range p.Statements {
+		conditions, err := canonicalConditions(st.Conditions)
+		if err != nil {
+			return nil, err
+		}
+
+		resources := []string{""}
+		if len(st.Resources) > 0 {
+			resources = resources[:0]
+			for r := range st.Resources {
+				resources = append(resources, r.String())
+			}
+		}
+		for action := range st.Actions {
+			for _, resource := range resources {
+				perms[PolicyPermission{
+					Effect:     st.Effect,
+					Action:     string(action),
+					Resource:   resource,
+					Conditions: conditions,
+				}] = struct{}{}
+			}
+		}
+	}
+	return perms, nil
+}
+
+// canonicalConditions - returns the JSON of conditions with condition
+// names, keys and values sorted, as value sets are marshaled in map
+// order.
+func canonicalConditions(conditions condition.Functions) (string, error) {
+	if len(conditions) == 0 {
+		return "", nil
+	}
+	data, err := json.Marshal(conditions)
+	if err != nil {
+		return "", err
+	}
+	var m map[string]map[string][]json.RawMessage
+	if err = json.Unmarshal(data, &m); err != nil {
+		return "", err
+	}
+	for _, keys := range m {
+		for _, values := range keys {
+			sort.Slice(values, func(i, j int) bool {
+				return string(values[i]) < string(values[j])
+			})
+		}
+	}
+	// Map keys are marshaled in sorted order.
+	if data, err = json.Marshal(m); err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+func sortPolicyPermissions(perms []PolicyPermission) {
+	sort.Slice(perms, func(i, j int) bool {
+		pi, pj := perms[i], perms[j]
+		switch {
+		case pi.Resource != pj.Resource:
+			return pi.Resource < pj.Resource
+		case pi.Action != pj.Action:
+			return pi.Action < pj.Action
+		case pi.Effect != pj.Effect:
+			return pi.Effect < pj.Effect
+		}
+		return pi.Conditions < pj.Conditions
+	})
+}
@@ -0,0 +1,162 @@
+/*
+ * MinIO Cloud Storage, (C) 2021 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/minio/minio/cmd/logger"
+)
+
+// Interval at which policy usage timestamps are saved to the store.
+const iamPolicyUsageFlushInterval = 10 * time.Minute
+
+func getPolicyUsagePath() string {
+	return pathJoin(iamConfigPoliciesPrefix, iamPolicyUsageFile)
+}
+
+// policyUsage - last time policies allowed a request. It is updated
+// on every allowed request, so it does not take sys.Lock: entries are
+// added once per policy and their timestamps are updated atomically.
+type policyUsage struct {
+	// map of policy names to *int64 unix nanoseconds.
+	lastUsed sync.Map
+}
+
+// markUsed - records that the given policies were used at t.
+func (u *policyUsage) markUsed(t time.Time, names ...string) {
+	for _, name := range names {
+		u.merge(name, t)
+	}
+}
+
+// merge - sets the last use of a policy to t unless it is more recent.
+func (u *policyUsage) merge(name string, t time.Time) {
+	v, ok := u.lastUsed.Load(name)
+	if !ok {
+		v, _ = u.lastUsed.LoadOrStore(name, new(int64))
+	}
+	ts := v.(*int64)
+	nanos := t.UnixNano()
+	for {
+		cur := atomic.LoadInt64(ts)
+		if cur >= nanos || atomic.CompareAndSwapInt64(ts, cur, nanos) {
+			return
+		}
+	}
+}
+
+func (u *policyUsage) get(name string) (time.Time, bool) {
+	v, ok := u.lastUsed.Load(name)
+	if !ok {
+		return time.Time{}, false
+	}
+	nanos := atomic.LoadInt64(v.(*int64))
+	if nanos == 0 {
+		return time.Time{}, false
+	}
+	return time.Unix(0, nanos).UTC(), true
+}
+
+func (u *policyUsage) delete(name string) {
+	u.lastUsed.Delete(name)
+}
+
+// PolicyLastUsed - returns the last time the given policy contributed
+// to an allowed request, as seen by this server or saved by others.
+func (sys *IAMSys) PolicyLastUsed(name string) (time.Time, bool) {
+	if !sys.Initialized() {
+		return time.Time{}, false
+	}
+
+	return sys.policyUsage.get(name)
+}
+
+// updatePolicyLastUsed - records that the given policies were used to
+// allow a request. All policies evaluated for the request are marked,
+// this may overestimate usage but never reports a policy in use as
+// unused. Policies which do not exist are dropped when the usage is
+// saved.
+func (sys *IAMSys) updatePolicyLastUsed(policies ...string) {
+	sys.policyUsage.markUsed(UTCNow(), policies...)
+}
+
+// loadPolicyUsage - merges the policy usage saved in the store into
+// memory, keeping the most recent timestamp of each policy, and returns
+// a copy of the result.
+func (sys *IAMSys) loadPolicyUsage(ctx context.Context) (map[string]time.Time, error) {
+	saved := make(map[string]time.Time)
+	if err := sys.store.loadIAMConfig(ctx, &saved, getPolicyUsagePath()); err != nil && !errors.Is(err, errConfigNotFound) {
+		return nil, err
+	}
+
+	for name, t := range saved {
+		sys.policyUsage.merge(name, t)
+	}
+
+	sys.Lock()
+	defer sys.Unlock()
+
+	usage := make(map[string]time.Time)
+	sys.policyUsage.lastUsed.Range(func(k, _ interface{}) bool {
+		name := k.(string)
+		_, ok := sys.iamPolicyDocsMap[name]
+		if !ok && !sys.iamLazyPolicies.Contains(name) {
+			// Policy was deleted meanwhile.
+			sys.policyUsage.delete(name)
+			return true
+		}
+		if t, ok := sys.policyUsage.get(name); ok {
+			usage[name] = t
+		}
+		return true
+	})
+	return usage, nil
+}
+
+// persistPolicyUsage - periodically saves the policy usage timestamps to
+// the store so that they survive restarts approximately.
+func (sys *IAMSys) persistPolicyUsage(ctx context.Context, interval time.Duration) {
+	if _, err := sys.loadPolicyUsage(ctx); err != nil {
+		logger.LogIf(ctx, err)
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		usage, err := sys.loadPolicyUsage(ctx)
+		if err != nil {
+			logger.LogIf(ctx, err)
+			continue
+		}
+		if sys.readOnly {
+			continue
+		}
+		logger.LogIf(ctx, sys.store.saveIAMConfig(ctx, usage, getPolicyUsagePath()))
+	}
+}
@@ -0,0 +1,119 @@
+/*
+ * MinIO Cloud Storage, (C) 2021 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/minio/minio/pkg/auth"
+)
+
+// ReEncryptIAM - re-signs the session tokens of all service accounts
+// signed with oldSecret using newSecret and persists them, so they stay
+// valid once the root secret key is rotated to newSecret. All claims are
+// carried over unchanged, embedded session policies included. Tokens
+// which already verify with newSecret are left alone, so an interrupted
+// rotation can be resumed by calling it again. The rest of the stored
+// identities, and the expiry of expiring service accounts, are kept.
+func (sys *IAMSys) ReEncryptIAM(oldSecret, newSecret string) error {
+	if !sys.Initialized() {
+		return errServerNotInitialized
+	}
+
+	if sys.readOnly {
+		return errIAMReadOnly
+	}
+
+	if oldSecret == "" || newSecret == "" {
+		return errInvalidArgument
+	}
+
+	if oldSecret == newSecret {
+		return nil
+	}
+
+	ctx := context.Background()
+
+	if err := sys.lockStore(); err != nil {
+		return err
+	}
+	defer sys.store.unlock()
+
+	// Read the service accounts from the store, the in-memory ones may
+	// be behind other servers.
+	m := make(map[string]auth.Credentials)
+	if err := sys.store.loadUsers(ctx, srvAccUser, m); err != nil {
+		return err
+	}
+
+	for accessKey, cr := range m {
+		if cr.SessionToken == "" || cr.IsExpired() {
+			continue
+		}
+
+		// Only the session token changes, keep everything else
+		// stored along with the credentials.
+		u, err := sys.store.getUserIdentity(ctx, accessKey, srvAccUser)
+		if err != nil {
+			if errors.Is(err, errNoSuchUser) {
+				// Deleted or expired meanwhile.
+				continue
+			}
+			return err
+		}
+		cr = u.Credentials
+
+		claims, err := auth.ExtractClaims(cr.SessionToken, oldSecret)
+		if err != nil {
+			if _, nerr := auth.ExtractClaims(cr.SessionToken, newSecret); nerr == nil {
+				// Already re-signed.
+				continue
+			}
+			return fmt.Errorf("unable to verify the session token of service account %s: %w", accessKey, err)
+		}
+
+		claimsMap := make(map[string]interface{}, len(claims.MapClaims))
+		for k, v := range claims.MapClaims {
+			claimsMap[k] = v
+		}
+		u.Credentials.SessionToken, err = auth.JWTSignWithAccessKey(accessKey, claimsMap, newSecret)
+		if err != nil {
+			return err
+		}
+
+		var opts []options
+		if cr.ServiceAccount {
+			// Expiring service accounts are saved with a TTL.
+			ttl := int64(cr.Expiration.Sub(UTCNow()).Seconds())
+			if ttl <= 0 {
+				continue
+			}
+			opts = append(opts, options{ttl: ttl})
+		}
+		if err = sys.store.saveUserIdentity(ctx, accessKey, srvAccUser, u, opts...); err != nil {
+			return err
+		}
+
+		sys.Lock()
+		sys.iamUsersMap[accessKey] = u.Credentials
+		sys.Unlock()
+	}
+
+	return nil
+}
@@ -0,0 +1,192 @@
+/*
+ * MinIO Cloud Storage, (C) 2021 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/minio/minio/cmd/logger"
+	iampolicy "github.com/minio/minio/pkg/iam/policy"
+)
+
+// IAMChangeSource - delivers changes of IAM objects made by peer
+// servers, e.g. received from a message bus, to be applied with
+// ApplyRemoteChange. This propagates changes without waiting for the
+// periodic reload on stores which cannot be watched.
+type IAMChangeSource interface {
+	// Changes - returns a channel of changes which is closed once
+	// ctx is done.
+	Changes(ctx context.Context) <-chan IAMChangeEvent
+}
+
+// WatchRemoteChanges - applies the changes delivered by src until ctx
+// is done or the channel is closed. Errors are logged, the change is
+// then picked up by the next periodic reload.
+func (sys *IAMSys) WatchRemoteChanges(ctx context.Context, src IAMChangeSource) {
+	for ev := range src.Changes(ctx) {
+		if err := sys.ApplyRemoteChange(ev); err != nil {
+			logger.LogIf(ctx, fmt.Errorf("unable to apply IAM change of %s %s: %w", ev.ObjectType, ev.Name, err))
+		}
+	}
+}
+
+// ApplyRemoteChange - reloads the IAM object named by ev from the store
+// into memory, after it was changed by a peer server. The object is
+// reconciled with the store whatever the action of ev, objects which
+// no longer exist are removed from memory, so events may be duplicated
+// or reordered. Change hooks are called as for any other load.
+func (sys *IAMSys) ApplyRemoteChange(ev IAMChangeEvent) error {
+	if !sys.Initialized() {
+		return errServerNotInitialized
+	}
+
+	if ev.Name == "" {
+		return fmt.Errorf("missing name in IAM change event: %w", errInvalidArgument)
+	}
+
+	switch ev.ObjectType {
+	case IAMObjectUser:
+		return sys.applyRemoteUserChange(ev.Name)
+	case IAMObjectGroup:
+		return sys.LoadGroup(ev.Name)
+	case IAMObjectPolicy:
+		return sys.applyRemotePolicyChange(ev.Name)
+	case IAMObjectPolicyMapping:
+		userType := regularUser
+		if !ev.IsGroup {
+			sys.Lock()
+			if cred, ok := sys.iamUsersMap[ev.Name]; ok && cred.IsTemp() {
+				userType = stsUser
+			}
+			sys.Unlock()
+		}
+		return sys.LoadPolicyMapping(ev.Name, userType, ev.IsGroup)
+	}
+	return fmt.Errorf("unknown IAM object type %q: %w", ev.ObjectType, errInvalidArgument)
+}
+
+// applyRemoteUserChange - reloads a user, service account or temporary
+// user of any type, or removes it from memory if it is not stored
+// anymore.
+func (sys *IAMSys) applyRemoteUserChange(accessKey string) error {
+	// Try the type of the known credentials first.
+	userTypes := []IAMUserType{regularUser, srvAccUser, stsUser}
+	sys.Lock()
+	if cred, ok := sys.iamUsersMap[accessKey]; ok {
+		switch {
+		case cred.IsTemp():
+			userTypes = []IAMUserType{stsUser, regularUser, srvAccUser}
+		case cred.IsServiceAccount():
+			userTypes = []IAMUserType{srvAccUser, regularUser, stsUser}
+		}
+	}
+	sys.Unlock()
+
+	for _, userType := range userTypes {
+		err := sys.LoadUser(accessKey, userType)
+		if !errors.Is(err, errNoSuchUser) {
+			return err
+		}
+	}
+
+	sys.Lock()
+	_, existed := sys.iamUsersMap[accessKey]
+	sys.removeUserFromMemory(accessKey)
+	sys.Unlock()
+
+	if existed {
+		sys.notifyChange(IAMChangeEvent{ObjectType: IAMObjectUser, Name: accessKey, Action: IAMChangeDelete})
+	}
+	return nil
+}
+
+// forgetUser - removes a user, service account or temporary user of
+// userType which is not stored anymore from memory, and calls the
+// change hooks if it was known. Credentials of another type with the
+// same access key are kept.
+func (sys *IAMSys) forgetUser(accessKey string, userType IAMUserType) {
+	sys.Lock()
+	cred, existed := sys.iamUsersMap[accessKey]
+	if existed {
+		credType := regularUser
+		switch {
+		case cred.IsTemp():
+			credType = stsUser
+		case cred.IsServiceAccount():
+			credType = srvAccUser
+		}
+		existed = credType == userType
+	}
+	if existed {
+		sys.removeUserFromMemory(accessKey)
+	}
+	sys.Unlock()
+
+	if existed {
+		sys.notifyChange(IAMChangeEvent{ObjectType: IAMObjectUser, Name: accessKey, Action: IAMChangeDelete})
+	}
+}
+
+// removeUserFromMemory - removes everything known about a user from
+// memory. IMPORTANT: Assumes that sys.Lock is held by caller.
+func (sys *IAMSys) removeUserFromMemory(accessKey string) {
+	delete(sys.iamUsersMap, accessKey)
+	delete(sys.iamUserPolicyMap, accessKey)
+	delete(sys.iamUserGroupMemberships, accessKey)
+	delete(sys.iamSecretRotations, accessKey)
+	delete(sys.iamAdditionalSecretKeys, accessKey)
+	sys.iamMFARequired.Remove(accessKey)
+	delete(sys.iamUserTags, accessKey)
+}
+
+// applyRemotePolicyChange - reloads a canned policy, or removes it from
+// memory if it is not stored anymore.
+func (sys *IAMSys) applyRemotePolicyChange(policyName string) error {
+	err := sys.LoadPolicy(policyName)
+	if errors.Is(err, errNoSuchPolicy) {
+		// LoadPolicy removed it from memory.
+		return nil
+	}
+	return err
+}
+
+// forgetPolicy - removes a canned policy which is not stored anymore
+// from memory, or restores the default canned policy of that name,
+// and calls the change hooks if a custom policy was known.
+func (sys *IAMSys) forgetPolicy(policyName string) {
+	defaults := make(map[string]iampolicy.Policy)
+	setDefaultCannedPolicies(defaults)
+
+	sys.Lock()
+	_, existed := sys.iamPolicyDocsMap[policyName]
+	d, isDefault := defaults[policyName]
+	if isDefault {
+		// Back to the default canned policy.
+		sys.iamPolicyDocsMap[policyName] = d
+		sys.iamLazyPolicies.Remove(policyName)
+	} else {
+		delete(sys.iamPolicyDocsMap, policyName)
+	}
+	sys.invalidateCombinedPolicies()
+	sys.Unlock()
+
+	if existed && !isDefault {
+		sys.notifyChange(IAMChangeEvent{ObjectType: IAMObjectPolicy, Name: policyName, Action: IAMChangeDelete})
+	}
+}
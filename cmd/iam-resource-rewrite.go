@@ -0,0 +1,80 @@
+/*
+ * MinIO Cloud Storage, (C) 2021 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/minio/minio/cmd/logger"
+	iampolicy "github.com/minio/minio/pkg/iam/policy"
+)
+
+// IAMResourceRewriter - transforms a resource ARN of a canned policy,
+// e.g. "arn:aws:s3:::mybucket/*", returning it unchanged keeps it.
+type IAMResourceRewriter func(resource string) string
+
+// SetResourceRewriter - registers fn to rewrite the resources of canned
+// policies as they are loaded from the store or set, replacing any
+// previous rewriter. Only the in-memory policies are rewritten, the
+// stored ones, exports and renamed copies keep the original resources.
+// It should be set before Init, policies already loaded are rewritten
+// on their next load. A nil fn disables rewriting.
+func (sys *IAMSys) SetResourceRewriter(fn IAMResourceRewriter) {
+	sys.resourceRewriter.Store(fn)
+}
+
+// rewriteResources - returns p with its resources rewritten, or p
+// itself when no rewriter is set. Resources rewritten into invalid
+// ARNs are kept unchanged.
+func (sys *IAMSys) rewriteResources(p iampolicy.Policy) iampolicy.Policy {
+	rewrite, _ := sys.resourceRewriter.Load().(IAMResourceRewriter)
+	if rewrite == nil {
+		return p
+	}
+
+	statements := make([]iampolicy.Statement, len(p.Statements))
+	for i, st := range p.Statements {
+		resources := iampolicy.NewResourceSet()
+		for r := range st.Resources {
+			arn := rewrite(r.String())
+			pattern := strings.TrimPrefix(arn, iampolicy.ResourceARNPrefix)
+			tokens := strings.SplitN(pattern, "/", 2)
+			if pattern == arn || tokens[0] == "" {
+				logger.LogIf(GlobalContext, fmt.Errorf("invalid resource %q rewritten from %q, keeping it unchanged", arn, r.String()))
+				resources.Add(r)
+				continue
+			}
+			resources.Add(iampolicy.Resource{BucketName: tokens[0], Pattern: pattern})
+		}
+		st.Resources = resources
+		statements[i] = st
+	}
+	p.Statements = statements
+	return p
+}
+
+// rewriteAllResources - rewrites the resources of all policies of m in
+// place.
+func (sys *IAMSys) rewriteAllResources(m map[string]iampolicy.Policy) {
+	if rewrite, _ := sys.resourceRewriter.Load().(IAMResourceRewriter); rewrite == nil {
+		return
+	}
+	for name, p := range m {
+		m[name] = sys.rewriteResources(p)
+	}
+}
@@ -0,0 +1,79 @@
+/*
+ * MinIO Cloud Storage, (C) 2021 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// minSecretKeyPrefixLen - shortest prefix accepted by
+// FindAccessKeysBySecretPrefix, shorter ones match too many
+// credentials to be of any use and only help guessing secrets.
+const minSecretKeyPrefixLen = 8
+
+// FindAccessKeysBySecretPrefix - returns the access keys, sorted, of all
+// users, service accounts and temporary credentials with a valid secret
+// key starting with prefix, including the additional and previous
+// secret keys of users.
+//
+// This is a break-glass tool to find the owner of a leaked secret key
+// fragment during an incident. It turns the IAM sub-system into an
+// oracle for secret keys: anyone able to call it can confirm guesses
+// about secrets of all accounts, a few characters at a time. It is
+// therefore disabled unless envIAMSecretLookup is enabled, should only
+// be enabled for the duration of an incident and must only be exposed
+// to the root user. The matches are never logged.
+func (sys *IAMSys) FindAccessKeysBySecretPrefix(prefix string) ([]string, error) {
+	if !sys.Initialized() {
+		return nil, errServerNotInitialized
+	}
+
+	if !sys.secretLookup {
+		return nil, errIAMSecretLookupDisabled
+	}
+
+	if len(prefix) < minSecretKeyPrefixLen {
+		return nil, fmt.Errorf("secret key prefix must be at least %d characters: %w", minSecretKeyPrefixLen, errInvalidArgument)
+	}
+
+	<-sys.configLoaded
+
+	sys.Lock()
+	defer sys.Unlock()
+
+	var accessKeys []string
+	now := UTCNow()
+	for k, v := range sys.iamUsersMap {
+		if v.IsExpired() {
+			continue
+		}
+		match := strings.HasPrefix(v.SecretKey, prefix)
+		for _, secretKey := range sys.iamAdditionalSecretKeys[k] {
+			match = match || strings.HasPrefix(secretKey, prefix)
+		}
+		if r, ok := sys.iamSecretRotations[k]; ok && now.Before(r.expiry) {
+			match = match || strings.HasPrefix(r.previousSecretKey, prefix)
+		}
+		if match {
+			accessKeys = append(accessKeys, k)
+		}
+	}
+	sort.Strings(accessKeys)
+	return accessKeys, nil
+}
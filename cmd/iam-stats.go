@@ -0,0 +1,71 @@
+/*
+ * MinIO Cloud Storage, (C) 2021 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"sync/atomic"
+)
+
+// IAMStats - represents IAM cache hits, cache misses
+// which triggered a store load, policy evaluations and
+// full reloads of the IAM sub-system.
+type IAMStats struct {
+	CacheHits         uint64
+	CacheMisses       uint64
+	PolicyEvaluations uint64
+	Reloads           uint64
+}
+
+// Increase cache hits by 1
+func (s *IAMStats) incCacheHit() {
+	atomic.AddUint64(&s.CacheHits, 1)
+}
+
+// Increase cache misses by 1
+func (s *IAMStats) incCacheMiss() {
+	atomic.AddUint64(&s.CacheMisses, 1)
+}
+
+// Increase policy evaluations by 1
+func (s *IAMStats) incPolicyEvaluation() {
+	atomic.AddUint64(&s.PolicyEvaluations, 1)
+}
+
+// Increase full reloads by 1
+func (s *IAMStats) incReload() {
+	atomic.AddUint64(&s.Reloads, 1)
+}
+
+// Get total cache hits
+func (s *IAMStats) getCacheHits() uint64 {
+	return atomic.LoadUint64(&s.CacheHits)
+}
+
+// Get total cache misses
+func (s *IAMStats) getCacheMisses() uint64 {
+	return atomic.LoadUint64(&s.CacheMisses)
+}
+
+// Get total policy evaluations
+func (s *IAMStats) getPolicyEvaluations() uint64 {
+	return atomic.LoadUint64(&s.PolicyEvaluations)
+}
+
+// Get total full reloads
+func (s *IAMStats) getReloads() uint64 {
+	return atomic.LoadUint64(&s.Reloads)
+}
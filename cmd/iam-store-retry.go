@@ -0,0 +1,150 @@
+/*
+ * MinIO Cloud Storage, (C) 2021 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// Defaults of the retries of IAM store writes, see
+// envIAMStoreWriteRetries.
+const (
+	defaultIAMStoreWriteRetries = 3
+	iamStoreRetryBackoff        = 100 * time.Millisecond
+	iamStoreRetryMaxBackoff     = time.Second
+	iamStoreRetryTimeout        = 5 * time.Second
+)
+
+// iamRetryStore - retries the save and delete operations of the wrapped
+// store on transient backend errors, with exponential backoff. No retry
+// is started once timeout elapsed since the first attempt.
+type iamRetryStore struct {
+	IAMStorageAPI
+
+	retries    int
+	backoff    time.Duration
+	maxBackoff time.Duration
+	timeout    time.Duration
+}
+
+func newIAMRetryStore(store IAMStorageAPI, retries int) *iamRetryStore {
+	return &iamRetryStore{
+		IAMStorageAPI: store,
+		retries:       retries,
+		backoff:       iamStoreRetryBackoff,
+		maxBackoff:    iamStoreRetryMaxBackoff,
+		timeout:       iamStoreRetryTimeout,
+	}
+}
+
+// isIAMStoreWriteRetriable - reports whether a failed write may succeed
+// when tried again. Missing items are final, deleting them again won't
+// help.
+func isIAMStoreWriteRetriable(err error) bool {
+	return configRetriableErrors(err) &&
+		!errors.Is(err, errConfigNotFound) &&
+		!isErrBucketNotFound(err)
+}
+
+func (s *iamRetryStore) retry(ctx context.Context, fn func() error) error {
+	deadline := time.Now().Add(s.timeout)
+	backoff := s.backoff
+	for attempt := 0; ; attempt++ {
+		err := fn()
+		if err == nil || attempt >= s.retries || !isIAMStoreWriteRetriable(err) {
+			return err
+		}
+		if time.Now().Add(backoff).After(deadline) {
+			return err
+		}
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+
+		backoff *= 2
+		if backoff > s.maxBackoff {
+			backoff = s.maxBackoff
+		}
+	}
+}
+
+func (s *iamRetryStore) saveIAMConfig(ctx context.Context, item interface{}, path string, opts ...options) error {
+	return s.retry(ctx, func() error {
+		return s.IAMStorageAPI.saveIAMConfig(ctx, item, path, opts...)
+	})
+}
+
+func (s *iamRetryStore) deleteIAMConfig(ctx context.Context, path string) error {
+	return s.retry(ctx, func() error {
+		return s.IAMStorageAPI.deleteIAMConfig(ctx, path)
+	})
+}
+
+func (s *iamRetryStore) savePolicyDoc(ctx context.Context, policyName string, d PolicyDoc) error {
+	return s.retry(ctx, func() error {
+		return s.IAMStorageAPI.savePolicyDoc(ctx, policyName, d)
+	})
+}
+
+func (s *iamRetryStore) saveMappedPolicy(ctx context.Context, name string, userType IAMUserType, isGroup bool, mp MappedPolicy, opts ...options) error {
+	return s.retry(ctx, func() error {
+		return s.IAMStorageAPI.saveMappedPolicy(ctx, name, userType, isGroup, mp, opts...)
+	})
+}
+
+func (s *iamRetryStore) saveUserIdentity(ctx context.Context, name string, userType IAMUserType, u UserIdentity, opts ...options) error {
+	return s.retry(ctx, func() error {
+		return s.IAMStorageAPI.saveUserIdentity(ctx, name, userType, u, opts...)
+	})
+}
+
+func (s *iamRetryStore) saveGroupInfo(ctx context.Context, group string, gi GroupInfo) error {
+	return s.retry(ctx, func() error {
+		return s.IAMStorageAPI.saveGroupInfo(ctx, group, gi)
+	})
+}
+
+func (s *iamRetryStore) deletePolicyDoc(ctx context.Context, policyName string) error {
+	return s.retry(ctx, func() error {
+		return s.IAMStorageAPI.deletePolicyDoc(ctx, policyName)
+	})
+}
+
+func (s *iamRetryStore) deleteMappedPolicy(ctx context.Context, name string, userType IAMUserType, isGroup bool) error {
+	return s.retry(ctx, func() error {
+		return s.IAMStorageAPI.deleteMappedPolicy(ctx, name, userType, isGroup)
+	})
+}
+
+func (s *iamRetryStore) deleteUserIdentity(ctx context.Context, name string, userType IAMUserType) error {
+	return s.retry(ctx, func() error {
+		return s.IAMStorageAPI.deleteUserIdentity(ctx, name, userType)
+	})
+}
+
+func (s *iamRetryStore) deleteGroupInfo(ctx context.Context, name string) error {
+	return s.retry(ctx, func() error {
+		return s.IAMStorageAPI.deleteGroupInfo(ctx, name)
+	})
+}
@@ -0,0 +1,89 @@
+/*
+ * MinIO Cloud Storage, (C) 2021 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"errors"
+	"time"
+)
+
+// defaultSTSRateLimit - default of envIAMSTSRateLimit.
+const defaultSTSRateLimit = 600
+
+// maxSTSRateLimiters - number of parent users tracked before the
+// limiters which are full again are dropped.
+const maxSTSRateLimiters = 10000
+
+// stsRateLimiter - token bucket limiting the STS credentials created
+// for one parent user.
+type stsRateLimiter struct {
+	tokens float64
+	last   time.Time
+}
+
+// refill - adds the tokens earned since the last refill, up to burst.
+func (l *stsRateLimiter) refill(now time.Time, perSecond, burst float64) {
+	l.tokens += now.Sub(l.last).Seconds() * perSecond
+	if l.tokens > burst {
+		l.tokens = burst
+	}
+	l.last = now
+}
+
+// allowSTSCredential - reports whether STS credentials may be created
+// for parentUser now, consuming a token if so. Up to stsRateLimit
+// credentials may be created at once, then stsRateLimit per minute.
+// IMPORTANT: Assumes that sys.Lock is held by caller.
+func (sys *IAMSys) allowSTSCredential(parentUser string, now time.Time) bool {
+	if sys.stsRateLimit <= 0 || parentUser == "" {
+		return true
+	}
+
+	burst := float64(sys.stsRateLimit)
+	perSecond := burst / 60
+
+	l, ok := sys.stsRateLimiters[parentUser]
+	if !ok {
+		if len(sys.stsRateLimiters) >= maxSTSRateLimiters {
+			// Limiters which are full again are the same as
+			// new ones.
+			for parent, pl := range sys.stsRateLimiters {
+				if pl.refill(now, perSecond, burst); pl.tokens >= burst {
+					delete(sys.stsRateLimiters, parent)
+				}
+			}
+		}
+		l = &stsRateLimiter{tokens: burst, last: now}
+		sys.stsRateLimiters[parentUser] = l
+	}
+
+	l.refill(now, perSecond, burst)
+	if l.tokens < 1 {
+		return false
+	}
+	l.tokens--
+	return true
+}
+
+// setTempUserErrCode - returns the STS error code for an error of
+// SetTempUser.
+func setTempUserErrCode(err error) STSErrorCode {
+	if errors.Is(err, errSTSRateLimited) {
+		return ErrSTSTooManyRequests
+	}
+	return ErrSTSInternalError
+}
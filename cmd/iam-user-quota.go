@@ -0,0 +1,376 @@
+/*
+ * MinIO Cloud Storage, (C) 2021 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/minio/minio/cmd/logger"
+)
+
+// UserQuota - limits of what a user, along with its service accounts
+// and STS credentials, may consume. A zero field means no limit.
+type UserQuota struct {
+	// Maximum number of buckets the user may own.
+	MaxBuckets int `json:"maxBuckets,omitempty"`
+}
+
+// IsZero - reports whether the quota doesn't limit anything.
+func (q UserQuota) IsZero() bool {
+	return q.MaxBuckets == 0
+}
+
+// userQuotas - contents of the user quotas file, quotas by username
+// and the buckets counted against them by username.
+type userQuotas struct {
+	Version int                  `json:"version"`
+	Quotas  map[string]UserQuota `json:"quotas"`
+	Buckets map[string][]string  `json:"buckets,omitempty"`
+}
+
+const userQuotasVersion1 = 1
+
+func getUserQuotasPath() string {
+	return iamConfigPrefix + SlashSeparator + iamUserQuotasFile
+}
+
+// loadUserQuotas - returns the user quotas saved in the store, none if
+// the quotas file doesn't exist yet.
+func loadUserQuotas(ctx context.Context, store IAMStorageAPI) (userQuotas, error) {
+	var q userQuotas
+	if err := store.loadIAMConfig(ctx, &q, getUserQuotasPath()); err != nil {
+		if !errors.Is(err, errConfigNotFound) {
+			return q, err
+		}
+	}
+	if q.Quotas == nil {
+		q.Quotas = make(map[string]UserQuota)
+	}
+	if q.Buckets == nil {
+		q.Buckets = make(map[string][]string)
+	}
+	return q, nil
+}
+
+func saveUserQuotas(ctx context.Context, store IAMStorageAPI, q userQuotas) error {
+	q.Version = userQuotasVersion1
+	return store.saveIAMConfig(ctx, q, getUserQuotasPath())
+}
+
+// setUserQuotas - replaces the user quotas in memory.
+// IMPORTANT: Assumes that sys.Lock is held by caller.
+func (sys *IAMSys) setUserQuotas(q userQuotas) {
+	sys.iamUserQuotas = q.Quotas
+	sys.iamUserBuckets = q.Buckets
+}
+
+// SetUserQuota - sets the quota of a user, a zero quota removes it.
+func (sys *IAMSys) SetUserQuota(accessKey string, quota UserQuota) error {
+	if !sys.Initialized() {
+		return errServerNotInitialized
+	}
+
+	if sys.readOnly {
+		return errIAMReadOnly
+	}
+
+	accessKey = sys.normalizeAccessKey(accessKey)
+
+	if sys.usersSysType != MinIOUsersSysType {
+		return errIAMActionNotAllowed
+	}
+
+	if quota.MaxBuckets < 0 {
+		return fmt.Errorf("maximum number of buckets must not be negative: %w", errInvalidArgument)
+	}
+
+	if err := sys.lockStore(); err != nil {
+		return err
+	}
+	defer sys.store.unlock()
+
+	sys.Lock()
+	cred, ok := sys.iamUsersMap[accessKey]
+	sys.Unlock()
+	if !ok || cred.IsTemp() || cred.IsServiceAccount() {
+		return errNoSuchUser
+	}
+
+	// Reload the quotas file, other servers may have changed it.
+	ctx := context.Background()
+	quotas, err := loadUserQuotas(ctx, sys.store)
+	if err != nil {
+		return err
+	}
+	if quota.IsZero() {
+		if _, ok := quotas.Quotas[accessKey]; !ok {
+			return nil
+		}
+		delete(quotas.Quotas, accessKey)
+		delete(quotas.Buckets, accessKey)
+	} else {
+		quotas.Quotas[accessKey] = quota
+	}
+	if err = saveUserQuotas(ctx, sys.store, quotas); err != nil {
+		return err
+	}
+
+	sys.Lock()
+	defer sys.Unlock()
+
+	sys.setUserQuotas(quotas)
+	return nil
+}
+
+// GetUserQuota - returns the quota of a user, a zero quota if it has
+// none.
+func (sys *IAMSys) GetUserQuota(accessKey string) (UserQuota, error) {
+	if !sys.Initialized() {
+		return UserQuota{}, errServerNotInitialized
+	}
+
+	accessKey = sys.normalizeAccessKey(accessKey)
+
+	if sys.usersSysType != MinIOUsersSysType {
+		return UserQuota{}, errIAMActionNotAllowed
+	}
+
+	<-sys.configLoaded
+
+	sys.Lock()
+	defer sys.Unlock()
+
+	cred, ok := sys.iamUsersMap[accessKey]
+	if !ok || cred.IsTemp() || cred.IsServiceAccount() {
+		return UserQuota{}, errNoSuchUser
+	}
+	return sys.iamUserQuotas[accessKey], nil
+}
+
+// GetUserBuckets - returns the buckets counted against the bucket
+// quota of a user.
+func (sys *IAMSys) GetUserBuckets(accessKey string) ([]string, error) {
+	if !sys.Initialized() {
+		return nil, errServerNotInitialized
+	}
+
+	accessKey = sys.normalizeAccessKey(accessKey)
+
+	<-sys.configLoaded
+
+	sys.Lock()
+	defer sys.Unlock()
+
+	return append([]string(nil), sys.iamUserBuckets[accessKey]...), nil
+}
+
+// quotaUser - returns the user whose quota applies to accessKey, its
+// parent user for service accounts and STS credentials.
+// IMPORTANT: Assumes that sys.Lock is held by caller.
+func (sys *IAMSys) quotaUser(accessKey string) string {
+	if cred, ok := sys.iamUsersMap[accessKey]; ok && cred.ParentUser != "" {
+		return cred.ParentUser
+	}
+	return accessKey
+}
+
+// ReserveUserBucket - counts bucket against the bucket quota of the
+// user accessKey belongs to before the bucket is created, failing with
+// errUserBucketQuotaExceeded if the user already owns as many buckets
+// as allowed. Service accounts and STS credentials are subject to the
+// quota of their parent user. Buckets of users without a quota are not
+// counted, so buckets created before a quota was set don't count
+// against it either. Returns whether the bucket was newly counted,
+// ReleaseUserBucket must be called for it if creating it fails.
+func (sys *IAMSys) ReserveUserBucket(accessKey, bucket string) (bool, error) {
+	if !sys.Initialized() {
+		return false, errServerNotInitialized
+	}
+
+	if sys.usersSysType != MinIOUsersSysType {
+		return false, nil
+	}
+
+	accessKey = sys.normalizeAccessKey(accessKey)
+
+	sys.Lock()
+	user := sys.quotaUser(accessKey)
+	_, limited := sys.iamUserQuotas[user]
+	sys.Unlock()
+	if !limited {
+		return false, nil
+	}
+
+	if sys.readOnly {
+		return false, errIAMReadOnly
+	}
+
+	if err := sys.lockStore(); err != nil {
+		return false, err
+	}
+	defer sys.store.unlock()
+
+	// Reload the quotas file, other servers may have changed it.
+	ctx := context.Background()
+	quotas, err := loadUserQuotas(ctx, sys.store)
+	if err != nil {
+		return false, err
+	}
+	quota, ok := quotas.Quotas[user]
+	if !ok {
+		return false, nil
+	}
+	buckets := quotas.Buckets[user]
+	for _, b := range buckets {
+		if b == bucket {
+			return false, nil
+		}
+	}
+	if quota.MaxBuckets > 0 && len(buckets) >= quota.MaxBuckets {
+		return false, errUserBucketQuotaExceeded
+	}
+	quotas.Buckets[user] = append(buckets, bucket)
+	if err = saveUserQuotas(ctx, sys.store, quotas); err != nil {
+		return false, err
+	}
+
+	sys.Lock()
+	defer sys.Unlock()
+
+	sys.setUserQuotas(quotas)
+	return true, nil
+}
+
+// ReleaseUserBucket - stops counting bucket against the bucket quota of
+// the user accessKey belongs to, or of any user it is counted for when
+// accessKey is empty. Called with the access key that reserved the
+// bucket when it could not be created after all, so a bucket owned by
+// another user stays counted for its owner, and with an empty access
+// key when the bucket is deleted.
+func (sys *IAMSys) ReleaseUserBucket(accessKey, bucket string) error {
+	if !sys.Initialized() {
+		return errServerNotInitialized
+	}
+
+	if sys.usersSysType != MinIOUsersSysType {
+		return nil
+	}
+
+	accessKey = sys.normalizeAccessKey(accessKey)
+
+	sys.Lock()
+	var user string
+	if accessKey != "" {
+		user = sys.quotaUser(accessKey)
+	}
+	var counted bool
+	for u, buckets := range sys.iamUserBuckets {
+		if user != "" && u != user {
+			continue
+		}
+		for _, b := range buckets {
+			if b == bucket {
+				counted = true
+			}
+		}
+	}
+	sys.Unlock()
+	if !counted {
+		return nil
+	}
+
+	if sys.readOnly {
+		return errIAMReadOnly
+	}
+
+	if err := sys.lockStore(); err != nil {
+		return err
+	}
+	defer sys.store.unlock()
+
+	ctx := context.Background()
+	quotas, err := loadUserQuotas(ctx, sys.store)
+	if err != nil {
+		return err
+	}
+	var changed bool
+	for u, buckets := range quotas.Buckets {
+		if user != "" && u != user {
+			continue
+		}
+		kept := buckets[:0]
+		for _, b := range buckets {
+			if b != bucket {
+				kept = append(kept, b)
+			}
+		}
+		if len(kept) == len(buckets) {
+			continue
+		}
+		changed = true
+		if len(kept) == 0 {
+			delete(quotas.Buckets, u)
+		} else {
+			quotas.Buckets[u] = kept
+		}
+	}
+	if !changed {
+		return nil
+	}
+	if err = saveUserQuotas(ctx, sys.store, quotas); err != nil {
+		return err
+	}
+
+	sys.Lock()
+	defer sys.Unlock()
+
+	sys.setUserQuotas(quotas)
+	return nil
+}
+
+// deleteUserQuotas - removes the quotas of deleted users from the store
+// and memory. IMPORTANT: Assumes that the store lock is held by caller.
+func (sys *IAMSys) deleteUserQuotas(ctx context.Context, users ...string) {
+	quotas, err := loadUserQuotas(ctx, sys.store)
+	if err != nil {
+		logger.LogIf(ctx, err)
+		return
+	}
+
+	var changed bool
+	for _, user := range users {
+		if _, ok := quotas.Quotas[user]; ok {
+			delete(quotas.Quotas, user)
+			delete(quotas.Buckets, user)
+			changed = true
+		}
+	}
+	if !changed {
+		return
+	}
+	if err = saveUserQuotas(ctx, sys.store, quotas); err != nil {
+		logger.LogIf(ctx, err)
+		return
+	}
+
+	sys.Lock()
+	defer sys.Unlock()
+
+	sys.setUserQuotas(quotas)
+}
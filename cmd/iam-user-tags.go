@@ -0,0 +1,149 @@
+/*
+ * MinIO Cloud Storage, (C) 2021 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"unicode/utf8"
+)
+
+// Limits of the tags of a user or service account, the same as for
+// AWS IAM tags.
+const (
+	maxUserTags           = 50
+	maxUserTagKeyLength   = 128
+	maxUserTagValueLength = 256
+)
+
+// validateUserTags - checks tags against the limits of user tags.
+func validateUserTags(tags map[string]string) error {
+	if len(tags) > maxUserTags {
+		return fmt.Errorf("%d tags exceed the maximum of %d: %w", len(tags), maxUserTags, errInvalidArgument)
+	}
+	for k, v := range tags {
+		if k == "" || utf8.RuneCountInString(k) > maxUserTagKeyLength {
+			return fmt.Errorf("tag key %q must have 1 to %d characters: %w", k, maxUserTagKeyLength, errInvalidArgument)
+		}
+		if utf8.RuneCountInString(v) > maxUserTagValueLength {
+			return fmt.Errorf("value of tag %s exceeds %d characters: %w", k, maxUserTagValueLength, errInvalidArgument)
+		}
+	}
+	return nil
+}
+
+// SetUserTags - replaces the tags of a user or service account, an
+// empty map removes them. Tags are kept for governance tooling and are
+// never used for authorization.
+func (sys *IAMSys) SetUserTags(accessKey string, tags map[string]string) error {
+	if !sys.Initialized() {
+		return errServerNotInitialized
+	}
+
+	if sys.readOnly {
+		return errIAMReadOnly
+	}
+
+	accessKey = sys.normalizeAccessKey(accessKey)
+
+	if err := validateUserTags(tags); err != nil {
+		return err
+	}
+
+	if err := sys.lockStore(); err != nil {
+		return err
+	}
+	defer sys.store.unlock()
+
+	sys.Lock()
+	cred, ok := sys.iamUsersMap[accessKey]
+	sys.Unlock()
+	if !ok {
+		return errNoSuchUser
+	}
+
+	userType := regularUser
+	switch {
+	case cred.IsServiceAccount():
+		userType = srvAccUser
+	case cred.IsTemp():
+		return errIAMActionNotAllowed
+	case sys.usersSysType != MinIOUsersSysType:
+		return errIAMActionNotAllowed
+	}
+
+	u, err := sys.store.getUserIdentity(context.Background(), accessKey, userType)
+	if err != nil {
+		return err
+	}
+	u.Tags = nil
+	if len(tags) > 0 {
+		u.Tags = make(map[string]string, len(tags))
+		for k, v := range tags {
+			u.Tags[k] = v
+		}
+	}
+	if err = sys.store.saveUserIdentity(context.Background(), accessKey, userType, u); err != nil {
+		return err
+	}
+
+	sys.Lock()
+	defer sys.Unlock()
+
+	sys.setUserTags(accessKey, u)
+	return nil
+}
+
+// GetUserTags - returns a copy of the tags of a user or service
+// account, nil when it has none.
+func (sys *IAMSys) GetUserTags(accessKey string) (map[string]string, error) {
+	if !sys.Initialized() {
+		return nil, errServerNotInitialized
+	}
+
+	accessKey = sys.normalizeAccessKey(accessKey)
+
+	<-sys.configLoaded
+
+	sys.Lock()
+	defer sys.Unlock()
+
+	if _, ok := sys.iamUsersMap[accessKey]; !ok {
+		return nil, errNoSuchUser
+	}
+
+	tags := sys.iamUserTags[accessKey]
+	if len(tags) == 0 {
+		return nil, nil
+	}
+	copied := make(map[string]string, len(tags))
+	for k, v := range tags {
+		copied[k] = v
+	}
+	return copied, nil
+}
+
+// setUserTags - records the tags of the given identity. Maps of tags
+// are replaced, never modified, so they may be shared with callers.
+// IMPORTANT: Assumes that sys.Lock is held by caller.
+func (sys *IAMSys) setUserTags(accessKey string, u UserIdentity) {
+	if len(u.Tags) == 0 {
+		delete(sys.iamUserTags, accessKey)
+		return
+	}
+	sys.iamUserTags[accessKey] = u.Tags
+}
@@ -0,0 +1,246 @@
+/*
+ * MinIO Cloud Storage, (C) 2021 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/minio/minio-go/v7/pkg/set"
+	"github.com/minio/minio/cmd/logger"
+)
+
+// Interval at which tombstones of deleted users past the undo window
+// are purged.
+const iamUserTombstonePurgeInterval = time.Hour
+
+// userTombstone - what is needed to restore a deleted user.
+type userTombstone struct {
+	DeletedAt time.Time    `json:"deletedAt"`
+	Identity  UserIdentity `json:"identity"`
+	Policy    MappedPolicy `json:"policy"`
+	Groups    []string     `json:"groups,omitempty"`
+}
+
+// userTombstones - contents of the deleted users file, tombstones by
+// username.
+type userTombstones struct {
+	Version int                      `json:"version"`
+	Users   map[string]userTombstone `json:"users"`
+}
+
+const userTombstonesVersion1 = 1
+
+func getUserTombstonesPath() string {
+	return iamConfigPrefix + SlashSeparator + iamDeletedUsersFile
+}
+
+func loadUserTombstones(ctx context.Context, store IAMStorageAPI) (map[string]userTombstone, error) {
+	var t userTombstones
+	if err := store.loadIAMConfig(ctx, &t, getUserTombstonesPath()); err != nil {
+		if errors.Is(err, errConfigNotFound) {
+			return make(map[string]userTombstone), nil
+		}
+		return nil, err
+	}
+	if t.Users == nil {
+		t.Users = make(map[string]userTombstone)
+	}
+	return t.Users, nil
+}
+
+func saveUserTombstones(ctx context.Context, store IAMStorageAPI, tombstones map[string]userTombstone) error {
+	return store.saveIAMConfig(ctx, userTombstones{Version: userTombstonesVersion1, Users: tombstones}, getUserTombstonesPath())
+}
+
+// saveUserTombstonesFor - saves the tombstones of users about to be
+// deleted, along with the groups they are members of by username.
+// Users already gone from the store are skipped. IMPORTANT: Assumes
+// that the store lock is held by caller.
+func (sys *IAMSys) saveUserTombstonesFor(ctx context.Context, memberOf map[string][]string) error {
+	tombstones, err := loadUserTombstones(ctx, sys.store)
+	if err != nil {
+		return err
+	}
+
+	now := UTCNow()
+	for user, groups := range memberOf {
+		u, err := sys.store.getUserIdentity(ctx, user, regularUser)
+		if err != nil {
+			if errors.Is(err, errNoSuchUser) {
+				continue
+			}
+			return err
+		}
+		mp, err := sys.store.getMappedPolicy(ctx, user, regularUser, false)
+		if err != nil && !errors.Is(err, errNoSuchPolicy) {
+			return err
+		}
+		tombstones[user] = userTombstone{
+			DeletedAt: now,
+			Identity:  u,
+			Policy:    mp,
+			Groups:    groups,
+		}
+	}
+	return saveUserTombstones(ctx, sys.store, tombstones)
+}
+
+// RestoreUser - restores a user deleted less than the undo window ago,
+// with its policies and its memberships of groups which still exist.
+// Service accounts and temporary users deleted along with the user are
+// not restored.
+func (sys *IAMSys) RestoreUser(accessKey string) error {
+	if !sys.Initialized() {
+		return errServerNotInitialized
+	}
+
+	if sys.readOnly {
+		return errIAMReadOnly
+	}
+
+	accessKey = sys.normalizeAccessKey(accessKey)
+
+	if sys.usersSysType != MinIOUsersSysType {
+		return errIAMActionNotAllowed
+	}
+
+	if err := sys.lockStore(); err != nil {
+		return err
+	}
+	defer sys.store.unlock()
+
+	ctx := context.Background()
+	tombstones, err := loadUserTombstones(ctx, sys.store)
+	if err != nil {
+		return err
+	}
+	t, ok := tombstones[accessKey]
+	if !ok || !UTCNow().Before(t.DeletedAt.Add(sys.userUndoWindow)) {
+		return errNoSuchUser
+	}
+
+	if _, err = sys.store.getUserIdentity(ctx, accessKey, regularUser); err == nil {
+		return fmt.Errorf("user %s exists already: %w", accessKey, errInvalidArgument)
+	} else if !errors.Is(err, errNoSuchUser) {
+		return err
+	}
+	if err = sys.loadGroups(); err != nil {
+		return err
+	}
+
+	if err = sys.store.saveUserIdentity(ctx, accessKey, regularUser, t.Identity); err != nil {
+		return err
+	}
+	if len(t.Policy.toSlice()) > 0 {
+		if err = sys.store.saveMappedPolicy(ctx, accessKey, regularUser, false, t.Policy); err != nil {
+			return err
+		}
+	}
+
+	groups := make(map[string]GroupInfo)
+	sys.Lock()
+	for _, group := range t.Groups {
+		if gi, ok := sys.iamGroupsMap[group]; ok {
+			gi.Members = append(gi.Members, accessKey)
+			groups[group] = gi.normalize()
+		}
+	}
+	sys.Unlock()
+	for group, gi := range groups {
+		if err = sys.store.saveGroupInfo(ctx, group, gi); err != nil {
+			return err
+		}
+	}
+
+	delete(tombstones, accessKey)
+	if err = saveUserTombstones(ctx, sys.store, tombstones); err != nil {
+		// The user is restored already.
+		logger.LogIf(ctx, err)
+	}
+
+	sys.Lock()
+	sys.iamUsersMap[accessKey] = t.Identity.Credentials
+	delete(sys.negativeUserCache, accessKey)
+	if len(t.Policy.toSlice()) > 0 {
+		sys.iamUserPolicyMap[accessKey] = t.Policy
+	}
+	sys.setSecretRotation(accessKey, t.Identity)
+	sys.setAdditionalSecretKeys(accessKey, t.Identity)
+	sys.setMFARequired(accessKey, t.Identity)
+	sys.setUserTags(accessKey, t.Identity)
+	for group, gi := range groups {
+		sys.iamGroupsMap[group] = gi
+		gset := sys.iamUserGroupMemberships[accessKey]
+		if gset == nil {
+			gset = set.NewStringSet()
+		}
+		gset.Add(group)
+		sys.iamUserGroupMemberships[accessKey] = gset
+	}
+	sys.Unlock()
+
+	sys.notifyCredential(ctx, IAMCredentialEvent{Action: IAMCredentialCreated, Type: IAMCredentialUser, AccessKey: accessKey})
+	return nil
+}
+
+// purgeUserTombstones - periodically removes the tombstones of users
+// deleted longer than the undo window ago.
+func (sys *IAMSys) purgeUserTombstones(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if sys.readOnly {
+			continue
+		}
+		logger.LogIf(ctx, sys.purgeExpiredUserTombstones(ctx))
+	}
+}
+
+func (sys *IAMSys) purgeExpiredUserTombstones(ctx context.Context) error {
+	if err := sys.lockStore(); err != nil {
+		return err
+	}
+	defer sys.store.unlock()
+
+	tombstones, err := loadUserTombstones(ctx, sys.store)
+	if err != nil {
+		return err
+	}
+
+	var purged bool
+	now := UTCNow()
+	for user, t := range tombstones {
+		if !now.Before(t.DeletedAt.Add(sys.userUndoWindow)) {
+			delete(tombstones, user)
+			purged = true
+		}
+	}
+	if !purged {
+		return nil
+	}
+	return saveUserTombstones(ctx, sys.store, tombstones)
+}
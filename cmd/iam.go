@@ -19,20 +19,30 @@ package cmd
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
+	"crypto/subtle"
 	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"math/rand"
 	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	humanize "github.com/dustin/go-humanize"
 	"github.com/minio/minio-go/v7/pkg/set"
+	"github.com/minio/minio/cmd/config"
 	"github.com/minio/minio/cmd/logger"
 	"github.com/minio/minio/pkg/auth"
+	"github.com/minio/minio/pkg/bucket/policy"
+	"github.com/minio/minio/pkg/env"
 	iampolicy "github.com/minio/minio/pkg/iam/policy"
 	"github.com/minio/minio/pkg/madmin"
 )
@@ -86,6 +96,16 @@ const (
 	// IAM group members file
 	iamGroupMembersFile = "members.json"
 
+	// IAM policy usage file, saved under the policies directory.
+	iamPolicyUsageFile = "usage.json"
+
+	// IAM user quotas file, quotas of all users.
+	iamUserQuotasFile = "quotas.json"
+
+	// IAM deleted users file, tombstones of the users which may
+	// still be restored.
+	iamDeletedUsersFile = "deleted-users.json"
+
 	// IAM format file
 	iamFormatFile = "format.json"
 
@@ -97,6 +117,144 @@ const (
 	statusDisabled = "disabled"
 )
 
+// Maximum number of distinct policy combinations for which the combined
+// policy is cached.
+const maxCombinedPolicyCacheEntries = 10000
+
+// Environment variables to tune the IAM sub-system.
+const (
+	// Maximum size of a canned policy document, e.g. "20KiB".
+	envIAMPolicyMaxSize = "MINIO_IAM_POLICY_MAX_SIZE"
+
+	// Maximum number of statements of a canned policy, 0 for no
+	// limit. Stored policies with more statements are not loaded.
+	envIAMPolicyMaxStatements = "MINIO_IAM_POLICY_MAX_STATEMENTS"
+
+	// Maximum size of the session policy embedded in a service
+	// account, e.g. "64KiB".
+	envIAMSessionPolicyMaxSize = "MINIO_IAM_SESSION_POLICY_MAX_SIZE"
+
+	// Treat access keys case-insensitively by lowercasing them,
+	// "on" or "off". Users created before turning this on keep
+	// their original keys on disk and in memory, so enabling it on
+	// an existing deployment requires migrating such users (i.e.
+	// re-creating them with lowercase access keys).
+	envIAMCaseInsensitiveAccessKeys = "MINIO_IAM_CASE_INSENSITIVE_ACCESS_KEYS"
+
+	// Periodically reload all of IAM from the store at this
+	// interval, e.g. "30s", instead of the default watch interval.
+	// Meant for read-only replicas which do not see changes made by
+	// a writer node otherwise.
+	envIAMReadThroughRefreshInterval = "MINIO_IAM_READ_THROUGH_REFRESH_INTERVAL"
+
+	// Remember the LDAP groups of STS credentials, "on" or "off",
+	// so that group descriptions can list their approximate
+	// current members in LDAP mode.
+	envIAMLDAPGroupMembershipCache = "MINIO_IAM_LDAP_GROUP_MEMBERSHIP_CACHE"
+
+	// Deny all requests of users which are members of a disabled
+	// group, "on" or "off". By default a disabled group merely
+	// stops contributing its policies and members keep the access
+	// granted by their own policies and other groups. In strict
+	// mode the membership overrides every other grant, including
+	// policies mapped directly to the user, which allows locking a
+	// whole team out by disabling its group.
+	envIAMStrictDisabledGroups = "MINIO_IAM_STRICT_DISABLED_GROUPS"
+
+	// Maximum lifetime of STS credentials, e.g. "1h". Longer
+	// requested durations are shortened to it.
+	envIAMSTSMaxDuration = "MINIO_IAM_STS_MAX_DURATION"
+
+	// Reject new users whose access key equals the name of a canned
+	// policy, a group or a reserved name, "on" or "off".
+	envIAMStrictNaming = "MINIO_IAM_STRICT_NAMING"
+
+	// Comma separated canned policies mapped to every new user in
+	// addition to the requested ones, e.g. "deny-delete-versioning".
+	envIAMDefaultUserPolicies = "MINIO_IAM_DEFAULT_USER_POLICIES"
+
+	// Maximum number of service accounts of a single parent user,
+	// 0 for no limit.
+	envIAMMaxServiceAccountsPerUser = "MINIO_IAM_MAX_SERVICE_ACCOUNTS_PER_USER"
+
+	// Report credentials which are not in memory and could not be
+	// loaded because of a transient store error as temporarily
+	// unavailable instead of unknown, "on" or "off".
+	envIAMStoreUnavailableError = "MINIO_IAM_STORE_UNAVAILABLE_ERROR"
+
+	// Enable FindAccessKeysBySecretPrefix, "on" or "off". Only meant
+	// to be turned on during incident response.
+	envIAMSecretLookup = "MINIO_IAM_SECRET_LOOKUP"
+
+	// Store canned policies compressed, "on" or "off". Policies stored
+	// either way are always loaded, but only by servers supporting
+	// compression, see iamCompressedConfigHeader.
+	envIAMCompressPolicies = "MINIO_IAM_COMPRESS_POLICIES"
+
+	// Maximum time IAM operations wait for the IAM store lock, e.g.
+	// "10s". By default the timeout adapts to the time the lock
+	// usually takes to be acquired.
+	envIAMLockTimeout = "MINIO_IAM_LOCK_TIMEOUT"
+
+	// Load only the names of canned policies at startup, "on" or
+	// "off". Policy documents are then read on first use.
+	envIAMLazyPolicyLoad = "MINIO_IAM_LAZY_POLICY_LOAD"
+
+	// Number of times writes to the IAM store are retried on
+	// transient backend errors, 0 disables retries.
+	envIAMStoreWriteRetries = "MINIO_IAM_STORE_WRITE_RETRIES"
+
+	// Maximum number of STS credentials created per minute for one
+	// parent user, as many may be created at once. 0 for no limit.
+	envIAMSTSRateLimit = "MINIO_IAM_STS_RATE_LIMIT"
+
+	// Time during which deleted users may be restored, e.g. "72h".
+	// Users are deleted for good when unset.
+	envIAMUserUndoWindow = "MINIO_IAM_USER_UNDO_WINDOW"
+)
+
+// Default timeout and minimum of the dynamic IAM store lock timeout,
+// see envIAMLockTimeout.
+const (
+	defaultIAMLockTimeout    = 30 * time.Second
+	defaultIAMLockTimeoutMin = 5 * time.Second
+)
+
+// defaultMaxServiceAccountsPerUser - default of
+// envIAMMaxServiceAccountsPerUser.
+const defaultMaxServiceAccountsPerUser = 1000
+
+// defaultPolicyMaxStatements - default of envIAMPolicyMaxStatements.
+const defaultPolicyMaxStatements = 1000
+
+// envPolicyMaxStatements - returns the maximum number of statements of
+// canned policies set by envIAMPolicyMaxStatements.
+func envPolicyMaxStatements() int {
+	v := env.Get(envIAMPolicyMaxStatements, "")
+	if v == "" {
+		return defaultPolicyMaxStatements
+	}
+	limit, err := strconv.Atoi(v)
+	if err != nil || limit < 0 {
+		logger.LogIf(GlobalContext, fmt.Errorf("invalid value for %s: %s", envIAMPolicyMaxStatements, v))
+		return defaultPolicyMaxStatements
+	}
+	return limit
+}
+
+// checkPolicyStatements - rejects policies with more than max
+// statements, zero allows any number.
+func checkPolicyStatements(p iampolicy.Policy, max int) error {
+	if max > 0 && len(p.Statements) > max {
+		return fmt.Errorf("policy has %d statements, exceeding the maximum of %d set by %s: %w",
+			len(p.Statements), max, envIAMPolicyMaxStatements, errPolicyTooManyStatements)
+	}
+	return nil
+}
+
+// Names which new users may not take when strict naming is enabled.
+var iamReservedNames = set.CreateStringSet("*", "root", "anonymous")
+
 type iamFormat struct {
 	Version int `json:"version"`
 }
@@ -119,18 +277,19 @@ func getUserIdentityPath(user string, userType IAMUserType) string {
 	default:
 		basePath = iamConfigUsersPrefix
 	}
-	return pathJoin(basePath, user, iamIdentityFile)
+	return pathJoin(basePath, iamPathName(user), iamIdentityFile)
 }
 
 func getGroupInfoPath(group string) string {
-	return pathJoin(iamConfigGroupsPrefix, group, iamGroupMembersFile)
+	return pathJoin(iamConfigGroupsPrefix, iamPathName(group), iamGroupMembersFile)
 }
 
 func getPolicyDocPath(name string) string {
-	return pathJoin(iamConfigPoliciesPrefix, name, iamPolicyFile)
+	return pathJoin(iamConfigPoliciesPrefix, iamPathName(name), iamPolicyFile)
 }
 
 func getMappedPolicyPath(name string, userType IAMUserType, isGroup bool) string {
+	name = iamPathName(name)
 	if isGroup {
 		return pathJoin(iamConfigPolicyDBGroupsPrefix, name+".json")
 	}
@@ -144,12 +303,50 @@ func getMappedPolicyPath(name string, userType IAMUserType, isGroup bool) string
 	}
 }
 
+// iamPathName - cleans a user, group or policy name used in a store
+// path, so that ".." elements can never reach outside of the prefix
+// the name is joined to. Valid names are returned as is.
+func iamPathName(name string) string {
+	return strings.TrimPrefix(path.Clean(SlashSeparator+name), SlashSeparator)
+}
+
+// checkIAMName - rejects non-empty user, group and policy names which
+// are not a single element of a store path, like "a/b" or "..".
+func checkIAMName(name string) error {
+	if name == "." || name == ".." || strings.Contains(name, SlashSeparator) {
+		return fmt.Errorf("invalid name %q: %w", name, errInvalidArgument)
+	}
+	return nil
+}
+
 // UserIdentity represents a user's secret key and their status
 type UserIdentity struct {
 	Version     int              `json:"version"`
 	Credentials auth.Credentials `json:"credentials"`
+
+	// Set while a secret key rotation is in progress, requests
+	// signed with the previous secret key are accepted until
+	// RotationExpiry.
+	PreviousSecretKey string    `json:"previousSecretKey,omitempty"`
+	RotationExpiry    time.Time `json:"rotationExpiry,omitempty"`
+
+	// Additional secret keys accepted for the access key besides
+	// Credentials.SecretKey, for clients pinned to an older secret.
+	SecretKeys []string `json:"secretKeys,omitempty"`
+
+	// Requests are denied unless the credentials carry an MFA
+	// verified claim, see SetUserMFARequired.
+	MFARequired bool `json:"mfaRequired,omitempty"`
+
+	// Free form labels of users and service accounts, see
+	// SetUserTags. They are never used for authorization.
+	Tags map[string]string `json:"tags,omitempty"`
 }
 
+// maxAdditionalSecretKeys - maximum number of secret keys a user may
+// have besides the primary one.
+const maxAdditionalSecretKeys = 4
+
 func newUserIdentity(cred auth.Credentials) UserIdentity {
 	return UserIdentity{Version: 1, Credentials: cred}
 }
@@ -161,8 +358,45 @@ type GroupInfo struct {
 	Members []string `json:"members"`
 }
 
+const (
+	// nestedGroupPrefix - prefix of the group members which refer
+	// to another group, whose members then belong to this group
+	// as well.
+	nestedGroupPrefix = "group:"
+
+	// maxGroupNestingDepth - groups nested deeper than this are
+	// not resolved.
+	maxGroupNestingDepth = 8
+)
+
+// nestedGroupName - returns the group a member refers to, if any.
+func nestedGroupName(member string) (string, bool) {
+	if strings.HasPrefix(member, nestedGroupPrefix) {
+		return strings.TrimPrefix(member, nestedGroupPrefix), true
+	}
+	return "", false
+}
+
+// checkAccessKeyName - rejects access keys which would be taken for a
+// nested group when listed as the member of a group.
+func checkAccessKeyName(accessKey string) error {
+	if _, ok := nestedGroupName(accessKey); ok {
+		return fmt.Errorf("access key %s must not start with %s: %w", accessKey, nestedGroupPrefix, errInvalidArgument)
+	}
+	return nil
+}
+
 func newGroupInfo(members []string) GroupInfo {
-	return GroupInfo{Version: 1, Status: statusEnabled, Members: members}
+	return GroupInfo{Version: 1, Status: statusEnabled, Members: members}.normalize()
+}
+
+// normalize - returns the group info with its members deduplicated and
+// sorted, so that it is saved and loaded the same way on all nodes.
+func (gi GroupInfo) normalize() GroupInfo {
+	if len(gi.Members) > 0 {
+		gi.Members = set.CreateStringSet(gi.Members...).ToSlice()
+	}
+	return gi
 }
 
 // MappedPolicy represents a policy name mapped to a user or group
@@ -196,18 +430,55 @@ func (mp MappedPolicy) policySet() set.StringSet {
 	return set.CreateStringSet(policies...)
 }
 
+// sortedPolicies - returns the distinct policy names in sorted order,
+// comma separated, so that the result is stable across nodes.
+func (mp MappedPolicy) sortedPolicies() string {
+	return strings.Join(mp.policySet().ToSlice(), ",")
+}
+
 func newMappedPolicy(policy string) MappedPolicy {
 	return MappedPolicy{Version: 1, Policies: policy}
 }
 
+// PolicyDoc - canned policy as stored in the IAM store, along with a
+// version incremented on every update of the policy.
+type PolicyDoc struct {
+	Version int              `json:"version"`
+	Policy  iampolicy.Policy `json:"policy"`
+}
+
+// parseJSON - parses a policy document, policies stored before
+// versioning was introduced are plain policies with version 0.
+func (d *PolicyDoc) parseJSON(data []byte) error {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	if _, ok := fields["policy"]; !ok {
+		*d = PolicyDoc{}
+		return json.Unmarshal(data, &d.Policy)
+	}
+	return json.Unmarshal(data, d)
+}
+
 // IAMSys - config system.
 type IAMSys struct {
-	sync.Mutex
+	// Keep the counters first to ensure 64-bit alignment of
+	// atomically accessed fields on 32-bit platforms.
+	stats IAMStats
+
+	// The hot read paths of access checks only take the read lock.
+	sync.RWMutex
 
 	usersSysType UsersSysType
 
 	// map of policy names to policy definitions
 	iamPolicyDocsMap map[string]iampolicy.Policy
+	// names of the policies in iamPolicyDocsMap whose documents were
+	// not loaded yet, see envIAMLazyPolicyLoad.
+	iamLazyPolicies set.StringSet
+	// reads of lazily loaded policy documents in progress.
+	lazyPolicyLoads lazyPolicyLoads
 	// map of usernames to credentials
 	iamUsersMap map[string]auth.Credentials
 	// map of group names to group info
@@ -218,6 +489,97 @@ type IAMSys struct {
 	iamUserPolicyMap map[string]MappedPolicy
 	// map of group names to policy names
 	iamGroupPolicyMap map[string]MappedPolicy
+	// map of sorted, comma separated policy names to their combined
+	// policy, cleared whenever iamPolicyDocsMap changes
+	combinedPolicyCache map[string]iampolicy.Policy
+	// last time policies allowed a request
+	policyUsage policyUsage
+	// map of usernames to the secret key rotation in progress
+	iamSecretRotations map[string]secretRotation
+	// access keys recently not found in the store, until when
+	negativeUserCache map[string]time.Time
+	// map of parent users to their STS credential rate limiter
+	stsRateLimiters map[string]*stsRateLimiter
+	// map of usernames to their additional secret keys
+	iamAdditionalSecretKeys map[string][]string
+	// map of usernames to their quota
+	iamUserQuotas map[string]UserQuota
+	// map of usernames to the buckets counted against their quota
+	iamUserBuckets map[string][]string
+	// usernames which require MFA
+	iamMFARequired set.StringSet
+	// map of users and service accounts to their tags
+	iamUserTags map[string]map[string]string
+
+	// functions called after changes are loaded from the store
+	changeHooks []func(IAMChangeEvent)
+	// functions called after credentials were created or deleted
+	credentialHooks []func(IAMCredentialEvent)
+	// *iamDecisionLogger receiving sampled IsAllowed decisions
+	decisionLogger atomic.Value
+	// IAMClaimValidator enforcing additional OpenID STS claims
+	claimValidator atomic.Value
+	// IAMResourceRewriter applied to policies as they are loaded
+	resourceRewriter atomic.Value
+	// CredentialPolicy new access and secret keys must comply with
+	credentialPolicy atomic.Value
+	// *bucketPolicyProviderHolder consulted by IsAllowed
+	bucketPolicyProvider atomic.Value
+	// outcome of the most recent full load, nil before the first
+	lastLoadReport *LoadReport
+
+	// maximum serialized size of a canned policy in bytes, zero for
+	// no limit, see envIAMPolicyMaxSize.
+	policyMaxSize int64
+	// maximum number of statements of a canned policy, see
+	// envIAMPolicyMaxStatements.
+	policyMaxStatements int
+	// maximum serialized size of a service account session policy
+	// in bytes.
+	sessionPolicyMaxSize int64
+	// lowercase access keys of users before storing or looking
+	// them up.
+	caseInsensitiveAccessKeys bool
+	// when non-zero, the store's watch reloads IAM from the store
+	// at this interval.
+	refreshInterval time.Duration
+	// cache the LDAP group memberships found in STS credentials
+	// in iamUserGroupMemberships, for informational purposes only.
+	ldapGroupMembershipCache bool
+	// deny requests of members of disabled groups, see
+	// envIAMStrictDisabledGroups.
+	strictDisabledGroups bool
+	// when non-zero, STS credentials expire at most this long
+	// after they are set.
+	stsMaxDuration time.Duration
+	// STS credentials per minute and parent user, see
+	// envIAMSTSRateLimit.
+	stsRateLimit int
+	// reject access keys shadowing other IAM names, see
+	// envIAMStrictNaming.
+	strictNaming bool
+	// policies mapped to new users in addition to the requested
+	// ones, see envIAMDefaultUserPolicies.
+	defaultUserPolicies []string
+	// maximum number of service accounts per parent user, zero
+	// for no limit.
+	maxServiceAccountsPerUser int
+	// distinguish transient store errors from unknown users, see
+	// envIAMStoreUnavailableError.
+	storeUnavailableError bool
+	// allow reverse lookups of secret keys, see envIAMSecretLookup.
+	secretLookup bool
+	// load policy documents on first use, see envIAMLazyPolicyLoad.
+	lazyPolicyLoad bool
+	// reject all writes, set by Init from JUICEFS_META_READ_ONLY.
+	readOnly bool
+	// timeout to acquire the store lock, see envIAMLockTimeout.
+	storeLockTimeout *DynamicTimeout
+	// retries of store writes, see envIAMStoreWriteRetries.
+	storeWriteRetries int
+	// when non-zero, deleted users may be restored for this long,
+	// see envIAMUserUndoWindow.
+	userUndoWindow time.Duration
 
 	// Persistence layer for IAM subsystem
 	store IAMStorageAPI
@@ -226,6 +588,13 @@ type IAMSys struct {
 	configLoaded chan struct{}
 }
 
+// secretRotation - previous secret key of a user which remains valid
+// until expiry.
+type secretRotation struct {
+	previousSecretKey string
+	expiry            time.Time
+}
+
 // IAMUserType represents a user type inside MinIO server
 type IAMUserType int
 
@@ -237,25 +606,34 @@ const (
 
 // key options
 type options struct {
-	ttl int64 //expiry in seconds
+	ttl      int64 //expiry in seconds
+	compress bool  // store the item compressed, if supported
 }
 
 // IAMStorageAPI defines an interface for the IAM persistence layer
 type IAMStorageAPI interface {
-	lock()
+	// lock and rlock give up with errIAMLockTimeout once ctx is
+	// done.
+	lock(ctx context.Context) error
 	unlock()
 
-	rlock()
+	rlock(ctx context.Context) error
 	runlock()
 
 	migrateBackendFormat(context.Context) error
 
+	getPolicyDoc(ctx context.Context, policy string) (PolicyDoc, error)
 	loadPolicyDoc(ctx context.Context, policy string, m map[string]iampolicy.Policy) error
 	loadPolicyDocs(ctx context.Context, m map[string]iampolicy.Policy) error
+	// loadPolicyNames - adds the names of all canned policies to
+	// names, without reading their documents.
+	loadPolicyNames(ctx context.Context, names set.StringSet) error
 
+	getUserIdentity(ctx context.Context, user string, userType IAMUserType) (UserIdentity, error)
 	getUserCredentials(ctx context.Context, user string, userType IAMUserType) (auth.Credentials, error)
 	loadUser(ctx context.Context, user string, userType IAMUserType, m map[string]auth.Credentials) error
 	loadUsers(ctx context.Context, userType IAMUserType, m map[string]auth.Credentials) error
+	loadUserIdentities(ctx context.Context, userType IAMUserType, m map[string]UserIdentity) error
 
 	getGroupInfo(ctx context.Context, group string) (GroupInfo, error)
 	loadGroup(ctx context.Context, group string, m map[string]GroupInfo) error
@@ -269,9 +647,13 @@ type IAMStorageAPI interface {
 
 	saveIAMConfig(ctx context.Context, item interface{}, path string, opts ...options) error
 	loadIAMConfig(ctx context.Context, item interface{}, path string) error
+	// loadIAMConfigIfChanged - same as loadIAMConfig, returns
+	// errIAMConfigNotModified when the item is the same as when
+	// the store last saved or loaded it with this method.
+	loadIAMConfigIfChanged(ctx context.Context, item interface{}, path string) error
 	deleteIAMConfig(ctx context.Context, path string) error
 
-	savePolicyDoc(ctx context.Context, policyName string, p iampolicy.Policy) error
+	savePolicyDoc(ctx context.Context, policyName string, d PolicyDoc) error
 	saveMappedPolicy(ctx context.Context, name string, userType IAMUserType, isGroup bool, mp MappedPolicy, opts ...options) error
 	saveUserIdentity(ctx context.Context, name string, userType IAMUserType, u UserIdentity, opts ...options) error
 	saveGroupInfo(ctx context.Context, group string, gi GroupInfo) error
@@ -300,12 +682,17 @@ func (sys *IAMSys) LoadGroup(group string) error {
 	}
 
 	sys.Lock()
-	defer sys.Unlock()
+	_, existed := sys.iamGroupsMap[group]
 	if errors.Is(err, errNoSuchGroup) {
 		// group does not exist - so remove from memory.
 		sys.removeGroupFromMembershipsMap(group)
 		delete(sys.iamGroupsMap, group)
 		delete(sys.iamGroupPolicyMap, group)
+		sys.Unlock()
+
+		if existed {
+			sys.notifyChange(IAMChangeEvent{ObjectType: IAMObjectGroup, Name: group, Action: IAMChangeDelete})
+		}
 		return nil
 	}
 	sys.iamGroupsMap[group] = gi
@@ -319,19 +706,56 @@ func (sys *IAMSys) LoadGroup(group string) error {
 
 	sys.removeGroupFromMembershipsMap(group)
 	sys.updateGroupMembershipsMap(group, &gi)
+	sys.Unlock()
+
+	sys.notifyChange(IAMChangeEvent{ObjectType: IAMObjectGroup, Name: group, Action: changeAction(existed)})
 	return nil
 }
 
 // LoadPolicy - reloads a specific canned policy from backend disks or etcd.
 func (sys *IAMSys) LoadPolicy(policyName string) error {
+	return sys.loadPolicyCtx(context.Background(), policyName)
+}
+
+func (sys *IAMSys) loadPolicyCtx(ctx context.Context, policyName string) error {
 	if !sys.Initialized() {
 		return errServerNotInitialized
 	}
 
+	d, err := getPolicyDocIfChanged(ctx, sys.store, policyName)
+	if errors.Is(err, errNoSuchPolicy) {
+		sys.forgetPolicy(policyName)
+		return err
+	}
+
 	sys.Lock()
-	defer sys.Unlock()
+	_, existed := sys.iamPolicyDocsMap[policyName]
+	switch {
+	case errors.Is(err, errIAMConfigNotModified) && existed:
+		// Nothing to apply.
+		sys.Unlock()
+		return nil
+	case errors.Is(err, errIAMConfigNotModified):
+		err = sys.store.loadPolicyDoc(ctx, policyName, sys.iamPolicyDocsMap)
+		if err == nil {
+			sys.iamPolicyDocsMap[policyName] = sys.rewriteResources(sys.iamPolicyDocsMap[policyName])
+			sys.iamLazyPolicies.Remove(policyName)
+		}
+	case err == nil:
+		sys.iamPolicyDocsMap[policyName] = sys.rewriteResources(d.Policy)
+		sys.iamLazyPolicies.Remove(policyName)
+	}
+	sys.invalidateCombinedPolicies()
+	sys.Unlock()
+	if errors.Is(err, errNoSuchPolicy) {
+		sys.forgetPolicy(policyName)
+	}
+	if err != nil {
+		return err
+	}
 
-	return sys.store.loadPolicyDoc(context.Background(), policyName, sys.iamPolicyDocsMap)
+	sys.notifyChange(IAMChangeEvent{ObjectType: IAMObjectPolicy, Name: policyName, Action: changeAction(existed)})
+	return nil
 }
 
 func (sys *IAMSys) LoadMappedPolicies(isGroup bool) error {
@@ -352,47 +776,137 @@ func (sys *IAMSys) LoadMappedPolicies(isGroup bool) error {
 // LoadPolicyMapping - loads the mapped policy for a user or group
 // from storage into server memory.
 func (sys *IAMSys) LoadPolicyMapping(userOrGroup string, userType IAMUserType, isGroup bool) error {
+	return sys.loadPolicyMappingCtx(context.Background(), userOrGroup, userType, isGroup)
+}
+
+func (sys *IAMSys) loadPolicyMappingCtx(ctx context.Context, userOrGroup string, userType IAMUserType, isGroup bool) error {
 	if !sys.Initialized() {
 		return errServerNotInitialized
 	}
 
-	p, err := sys.store.getMappedPolicy(context.Background(), userOrGroup, userType, isGroup)
+	p, err := sys.store.getMappedPolicy(ctx, userOrGroup, userType, isGroup)
 	// Ignore policy not mapped error
 	if err != nil && !errors.Is(err, errNoSuchPolicy) {
 		return err
 	}
 
 	sys.Lock()
-	defer sys.Unlock()
+	var prev MappedPolicy
 	if isGroup {
+		prev = sys.iamGroupPolicyMap[userOrGroup]
 		sys.iamGroupPolicyMap[userOrGroup] = p
 	} else {
+		prev = sys.iamUserPolicyMap[userOrGroup]
 		sys.iamUserPolicyMap[userOrGroup] = p
 	}
+	sys.Unlock()
+
+	action := changeAction(prev.Policies != "")
+	if p.Policies == "" {
+		if prev.Policies == "" {
+			// Nothing was mapped before either.
+			return nil
+		}
+		action = IAMChangeDelete
+	}
+	sys.notifyChange(IAMChangeEvent{ObjectType: IAMObjectPolicyMapping, Name: userOrGroup, Action: action, IsGroup: isGroup})
+	return nil
+}
+
+// LoadPolicyMappings - same as LoadPolicyMapping for several users or
+// groups, the in-memory maps are updated under a single lock once all
+// mappings were read from storage.
+func (sys *IAMSys) LoadPolicyMappings(names []string, userType IAMUserType, isGroup bool) error {
+	if !sys.Initialized() {
+		return errServerNotInitialized
+	}
+
+	mappings := make(map[string]MappedPolicy, len(names))
+	for _, name := range names {
+		p, err := sys.store.getMappedPolicy(context.Background(), name, userType, isGroup)
+		// Ignore policy not mapped error
+		if err != nil && !errors.Is(err, errNoSuchPolicy) {
+			return err
+		}
+		mappings[name] = p
+	}
+
+	var events []IAMChangeEvent
+	sys.Lock()
+	m := sys.iamUserPolicyMap
+	if isGroup {
+		m = sys.iamGroupPolicyMap
+	}
+	for name, p := range mappings {
+		prev := m[name]
+		m[name] = p
+
+		action := changeAction(prev.Policies != "")
+		if p.Policies == "" {
+			if prev.Policies == "" {
+				continue
+			}
+			action = IAMChangeDelete
+		}
+		events = append(events, IAMChangeEvent{ObjectType: IAMObjectPolicyMapping, Name: name, Action: action, IsGroup: isGroup})
+	}
+	sys.Unlock()
+
+	for _, ev := range events {
+		sys.notifyChange(ev)
+	}
 	return nil
 }
 
 // LoadUser - reloads a specific user from backend disks or etcd.
 func (sys *IAMSys) LoadUser(accessKey string, userType IAMUserType) error {
+	return sys.loadUserCtx(context.Background(), accessKey, userType)
+}
+
+func (sys *IAMSys) loadUserCtx(ctx context.Context, accessKey string, userType IAMUserType) error {
 	if !sys.Initialized() {
 		return errServerNotInitialized
 	}
 	var err error
-	var user auth.Credentials
-	if user, err = sys.store.getUserCredentials(context.Background(), accessKey, userType); err != nil {
+	var u UserIdentity
+	u, err = getUserIdentityIfChanged(ctx, sys.store, accessKey, userType)
+	if errors.Is(err, errIAMConfigNotModified) {
+		sys.Lock()
+		_, found := sys.iamUsersMap[accessKey]
+		sys.Unlock()
+		if found {
+			// The identity is unchanged, only the mapping may
+			// need to be reloaded.
+			return sys.loadPolicyMappingCtx(ctx, accessKey, userType, false)
+		}
+		u, err = sys.store.getUserIdentity(ctx, accessKey, userType)
+	}
+	if errors.Is(err, errNoSuchUser) {
+		sys.forgetUser(accessKey, userType)
+	}
+	if err != nil {
 		return err
 	}
+	user := u.Credentials
 
 	// Ignore policy not mapped error
 	var p MappedPolicy
-	if p, err = sys.store.getMappedPolicy(context.Background(), accessKey, userType, false); err != nil && !errors.Is(err, errNoSuchPolicy) {
+	if p, err = sys.store.getMappedPolicy(ctx, accessKey, userType, false); err != nil && !errors.Is(err, errNoSuchPolicy) {
 		return err
 	}
 
 	sys.Lock()
-	defer sys.Unlock()
+	_, existed := sys.iamUsersMap[accessKey]
 	sys.iamUsersMap[accessKey] = user
+	delete(sys.negativeUserCache, accessKey)
 	sys.iamUserPolicyMap[accessKey] = p
+	sys.setSecretRotation(accessKey, u)
+	sys.setAdditionalSecretKeys(accessKey, u)
+	sys.setMFARequired(accessKey, u)
+	sys.setUserTags(accessKey, u)
+	sys.Unlock()
+
+	sys.notifyChange(IAMChangeEvent{ObjectType: IAMObjectUser, Name: accessKey, Action: changeAction(existed)})
 	return nil
 }
 
@@ -420,10 +934,14 @@ func (sys *IAMSys) LoadServiceAccount(accessKey string) error {
 	}
 
 	if globalEtcdClient == nil {
-		err := sys.store.loadUser(context.Background(), accessKey, srvAccUser, sys.iamUsersMap)
+		u, err := sys.store.getUserIdentity(context.Background(), accessKey, srvAccUser)
 		if err != nil {
 			return err
 		}
+		sys.Lock()
+		sys.iamUsersMap[accessKey] = u.Credentials
+		sys.setUserTags(accessKey, u)
+		sys.Unlock()
 	}
 	// When etcd is set, we use watch APIs so this code is not needed.
 	return nil
@@ -436,11 +954,27 @@ func (sys *IAMSys) doIAMConfigMigration(ctx context.Context) error {
 
 // InitStore initializes IAM stores
 func (sys *IAMSys) InitStore(objAPI ObjectLayer) {
+	if globalEtcdClient == nil {
+		sys.InitStoreWith(newIAMObjectStore(objAPI))
+		return
+	}
+	sys.InitStoreWith(nil)
+}
+
+// InitStoreWith - initializes the IAM sub-system with the given store
+// instead of one backed by the object layer, e.g. NewInMemoryIAMStore.
+// The store still has to be loaded, see Load.
+func (sys *IAMSys) InitStoreWith(store IAMStorageAPI) {
 	sys.Lock()
 	defer sys.Unlock()
 
-	if globalEtcdClient == nil {
-		sys.store = newIAMObjectStore(objAPI)
+	sys.loadEnvConfig()
+
+	if store != nil {
+		if sys.storeWriteRetries > 0 {
+			store = newIAMRetryStore(store, sys.storeWriteRetries)
+		}
+		sys.store = store
 	}
 
 	if globalLDAPConfig.Enabled {
@@ -448,6 +982,186 @@ func (sys *IAMSys) InitStore(objAPI ObjectLayer) {
 	}
 }
 
+// loadEnvConfig - reads the optional IAM settings from the
+// environment. IMPORTANT: Assumes that sys.Lock is held by caller.
+func (sys *IAMSys) loadEnvConfig() {
+	if v := env.Get(envIAMPolicyMaxSize, ""); v != "" {
+		size, err := humanize.ParseBytes(v)
+		if err != nil {
+			logger.LogIf(GlobalContext, fmt.Errorf("invalid value for %s: %w", envIAMPolicyMaxSize, err))
+		} else {
+			sys.policyMaxSize = int64(size)
+		}
+	}
+	sys.policyMaxStatements = envPolicyMaxStatements()
+	if v := env.Get(envIAMSessionPolicyMaxSize, ""); v != "" {
+		size, err := humanize.ParseBytes(v)
+		if err != nil {
+			logger.LogIf(GlobalContext, fmt.Errorf("invalid value for %s: %w", envIAMSessionPolicyMaxSize, err))
+		} else {
+			sys.sessionPolicyMaxSize = int64(size)
+		}
+	}
+	enabled, err := config.ParseBool(env.Get(envIAMCaseInsensitiveAccessKeys, config.EnableOff))
+	if err != nil {
+		logger.LogIf(GlobalContext, fmt.Errorf("invalid value for %s: %w", envIAMCaseInsensitiveAccessKeys, err))
+	}
+	sys.caseInsensitiveAccessKeys = enabled
+
+	if v := env.Get(envIAMReadThroughRefreshInterval, ""); v != "" {
+		interval, err := time.ParseDuration(v)
+		if err != nil || interval < 0 {
+			logger.LogIf(GlobalContext, fmt.Errorf("invalid value for %s: %s", envIAMReadThroughRefreshInterval, v))
+		} else {
+			sys.refreshInterval = interval
+		}
+	}
+
+	enabled, err = config.ParseBool(env.Get(envIAMLDAPGroupMembershipCache, config.EnableOff))
+	if err != nil {
+		logger.LogIf(GlobalContext, fmt.Errorf("invalid value for %s: %w", envIAMLDAPGroupMembershipCache, err))
+	}
+	sys.ldapGroupMembershipCache = enabled
+
+	enabled, err = config.ParseBool(env.Get(envIAMStrictDisabledGroups, config.EnableOff))
+	if err != nil {
+		logger.LogIf(GlobalContext, fmt.Errorf("invalid value for %s: %w", envIAMStrictDisabledGroups, err))
+	}
+	sys.strictDisabledGroups = enabled
+
+	sys.defaultUserPolicies = newMappedPolicy(env.Get(envIAMDefaultUserPolicies, "")).toSlice()
+
+	enabled, err = config.ParseBool(env.Get(envIAMStrictNaming, config.EnableOff))
+	if err != nil {
+		logger.LogIf(GlobalContext, fmt.Errorf("invalid value for %s: %w", envIAMStrictNaming, err))
+	}
+	sys.strictNaming = enabled
+
+	enabled, err = config.ParseBool(env.Get(envIAMStoreUnavailableError, config.EnableOff))
+	if err != nil {
+		logger.LogIf(GlobalContext, fmt.Errorf("invalid value for %s: %w", envIAMStoreUnavailableError, err))
+	}
+	sys.storeUnavailableError = enabled
+
+	enabled, err = config.ParseBool(env.Get(envIAMSecretLookup, config.EnableOff))
+	if err != nil {
+		logger.LogIf(GlobalContext, fmt.Errorf("invalid value for %s: %w", envIAMSecretLookup, err))
+	}
+	sys.secretLookup = enabled
+
+	enabled, err = config.ParseBool(env.Get(envIAMLazyPolicyLoad, config.EnableOff))
+	if err != nil {
+		logger.LogIf(GlobalContext, fmt.Errorf("invalid value for %s: %w", envIAMLazyPolicyLoad, err))
+	}
+	sys.lazyPolicyLoad = enabled
+
+	if v := env.Get(envIAMSTSMaxDuration, ""); v != "" {
+		duration, err := time.ParseDuration(v)
+		if err != nil || duration < 0 {
+			logger.LogIf(GlobalContext, fmt.Errorf("invalid value for %s: %s", envIAMSTSMaxDuration, v))
+		} else {
+			sys.stsMaxDuration = duration
+		}
+	}
+
+	if v := env.Get(envIAMLockTimeout, ""); v != "" {
+		timeout, err := time.ParseDuration(v)
+		if err != nil || timeout <= 0 {
+			logger.LogIf(GlobalContext, fmt.Errorf("invalid value for %s: %s", envIAMLockTimeout, v))
+		} else {
+			sys.storeLockTimeout = NewDynamicTimeout(timeout, timeout)
+		}
+	}
+
+	if v := env.Get(envIAMMaxServiceAccountsPerUser, ""); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 0 {
+			logger.LogIf(GlobalContext, fmt.Errorf("invalid value for %s: %s", envIAMMaxServiceAccountsPerUser, v))
+		} else {
+			sys.maxServiceAccountsPerUser = limit
+		}
+	}
+
+	if v := env.Get(envIAMSTSRateLimit, ""); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 0 {
+			logger.LogIf(GlobalContext, fmt.Errorf("invalid value for %s: %s", envIAMSTSRateLimit, v))
+		} else {
+			sys.stsRateLimit = limit
+		}
+	}
+
+	if v := env.Get(envIAMUserUndoWindow, ""); v != "" {
+		window, err := time.ParseDuration(v)
+		if err != nil || window < 0 {
+			logger.LogIf(GlobalContext, fmt.Errorf("invalid value for %s: %s", envIAMUserUndoWindow, v))
+		} else {
+			sys.userUndoWindow = window
+		}
+	}
+
+	if v := env.Get(envIAMStoreWriteRetries, ""); v != "" {
+		retries, err := strconv.Atoi(v)
+		if err != nil || retries < 0 {
+			logger.LogIf(GlobalContext, fmt.Errorf("invalid value for %s: %s", envIAMStoreWriteRetries, v))
+		} else {
+			sys.storeWriteRetries = retries
+		}
+	}
+}
+
+// normalizeAccessKey - returns the access key as it is stored in
+// memory and on disk, which is lowercased when case-insensitive
+// access keys are enabled.
+func (sys *IAMSys) normalizeAccessKey(accessKey string) string {
+	if sys.caseInsensitiveAccessKeys {
+		return strings.ToLower(accessKey)
+	}
+	return accessKey
+}
+
+// normalizeAccessKeys - normalizes a list of access keys, see
+// normalizeAccessKey.
+func (sys *IAMSys) normalizeAccessKeys(accessKeys []string) []string {
+	if !sys.caseInsensitiveAccessKeys {
+		return accessKeys
+	}
+	normalized := make([]string, 0, len(accessKeys))
+	for _, accessKey := range accessKeys {
+		normalized = append(normalized, strings.ToLower(accessKey))
+	}
+	return normalized
+}
+
+// lockStore - acquires the store lock, giving up with
+// errIAMLockTimeout when it is not obtained within the lock timeout,
+// e.g. while a peer holding it is unreachable.
+func (sys *IAMSys) lockStore() error {
+	return sys.acquireStoreLock(sys.store.lock)
+}
+
+// rlockStore - same as lockStore for the store read lock.
+func (sys *IAMSys) rlockStore() error {
+	return sys.acquireStoreLock(sys.store.rlock)
+}
+
+func (sys *IAMSys) acquireStoreLock(lock func(context.Context) error) error {
+	if sys.storeLockTimeout == nil {
+		return lock(context.Background())
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), sys.storeLockTimeout.Timeout())
+	defer cancel()
+
+	start := time.Now()
+	if err := lock(ctx); err != nil {
+		sys.storeLockTimeout.LogFailure()
+		return err
+	}
+	sys.storeLockTimeout.LogSuccess(time.Since(start))
+	return nil
+}
+
 // Initialized check if IAM is initialized
 func (sys *IAMSys) Initialized() bool {
 	if sys == nil {
@@ -459,75 +1173,162 @@ func (sys *IAMSys) Initialized() bool {
 }
 
 // Load - loads all credentials
-func (sys *IAMSys) Load(ctx context.Context, store IAMStorageAPI) error {
+func (sys *IAMSys) Load(ctx context.Context, store IAMStorageAPI) (err error) {
+	report := &LoadReport{Time: UTCNow()}
+	defer func() {
+		if err != nil {
+			report.Err = err.Error()
+		}
+		sys.Lock()
+		sys.lastLoadReport = report
+		sys.Unlock()
+	}()
+
 	iamUsersMap := make(map[string]auth.Credentials)
 	iamGroupsMap := make(map[string]GroupInfo)
 	iamUserPolicyMap := make(map[string]MappedPolicy)
 	iamGroupPolicyMap := make(map[string]MappedPolicy)
 	iamPolicyDocsMap := make(map[string]iampolicy.Policy)
+	identities := make(map[string]UserIdentity)
 
-	store.rlock()
+	if err := store.rlock(ctx); err != nil {
+		return err
+	}
 	defer store.runlock()
 
 	isMinIOUsersSys := sys.usersSysType == MinIOUsersSysType
-	if err := store.loadPolicyDocs(ctx, iamPolicyDocsMap); err != nil && !errors.As(err, &BucketNotFound{}) {
+	lazyPolicies, err := sys.loadPolicyDocsFrom(ctx, store, iamPolicyDocsMap)
+	if err = report.addFailure(iamConfigPoliciesPrefix, err); err != nil {
 		return err
 	}
+	sys.rewriteAllResources(iamPolicyDocsMap)
 	// Sets default canned policies, if none are set.
 	setDefaultCannedPolicies(iamPolicyDocsMap)
 
 	if isMinIOUsersSys {
-		if err := store.loadUsers(ctx, regularUser, iamUsersMap); err != nil && !errors.As(err, &BucketNotFound{}) {
+		if err := report.addFailure(iamConfigUsersPrefix, store.loadUserIdentities(ctx, regularUser, identities)); err != nil {
 			return err
 		}
-		if err := store.loadGroups(ctx, iamGroupsMap); err != nil && !errors.As(err, &BucketNotFound{}) {
+		for user, u := range identities {
+			iamUsersMap[user] = u.Credentials
+		}
+		if err := report.addFailure(iamConfigGroupsPrefix, store.loadGroups(ctx, iamGroupsMap)); err != nil {
 			return err
 		}
 	}
 
 	// load polices mapped to users
-	if err := store.loadMappedPolicies(ctx, regularUser, false, iamUserPolicyMap); err != nil && !errors.As(err, &BucketNotFound{}) {
+	if err := report.addFailure(iamConfigPolicyDBUsersPrefix, store.loadMappedPolicies(ctx, regularUser, false, iamUserPolicyMap)); err != nil {
 		return err
 	}
 
 	// load policies mapped to groups
-	if err := store.loadMappedPolicies(ctx, regularUser, true, iamGroupPolicyMap); err != nil && !errors.As(err, &BucketNotFound{}) {
+	if err := report.addFailure(iamConfigPolicyDBGroupsPrefix, store.loadMappedPolicies(ctx, regularUser, true, iamGroupPolicyMap)); err != nil {
 		return err
 	}
 
-	if err := store.loadUsers(ctx, srvAccUser, iamUsersMap); err != nil && !errors.As(err, &BucketNotFound{}) {
+	svcIdentities := make(map[string]UserIdentity)
+	if err := report.addFailure(iamConfigServiceAccountsPrefix, store.loadUserIdentities(ctx, srvAccUser, svcIdentities)); err != nil {
 		return err
 	}
+	for user, u := range svcIdentities {
+		iamUsersMap[user] = u.Credentials
+	}
 
 	// load STS temp users
-	if err := store.loadUsers(ctx, stsUser, iamUsersMap); err != nil && !errors.As(err, &BucketNotFound{}) {
+	if err := report.addFailure(iamConfigSTSPrefix, store.loadUsers(ctx, stsUser, iamUsersMap)); err != nil {
 		return err
 	}
 
 	// load STS policy mappings
-	if err := store.loadMappedPolicies(ctx, stsUser, false, iamUserPolicyMap); err != nil && !errors.As(err, &BucketNotFound{}) {
+	if err := report.addFailure(iamConfigPolicyDBSTSUsersPrefix, store.loadMappedPolicies(ctx, stsUser, false, iamUserPolicyMap)); err != nil {
 		return err
 	}
 
+	iamUserQuotas := userQuotas{
+		Quotas:  make(map[string]UserQuota),
+		Buckets: make(map[string][]string),
+	}
+	if isMinIOUsersSys {
+		quotas, qerr := loadUserQuotas(ctx, store)
+		if err := report.addFailure(getUserQuotasPath(), qerr); err != nil {
+			return err
+		}
+		if qerr == nil {
+			iamUserQuotas = quotas
+		}
+	}
+
+	report.Policies = len(iamPolicyDocsMap)
+	report.Groups = len(iamGroupsMap)
+	report.UserPolicyMappings = len(iamUserPolicyMap)
+	report.GroupPolicyMappings = len(iamGroupPolicyMap)
+	for _, cred := range iamUsersMap {
+		switch {
+		case cred.IsServiceAccount():
+			report.ServiceAccounts++
+		case cred.IsTemp():
+			report.STSUsers++
+		default:
+			report.Users++
+		}
+	}
+
 	sys.Lock()
 	defer sys.Unlock()
 
+	sys.stats.incReload()
+
 	sys.iamPolicyDocsMap = iamPolicyDocsMap
+	sys.iamLazyPolicies = lazyPolicies
+	sys.invalidateCombinedPolicies()
 
 	sys.iamUsersMap = iamUsersMap
 
 	sys.iamUserPolicyMap = iamUserPolicyMap
 
+	sys.setUserQuotas(iamUserQuotas)
+
+	sys.iamSecretRotations = make(map[string]secretRotation)
+	sys.iamAdditionalSecretKeys = make(map[string][]string)
+	sys.iamMFARequired = set.NewStringSet()
+	sys.iamUserTags = make(map[string]map[string]string)
+	for user, u := range svcIdentities {
+		sys.setUserTags(user, u)
+	}
+	var expiredRotations []string
+	for user, u := range identities {
+		sys.setAdditionalSecretKeys(user, u)
+		sys.setMFARequired(user, u)
+		sys.setUserTags(user, u)
+		sys.setSecretRotation(user, u)
+		if u.PreviousSecretKey != "" && !UTCNow().Before(u.RotationExpiry) {
+			expiredRotations = append(expiredRotations, user)
+		}
+	}
+	if len(expiredRotations) > 0 && !sys.readOnly {
+		// Only the store read lock is held here.
+		go sys.purgeSecretRotations(expiredRotations)
+	}
+
 	// purge any expired entries which became expired now.
-	var expiredEntries []string
+	var expiredEntries, expiredServiceAccounts []string
 	for k, v := range sys.iamUsersMap {
 		if v.IsExpired() {
 			delete(sys.iamUsersMap, k)
 			delete(sys.iamUserPolicyMap, k)
+			if v.IsServiceAccount() {
+				expiredServiceAccounts = append(expiredServiceAccounts, k)
+				continue
+			}
 			expiredEntries = append(expiredEntries, k)
 			// Deleting on the disk is taken care of in the next cycle
 		}
 	}
+	if len(expiredServiceAccounts) > 0 && !sys.readOnly {
+		// Only the store read lock is held here.
+		go sys.purgeExpiredServiceAccounts(expiredServiceAccounts)
+	}
 
 	for _, v := range sys.iamUsersMap {
 		if v.IsServiceAccount() {
@@ -551,9 +1352,10 @@ func (sys *IAMSys) Load(ctx context.Context, store IAMStorageAPI) error {
 
 	sys.iamGroupPolicyMap = iamGroupPolicyMap
 
-	sys.iamGroupsMap = iamGroupsMap
-
-	sys.buildUserGroupMemberships()
+	sys.setGroupsMap(iamGroupsMap)
+	for _, cred := range sys.iamUsersMap {
+		sys.cacheLDAPGroupMemberships(cred)
+	}
 	select {
 	case <-sys.configLoaded:
 	default:
@@ -564,6 +1366,10 @@ func (sys *IAMSys) Load(ctx context.Context, store IAMStorageAPI) error {
 
 // Init - initializes config system by reading entries from config/iam
 func (sys *IAMSys) Init(ctx context.Context, objAPI ObjectLayer) {
+	// Set before the store, write methods only look at it once
+	// the IAM sub-system is initialized.
+	sys.readOnly = os.Getenv("JUICEFS_META_READ_ONLY") != ""
+
 	// Initialize IAM store
 	sys.InitStore(objAPI)
 
@@ -580,7 +1386,7 @@ func (sys *IAMSys) Init(ctx context.Context, objAPI ObjectLayer) {
 
 	r := rand.New(rand.NewSource(time.Now().UnixNano()))
 
-	if os.Getenv("JUICEFS_META_READ_ONLY") == "" {
+	if !sys.readOnly {
 		for {
 			// let one of the server acquire the lock, if not let them timeout.
 			// which shall be retried again by this loop.
@@ -636,32 +1442,82 @@ func (sys *IAMSys) Init(ctx context.Context, objAPI ObjectLayer) {
 	// Invalidate the old cred always, even upon error to avoid any leakage.
 	globalOldCred = auth.Credentials{}
 	go sys.store.watch(ctx, sys)
+	go sys.persistPolicyUsage(ctx, iamPolicyUsageFlushInterval)
+	if sys.userUndoWindow > 0 {
+		go sys.purgeUserTombstones(ctx, iamUserTombstonePurgeInterval)
+	}
 
 	logger.Info("IAM initialization complete")
 }
 
 // DeletePolicy - deletes a canned policy from backend or etcd.
 func (sys *IAMSys) DeletePolicy(policyName string) error {
+	return sys.DeletePolicyWithOpts(policyName, deletePolicyOpts{})
+}
+
+// deletePolicyOpts - options of DeletePolicyWithOpts.
+type deletePolicyOpts struct {
+	// refuse to delete a policy still mapped to users or groups,
+	// instead of removing it from their mappings.
+	rejectInUse bool
+}
+
+// DeletePolicyWithOpts - same as DeletePolicy. With rejectInUse, a
+// policy mapped to any user or group is not deleted and an error
+// wrapping errPolicyInUse lists the mappings.
+func (sys *IAMSys) DeletePolicyWithOpts(policyName string, opts deletePolicyOpts) error {
 	if !sys.Initialized() {
 		return errServerNotInitialized
 	}
 
+	if sys.readOnly {
+		return errIAMReadOnly
+	}
+
 	if policyName == "" {
 		return errInvalidArgument
 	}
 
-	sys.store.lock()
+	if err := sys.lockStore(); err != nil {
+		return err
+	}
 	defer sys.store.unlock()
 
-	err := sys.store.deletePolicyDoc(context.Background(), policyName)
-	if errors.Is(err, errNoSuchPolicy) {
+	if opts.rejectInUse {
+		users, groups, err := sys.ListUsersByPolicy(policyName)
+		if err != nil {
+			return err
+		}
+		if len(users) > 0 || len(groups) > 0 {
+			attachments := make([]string, 0, len(users)+len(groups))
+			for _, u := range users {
+				attachments = append(attachments, "user:"+u)
+			}
+			for _, g := range groups {
+				attachments = append(attachments, "group:"+g)
+			}
+			return fmt.Errorf("policy %s is mapped to %s: %w", policyName, strings.Join(attachments, ", "), errPolicyInUse)
+		}
+	}
+
+	err := sys.store.deletePolicyDoc(context.Background(), policyName)
+	if errors.Is(err, errNoSuchPolicy) {
 		// Ignore error if policy is already deleted.
 		err = nil
 	}
 	sys.Lock()
+	_, existed := sys.iamPolicyDocsMap[policyName]
 	delete(sys.iamPolicyDocsMap, policyName)
+	sys.policyUsage.delete(policyName)
+	sys.invalidateCombinedPolicies()
 	sys.Unlock()
 
+	if existed && err == nil {
+		// Deferred first, so hooks are called once sys.Lock is
+		// released below.
+		defer sys.notifyChange(IAMChangeEvent{ObjectType: IAMObjectPolicy, Name: policyName, Action: IAMChangeDelete})
+	}
+
 	// update iamUsersMap
 	if err := sys.LoadAllTypeUsers(); err != nil {
 		return err
@@ -726,9 +1582,13 @@ func (sys *IAMSys) InfoPolicy(policyName string) (iampolicy.Policy, error) {
 		return iampolicy.Policy{}, errServerNotInitialized
 	}
 
+	if err := sys.loadLazyPolicies(policyName); err != nil {
+		return iampolicy.Policy{}, err
+	}
+
 	sys.Lock()
 	defer sys.Unlock()
-	v, ok := sys.iamPolicyDocsMap[policyName]
+	v, ok := sys.policyDoc(policyName)
 	if !ok {
 		return iampolicy.Policy{}, errNoSuchPolicy
 	}
@@ -744,12 +1604,66 @@ func (sys *IAMSys) ListPolicies() (map[string]iampolicy.Policy, error) {
 
 	<-sys.configLoaded
 
+	if err := sys.loadLazyPolicyDocs(); err != nil {
+		return nil, err
+	}
+
 	sys.Lock()
 	defer sys.Unlock()
 
 	policyDocsMap := make(map[string]iampolicy.Policy, len(sys.iamPolicyDocsMap))
-	for k, v := range sys.iamPolicyDocsMap {
-		policyDocsMap[k] = v
+	for k := range sys.iamPolicyDocsMap {
+		if v, ok := sys.policyDoc(k); ok {
+			policyDocsMap[k] = v
+		}
+	}
+
+	return policyDocsMap, nil
+}
+
+// ListPoliciesMatching - lists canned policies whose name matches the
+// pattern. The pattern is a glob supporting '*' and '?', unless it
+// starts with '^' in which case it is taken as a regular expression.
+// Both must match the whole policy name.
+func (sys *IAMSys) ListPoliciesMatching(pattern string) (map[string]iampolicy.Policy, error) {
+	if !sys.Initialized() {
+		return nil, errServerNotInitialized
+	}
+
+	expr := strings.TrimPrefix(pattern, "^")
+	if expr == pattern {
+		expr = regexp.QuoteMeta(expr)
+		expr = strings.Replace(expr, `\*`, ".*", -1)
+		expr = strings.Replace(expr, `\?`, ".", -1)
+	}
+	re, err := regexp.Compile("^(?:" + expr + ")$")
+	if err != nil {
+		return nil, errInvalidArgument
+	}
+
+	<-sys.configLoaded
+
+	sys.Lock()
+	var names []string
+	for k := range sys.iamPolicyDocsMap {
+		if re.MatchString(k) {
+			names = append(names, k)
+		}
+	}
+	sys.Unlock()
+
+	if err := sys.loadLazyPolicies(names...); err != nil {
+		return nil, err
+	}
+
+	sys.Lock()
+	defer sys.Unlock()
+
+	policyDocsMap := make(map[string]iampolicy.Policy, len(names))
+	for _, k := range names {
+		if v, ok := sys.policyDoc(k); ok {
+			policyDocsMap[k] = v
+		}
 	}
 
 	return policyDocsMap, nil
@@ -761,23 +1675,351 @@ func (sys *IAMSys) SetPolicy(policyName string, p iampolicy.Policy) error {
 		return errServerNotInitialized
 	}
 
+	if sys.readOnly {
+		return errIAMReadOnly
+	}
+
 	if p.IsEmpty() || policyName == "" {
 		return errInvalidArgument
 	}
 
-	sys.store.lock()
+	if err := checkIAMName(policyName); err != nil {
+		return err
+	}
+
+	if err := sys.validatePolicy(p); err != nil {
+		return err
+	}
+
+	if err := sys.lockStore(); err != nil {
+		return err
+	}
+	defer sys.store.unlock()
+
+	if err := sys.loadPolicyDocs(); err != nil {
+		return err
+	}
+	if err := sys.savePolicyVersion(context.Background(), policyName, p, anyPolicyVersion); err != nil {
+		return err
+	}
+
+	sys.Lock()
+	defer sys.Unlock()
+	sys.iamPolicyDocsMap[policyName] = sys.rewriteResources(p)
+	sys.iamLazyPolicies.Remove(policyName)
+	sys.invalidateCombinedPolicies()
+	return nil
+}
+
+// anyPolicyVersion - saves a policy regardless of its stored version.
+const anyPolicyVersion = -1
+
+// SetPolicyJSON - same as SetPolicy for a policy in JSON, as uploaded
+// by clients. Malformed policies are reported with the line and column
+// of the error.
+func (sys *IAMSys) SetPolicyJSON(policyName string, raw []byte) error {
+	p, err := iampolicy.ParseConfig(bytes.NewReader(raw))
+	if err != nil {
+		return policyJSONError(raw, err)
+	}
+
+	// Version in policy must not be empty
+	if p.Version == "" {
+		return iampolicy.Errorf("policy version must not be empty")
+	}
+
+	return sys.SetPolicy(policyName, *p)
+}
+
+// policyJSONError - adds the position in data of JSON decoding errors
+// to err, other errors are returned as is.
+func policyJSONError(data []byte, err error) error {
+	var offset int64
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &syntaxErr):
+		offset = syntaxErr.Offset
+	case errors.As(err, &typeErr):
+		offset = typeErr.Offset
+	default:
+		return err
+	}
+
+	// The offending byte is the last one read.
+	end := offset - 1
+	if end < 0 {
+		end = 0
+	}
+	if end > int64(len(data)) {
+		end = int64(len(data))
+	}
+	line, column := 1, 1
+	for _, b := range data[:end] {
+		if b == '\n' {
+			line++
+			column = 1
+		} else {
+			column++
+		}
+	}
+	return iampolicy.Errorf("malformed policy at line %d, column %d: %v", line, column, err)
+}
+
+// SetPolicyIfMatch - sets a named policy only if its stored version is
+// expectedVersion, otherwise errPolicyVersionConflict is returned. A
+// policy which does not exist has version 0.
+func (sys *IAMSys) SetPolicyIfMatch(policyName string, p iampolicy.Policy, expectedVersion int) error {
+	if !sys.Initialized() {
+		return errServerNotInitialized
+	}
+
+	if sys.readOnly {
+		return errIAMReadOnly
+	}
+
+	if p.IsEmpty() || policyName == "" || expectedVersion < 0 {
+		return errInvalidArgument
+	}
+
+	if err := checkIAMName(policyName); err != nil {
+		return err
+	}
+
+	if err := sys.validatePolicy(p); err != nil {
+		return err
+	}
+
+	if err := sys.lockStore(); err != nil {
+		return err
+	}
 	defer sys.store.unlock()
 
 	if err := sys.loadPolicyDocs(); err != nil {
 		return err
 	}
-	if err := sys.store.savePolicyDoc(context.Background(), policyName, p); err != nil {
+	if err := sys.savePolicyVersion(context.Background(), policyName, p, expectedVersion); err != nil {
 		return err
 	}
 
 	sys.Lock()
 	defer sys.Unlock()
-	sys.iamPolicyDocsMap[policyName] = p
+	sys.iamPolicyDocsMap[policyName] = sys.rewriteResources(p)
+	sys.iamLazyPolicies.Remove(policyName)
+	sys.invalidateCombinedPolicies()
+	return nil
+}
+
+// GetPolicyDoc - returns the named policy as stored, along with its
+// version to be passed to SetPolicyIfMatch.
+func (sys *IAMSys) GetPolicyDoc(policyName string) (PolicyDoc, error) {
+	if !sys.Initialized() {
+		return PolicyDoc{}, errServerNotInitialized
+	}
+
+	if err := sys.rlockStore(); err != nil {
+		return PolicyDoc{}, err
+	}
+	defer sys.store.runlock()
+
+	return sys.store.getPolicyDoc(context.Background(), policyName)
+}
+
+// savePolicyVersion - saves p as the next version of the stored policy,
+// failing if the stored version is not expectedVersion unless it is
+// anyPolicyVersion.
+// IMPORTANT: Assumes that sys.store.lock is held by caller.
+func (sys *IAMSys) savePolicyVersion(ctx context.Context, policyName string, p iampolicy.Policy, expectedVersion int) error {
+	d, err := sys.store.getPolicyDoc(ctx, policyName)
+	if err != nil && err != errNoSuchPolicy {
+		return err
+	}
+	if expectedVersion != anyPolicyVersion && d.Version != expectedVersion {
+		return errPolicyVersionConflict
+	}
+	return sys.store.savePolicyDoc(ctx, policyName, PolicyDoc{Version: d.Version + 1, Policy: p})
+}
+
+// RenamePolicy - renames a canned policy, all users and groups mapped
+// to the old policy are mapped to the new policy before the old policy
+// is deleted, so that they never lose access in between. If a mapping
+// can not be saved, the mappings changed so far are restored and the
+// new policy is deleted again.
+func (sys *IAMSys) RenamePolicy(oldName, newName string) error {
+	if !sys.Initialized() {
+		return errServerNotInitialized
+	}
+
+	if sys.readOnly {
+		return errIAMReadOnly
+	}
+
+	if oldName == "" || newName == "" || oldName == newName {
+		return errInvalidArgument
+	}
+
+	if err := checkIAMName(newName); err != nil {
+		return err
+	}
+
+	if err := sys.lockStore(); err != nil {
+		return err
+	}
+	defer sys.store.unlock()
+
+	if err := sys.loadPolicyDocs(); err != nil {
+		return err
+	}
+
+	sys.Lock()
+	_, ok := sys.iamPolicyDocsMap[oldName]
+	// Lazily loaded policies are read from the store below, the store
+	// lock is held already.
+	p, loaded := sys.policyDoc(oldName)
+	_, exists := sys.iamPolicyDocsMap[newName]
+	sys.Unlock()
+	if !ok {
+		return errNoSuchPolicy
+	}
+	if exists {
+		return errInvalidArgument
+	}
+
+	// Copy the stored policy, the resources of the in-memory one may
+	// be rewritten. Default canned policies are only in memory.
+	d, err := sys.store.getPolicyDoc(context.Background(), oldName)
+	switch {
+	case err == nil:
+		p = d.Policy
+	case err != errNoSuchPolicy:
+		return err
+	case !loaded:
+		return errNoSuchPolicy
+	}
+
+	if err := sys.savePolicyVersion(context.Background(), newName, p, anyPolicyVersion); err != nil {
+		return err
+	}
+
+	sys.Lock()
+	sys.iamPolicyDocsMap[newName] = sys.rewriteResources(p)
+	sys.iamLazyPolicies.Remove(newName)
+	sys.invalidateCombinedPolicies()
+
+	renamed := func(mp MappedPolicy) MappedPolicy {
+		pset := mp.policySet()
+		pset.Remove(oldName)
+		pset.Add(newName)
+		return newMappedPolicy(strings.Join(pset.ToSlice(), ","))
+	}
+
+	var remaps []policyRemap
+	for u, mp := range sys.iamUserPolicyMap {
+		if !mp.policySet().Contains(oldName) {
+			continue
+		}
+		userType := regularUser
+		if cr, ok := sys.iamUsersMap[u]; (ok && cr.IsTemp()) || sys.usersSysType == LDAPUsersSysType {
+			userType = stsUser
+		}
+		remaps = append(remaps, policyRemap{name: u, userType: userType, old: mp, new: renamed(mp)})
+	}
+	for g, mp := range sys.iamGroupPolicyMap {
+		if mp.policySet().Contains(oldName) {
+			remaps = append(remaps, policyRemap{name: g, userType: regularUser, isGroup: true, old: mp, new: renamed(mp)})
+		}
+	}
+	sys.Unlock()
+
+	for i, r := range remaps {
+		if err := sys.store.saveMappedPolicy(context.Background(), r.name, r.userType, r.isGroup, r.new); err != nil {
+			// Map the users and groups switched so far back to the
+			// old policy, which is still stored, and drop the new one.
+			sys.rollbackPolicyRemaps(remaps[:i])
+			if derr := sys.store.deletePolicyDoc(context.Background(), newName); derr != nil && !errors.Is(derr, errNoSuchPolicy) {
+				logger.LogIf(GlobalContext, fmt.Errorf("unable to delete the policy %s: %w", newName, derr))
+			}
+			sys.Lock()
+			delete(sys.iamPolicyDocsMap, newName)
+			sys.invalidateCombinedPolicies()
+			sys.Unlock()
+			return err
+		}
+		sys.Lock()
+		sys.setPolicyRemap(r, r.new)
+		sys.Unlock()
+	}
+
+	if err := sys.store.deletePolicyDoc(context.Background(), oldName); err != nil && !errors.Is(err, errNoSuchPolicy) {
+		return err
+	}
+
+	sys.Lock()
+	delete(sys.iamPolicyDocsMap, oldName)
+	sys.invalidateCombinedPolicies()
+	sys.Unlock()
+	return nil
+}
+
+// policyRemap - a policy mapping of a user or a group changed by
+// RenamePolicy.
+type policyRemap struct {
+	name     string
+	userType IAMUserType
+	isGroup  bool
+	old, new MappedPolicy
+}
+
+// setPolicyRemap - sets the in-memory policy mapping of the user or
+// group of r to mp.
+// IMPORTANT: Assumes that sys.Lock is held by caller.
+func (sys *IAMSys) setPolicyRemap(r policyRemap, mp MappedPolicy) {
+	if r.isGroup {
+		sys.iamGroupPolicyMap[r.name] = mp
+	} else {
+		sys.iamUserPolicyMap[r.name] = mp
+	}
+}
+
+// rollbackPolicyRemaps - restores the policy mappings changed by
+// remaps, in the store and in memory.
+// IMPORTANT: Assumes that sys.store.lock is held by caller.
+func (sys *IAMSys) rollbackPolicyRemaps(remaps []policyRemap) {
+	for _, r := range remaps {
+		if err := sys.store.saveMappedPolicy(context.Background(), r.name, r.userType, r.isGroup, r.old); err != nil {
+			logger.LogIf(GlobalContext, fmt.Errorf("unable to restore the policy mapping of %s: %w", r.name, err))
+		}
+		sys.Lock()
+		sys.setPolicyRemap(r, r.old)
+		sys.Unlock()
+	}
+}
+
+// validatePolicy - validates a canned policy before it is saved, the
+// returned error names the offending statement.
+func (sys *IAMSys) validatePolicy(p iampolicy.Policy) error {
+	if err := checkPolicyStatements(p, sys.policyMaxStatements); err != nil {
+		return err
+	}
+	for i, statement := range p.Statements {
+		if err := statement.Validate(); err != nil {
+			return iampolicy.Errorf("invalid statement at index %d: %v", i, err)
+		}
+	}
+	if err := p.Validate(); err != nil {
+		return err
+	}
+
+	if sys.policyMaxSize > 0 {
+		policyBuf, err := json.Marshal(p)
+		if err != nil {
+			return err
+		}
+		if int64(len(policyBuf)) > sys.policyMaxSize {
+			return iampolicy.Errorf("policy size %d bytes exceeds the allowed maximum of %d bytes set by %s",
+				len(policyBuf), sys.policyMaxSize, envIAMPolicyMaxSize)
+		}
+	}
 	return nil
 }
 
@@ -787,6 +2029,12 @@ func (sys *IAMSys) DeleteUser(accessKey string) error {
 		return errServerNotInitialized
 	}
 
+	if sys.readOnly {
+		return errIAMReadOnly
+	}
+
+	accessKey = sys.normalizeAccessKey(accessKey)
+
 	if sys.usersSysType != MinIOUsersSysType {
 		return errIAMActionNotAllowed
 	}
@@ -797,6 +2045,20 @@ func (sys *IAMSys) DeleteUser(accessKey string) error {
 		return getErr
 	}
 
+	if sys.userUndoWindow > 0 {
+		// Keep what is needed to restore the user before
+		// anything is removed.
+		if err := func() error {
+			if err := sys.lockStore(); err != nil {
+				return err
+			}
+			defer sys.store.unlock()
+			return sys.saveUserTombstonesFor(context.Background(), map[string][]string{accessKey: userInfo.MemberOf})
+		}(); err != nil {
+			return err
+		}
+	}
+
 	for _, group := range userInfo.MemberOf {
 		removeErr := sys.RemoveUsersFromGroup(group, []string{accessKey})
 		if removeErr != nil {
@@ -805,22 +2067,29 @@ func (sys *IAMSys) DeleteUser(accessKey string) error {
 	}
 
 	// Next we can remove the user from memory and IAM store
-	sys.store.lock()
+	if err := sys.lockStore(); err != nil {
+		return err
+	}
 	defer sys.store.unlock()
 
+	var events []IAMCredentialEvent
 	sys.Lock()
 	for _, u := range sys.iamUsersMap {
 		// Delete any service accounts if any first.
 		if u.IsServiceAccount() {
 			if u.ParentUser == accessKey {
-				_ = sys.store.deleteUserIdentity(context.Background(), u.AccessKey, srvAccUser)
+				if sys.store.deleteUserIdentity(context.Background(), u.AccessKey, srvAccUser) == nil {
+					events = append(events, IAMCredentialEvent{Action: IAMCredentialDeleted, Type: IAMCredentialServiceAccount, AccessKey: u.AccessKey, ParentUser: accessKey})
+				}
 				delete(sys.iamUsersMap, u.AccessKey)
 			}
 		}
 		// Delete any associated STS users.
 		if u.IsTemp() {
 			if u.ParentUser == accessKey {
-				_ = sys.store.deleteUserIdentity(context.Background(), u.AccessKey, stsUser)
+				if sys.store.deleteUserIdentity(context.Background(), u.AccessKey, stsUser) == nil {
+					events = append(events, IAMCredentialEvent{Action: IAMCredentialDeleted, Type: IAMCredentialSTS, AccessKey: u.AccessKey, ParentUser: accessKey})
+				}
 				delete(sys.iamUsersMap, u.AccessKey)
 			}
 		}
@@ -838,597 +2107,1899 @@ func (sys *IAMSys) DeleteUser(accessKey string) error {
 	sys.Lock()
 	delete(sys.iamUsersMap, accessKey)
 	delete(sys.iamUserPolicyMap, accessKey)
+	delete(sys.iamSecretRotations, accessKey)
+	delete(sys.iamAdditionalSecretKeys, accessKey)
+	sys.iamMFARequired.Remove(accessKey)
+	delete(sys.iamUserTags, accessKey)
 	sys.Unlock()
 
+	if err == nil {
+		sys.deleteUserQuotas(context.Background(), accessKey)
+		events = append(events, IAMCredentialEvent{Action: IAMCredentialDeleted, Type: IAMCredentialUser, AccessKey: accessKey})
+	}
+	for _, ev := range events {
+		sys.notifyCredential(context.Background(), ev)
+		sys.notifyChange(IAMChangeEvent{ObjectType: IAMObjectUser, Name: ev.AccessKey, Action: IAMChangeDelete})
+	}
+
 	return err
 }
 
-// CurrentPolicies - returns comma separated policy string, from
-// an input policy after validating if there are any current
-// policies which exist on MinIO corresponding to the input.
-func (sys *IAMSys) CurrentPolicies(policyName string) string {
+// DeleteUsers - deletes several users like DeleteUser in a single pass
+// under one store lock: the users are removed from their groups, and
+// their service accounts and temporary users are deleted along with
+// them. The result of each access key is returned in the map, users
+// which are already deleted from the store are not an error.
+func (sys *IAMSys) DeleteUsers(accessKeys []string) (map[string]error, error) {
 	if !sys.Initialized() {
-		return ""
+		return nil, errServerNotInitialized
 	}
 
-	sys.Lock()
-	defer sys.Unlock()
-
-	var policies []string
-	mp := newMappedPolicy(policyName)
-	for _, policy := range mp.toSlice() {
-		_, found := sys.iamPolicyDocsMap[policy]
-		if found {
-			policies = append(policies, policy)
-		}
+	if sys.readOnly {
+		return nil, errIAMReadOnly
 	}
-	return strings.Join(policies, ",")
-}
 
-// SetTempUser - set temporary user credentials, these credentials have an expiry.
-func (sys *IAMSys) SetTempUser(accessKey string, cred auth.Credentials, policyName string) error {
-	if !sys.Initialized() {
-		return errServerNotInitialized
+	if sys.usersSysType != MinIOUsersSysType {
+		return nil, errIAMActionNotAllowed
 	}
 
-	ttl := int64(cred.Expiration.Sub(UTCNow()).Seconds())
+	accessKeys = sys.normalizeAccessKeys(accessKeys)
+	results := make(map[string]error, len(accessKeys))
 
-	sys.store.lock()
+	if err := sys.lockStore(); err != nil {
+		return nil, err
+	}
 	defer sys.store.unlock()
 
-	// If OPA is not set we honor any policy claims for this
-	// temporary user which match with pre-configured canned
-	// policies for this server.
-	if globalPolicyOPA == nil && policyName != "" {
-		mp := newMappedPolicy(policyName)
-		combinedPolicy := sys.GetCombinedPolicy(mp.toSlice()...)
+	if err := sys.LoadAllTypeUsers(); err != nil {
+		return nil, err
+	}
+	if err := sys.loadGroups(); err != nil {
+		return nil, err
+	}
 
-		if combinedPolicy.IsEmpty() {
-			return fmt.Errorf("specified policy %s, not found %w", policyName, errNoSuchPolicy)
+	sys.Lock()
+	toDelete := set.NewStringSet()
+	for _, accessKey := range accessKeys {
+		cr, ok := sys.iamUsersMap[accessKey]
+		if !ok || cr.IsTemp() || cr.IsServiceAccount() {
+			results[accessKey] = errNoSuchUser
+			continue
 		}
+		toDelete.Add(accessKey)
+	}
 
-		if err := sys.store.saveMappedPolicy(context.Background(), accessKey, stsUser, false, mp, options{ttl: ttl}); err != nil {
-			return err
+	// Memberships of the users, and the groups to update with their
+	// remaining members.
+	memberOf := make(map[string][]string, len(toDelete))
+	groups := make(map[string]GroupInfo)
+	for _, accessKey := range toDelete.ToSlice() {
+		memberOf[accessKey] = sys.iamUserGroupMemberships[accessKey].ToSlice()
+		for _, group := range memberOf[accessKey] {
+			gi, ok := groups[group]
+			if !ok {
+				if gi, ok = sys.iamGroupsMap[group]; !ok {
+					continue
+				}
+			}
+			gi.Members = set.CreateStringSet(gi.Members...).Difference(toDelete).ToSlice()
+			groups[group] = gi
 		}
-		sys.Lock()
-		sys.iamUserPolicyMap[accessKey] = mp
-		sys.Unlock()
 	}
+	sys.Unlock()
 
-	u := newUserIdentity(cred)
-	if err := sys.store.saveUserIdentity(context.Background(), accessKey, stsUser, u, options{ttl: ttl}); err != nil {
-		return err
+	if sys.userUndoWindow > 0 {
+		// Keep what is needed to restore the users before
+		// anything is removed.
+		if err := sys.saveUserTombstonesFor(context.Background(), memberOf); err != nil {
+			return nil, err
+		}
 	}
 
-	sys.Lock()
-	sys.iamUsersMap[accessKey] = cred
-	sys.Unlock()
-	return nil
-}
+	// First we remove the users from their groups.
+	for group, gi := range groups {
+		if err := sys.store.saveGroupInfo(context.Background(), group, gi); err != nil {
+			sys.Lock()
+			for _, accessKey := range toDelete.ToSlice() {
+				if sys.iamUserGroupMemberships[accessKey].Contains(group) {
+					results[accessKey] = err
+					toDelete.Remove(accessKey)
+				}
+			}
+			sys.Unlock()
+			continue
+		}
 
-// ListUsers - list all users.
-func (sys *IAMSys) ListUsers() (map[string]madmin.UserInfo, error) {
-	if !sys.Initialized() {
-		return nil, errServerNotInitialized
+		sys.Lock()
+		sys.iamGroupsMap[group] = gi
+		for _, accessKey := range toDelete.ToSlice() {
+			if gset := sys.iamUserGroupMemberships[accessKey]; gset != nil {
+				gset.Remove(group)
+			}
+		}
+		sys.Unlock()
 	}
 
-	if sys.usersSysType != MinIOUsersSysType {
-		return nil, errIAMActionNotAllowed
+	// Next the service accounts and temporary users of the users,
+	// in one pass.
+	var events []IAMCredentialEvent
+	sys.Lock()
+	var dependents []auth.Credentials
+	for _, u := range sys.iamUsersMap {
+		if (u.IsServiceAccount() || u.IsTemp()) && toDelete.Contains(u.ParentUser) {
+			dependents = append(dependents, u)
+		}
 	}
+	sys.Unlock()
 
-	<-sys.configLoaded
+	for _, u := range dependents {
+		userType, credType := srvAccUser, IAMCredentialServiceAccount
+		if u.IsTemp() {
+			userType, credType = stsUser, IAMCredentialSTS
+			// It is ok to ignore deletion error on the mapped policy
+			sys.store.deleteMappedPolicy(context.Background(), u.AccessKey, stsUser, false)
+		}
+		if sys.store.deleteUserIdentity(context.Background(), u.AccessKey, userType) == nil {
+			events = append(events, IAMCredentialEvent{Action: IAMCredentialDeleted, Type: credType, AccessKey: u.AccessKey, ParentUser: u.ParentUser})
+		}
+		sys.Lock()
+		delete(sys.iamUsersMap, u.AccessKey)
+		delete(sys.iamUserPolicyMap, u.AccessKey)
+		sys.Unlock()
+	}
 
-	sys.Lock()
-	defer sys.Unlock()
+	// Finally the users themselves.
+	var deleted []string
+	for _, accessKey := range toDelete.ToSlice() {
+		// It is ok to ignore deletion error on the mapped policy
+		sys.store.deleteMappedPolicy(context.Background(), accessKey, regularUser, false)
+		err := sys.store.deleteUserIdentity(context.Background(), accessKey, regularUser)
+		if errors.Is(err, errNoSuchUser) {
+			// ignore if user is already deleted.
+			err = nil
+		}
+		results[accessKey] = err
 
-	var users = make(map[string]madmin.UserInfo)
+		sys.Lock()
+		delete(sys.iamUsersMap, accessKey)
+		delete(sys.iamUserPolicyMap, accessKey)
+		delete(sys.iamUserGroupMemberships, accessKey)
+		delete(sys.iamSecretRotations, accessKey)
+		delete(sys.iamAdditionalSecretKeys, accessKey)
+		sys.iamMFARequired.Remove(accessKey)
+		delete(sys.iamUserTags, accessKey)
+		sys.Unlock()
 
-	for k, v := range sys.iamUsersMap {
-		if !v.IsTemp() && !v.IsServiceAccount() {
-			users[k] = madmin.UserInfo{
-				PolicyName: sys.iamUserPolicyMap[k].Policies,
-				Status: func() madmin.AccountStatus {
-					if v.IsValid() {
-						return madmin.AccountEnabled
-					}
-					return madmin.AccountDisabled
-				}(),
-			}
+		if err == nil {
+			deleted = append(deleted, accessKey)
+			events = append(events, IAMCredentialEvent{Action: IAMCredentialDeleted, Type: IAMCredentialUser, AccessKey: accessKey})
 		}
 	}
+	sys.deleteUserQuotas(context.Background(), deleted...)
 
-	return users, nil
+	for _, ev := range events {
+		sys.notifyCredential(context.Background(), ev)
+		sys.notifyChange(IAMChangeEvent{ObjectType: IAMObjectUser, Name: ev.AccessKey, Action: IAMChangeDelete})
+	}
+
+	return results, nil
 }
 
-// IsTempUser - returns if given key is a temporary user.
-func (sys *IAMSys) IsTempUser(name string) (bool, string, error) {
+// CurrentPolicies - returns comma separated policy string, from
+// an input policy after validating if there are any current
+// policies which exist on MinIO corresponding to the input.
+func (sys *IAMSys) CurrentPolicies(policyName string) string {
 	if !sys.Initialized() {
-		return false, "", errServerNotInitialized
+		return ""
 	}
 
 	sys.Lock()
 	defer sys.Unlock()
 
-	cred, found := sys.iamUsersMap[name]
-	if !found {
-		return false, "", errNoSuchUser
+	var policies []string
+	mp := newMappedPolicy(policyName)
+	for _, policy := range mp.toSlice() {
+		_, found := sys.iamPolicyDocsMap[policy]
+		if found {
+			policies = append(policies, policy)
+		}
 	}
+	return strings.Join(policies, ",")
+}
 
-	if cred.IsTemp() {
-		return true, cred.ParentUser, nil
-	}
+// SetTempUser - set temporary user credentials, these credentials have an expiry.
+// The expiry is capped to envIAMSTSMaxDuration if set, the effective
+// expiry is returned. Existing credentials with the same access key are
+// overwritten.
+func (sys *IAMSys) SetTempUser(accessKey string, cred auth.Credentials, policyName string) (time.Time, error) {
+	return sys.SetTempUserWithOpts(accessKey, cred, policyName, setTempUserOpts{})
+}
 
-	return false, "", nil
+type setTempUserOpts struct {
+	// rejectDuplicate fails with errSTSAccessKeyConflict instead of
+	// overwriting live credentials with the same access key, unless
+	// they have the same parent user and session token.
+	rejectDuplicate bool
 }
 
-// IsServiceAccount - returns if given key is a service account
-func (sys *IAMSys) IsServiceAccount(name string) (bool, string, error) {
+// SetTempUserWithOpts - same as SetTempUser, duplicate access keys may
+// be rejected in opts.
+func (sys *IAMSys) SetTempUserWithOpts(accessKey string, cred auth.Credentials, policyName string, opts setTempUserOpts) (time.Time, error) {
 	if !sys.Initialized() {
-		return false, "", errServerNotInitialized
+		return time.Time{}, errServerNotInitialized
 	}
 
-	sys.Lock()
-	defer sys.Unlock()
+	if sys.readOnly {
+		return time.Time{}, errIAMReadOnly
+	}
 
-	cred, found := sys.iamUsersMap[name]
-	if !found {
-		return false, "", errNoSuchUser
+	accessKey = sys.normalizeAccessKey(accessKey)
+	cred.AccessKey = sys.normalizeAccessKey(cred.AccessKey)
+
+	if err := checkAccessKeyName(accessKey); err != nil {
+		return time.Time{}, err
 	}
 
-	if cred.IsServiceAccount() {
-		return true, cred.ParentUser, nil
+	if sys.stsMaxDuration > 0 && !cred.Expiration.IsZero() {
+		if maxExpiration := UTCNow().Add(sys.stsMaxDuration); cred.Expiration.After(maxExpiration) {
+			cred.Expiration = maxExpiration
+		}
 	}
 
-	return false, "", nil
-}
+	ttl := int64(cred.Expiration.Sub(UTCNow()).Seconds())
 
-// GetUserInfo - get info on a user.
-func (sys *IAMSys) GetUserInfo(name string) (u madmin.UserInfo, err error) {
-	if !sys.Initialized() {
-		return u, errServerNotInitialized
+	sys.Lock()
+	allowed := sys.allowSTSCredential(cred.ParentUser, UTCNow())
+	sys.Unlock()
+	if !allowed {
+		return time.Time{}, errSTSRateLimited
 	}
 
-	select {
-	case <-sys.configLoaded:
-	default:
-		sys.loadUserFromStore(name)
+	if err := sys.lockStore(); err != nil {
+		return time.Time{}, err
 	}
+	defer sys.store.unlock()
 
-	sys.Lock()
-	defer sys.Unlock()
-
-	if sys.usersSysType != MinIOUsersSysType {
-		// If the user has a mapped policy or is a member of a group, we
-		// return that info. Otherwise we return error.
-		mappedPolicy, ok1 := sys.iamUserPolicyMap[name]
-		memberships, ok2 := sys.iamUserGroupMemberships[name]
-		//sys.Unlock()
-		if !ok1 && !ok2 {
-			return u, errNoSuchUser
+	if opts.rejectDuplicate {
+		if err := sys.checkTempUserDuplicate(accessKey, cred); err != nil {
+			return time.Time{}, err
 		}
-		return madmin.UserInfo{
-			PolicyName: mappedPolicy.Policies,
-			MemberOf:   memberships.ToSlice(),
-		}, nil
 	}
 
-	cred, found := sys.iamUsersMap[name]
-	if !found {
-		return u, errNoSuchUser
+	// If OPA is not set we honor any policy claims for this
+	// temporary user which match with pre-configured canned
+	// policies for this server.
+	if globalPolicyOPA == nil && policyName != "" {
+		mp := newMappedPolicy(policyName)
+		combinedPolicy := sys.GetCombinedPolicy(mp.toSlice()...)
+
+		if combinedPolicy.IsEmpty() {
+			return time.Time{}, fmt.Errorf("specified policy %s, not found %w", policyName, errNoSuchPolicy)
+		}
+
+		if err := sys.store.saveMappedPolicy(context.Background(), accessKey, stsUser, false, mp, options{ttl: ttl}); err != nil {
+			return time.Time{}, err
+		}
+		sys.Lock()
+		sys.iamUserPolicyMap[accessKey] = mp
+		sys.Unlock()
 	}
 
-	if cred.IsTemp() || cred.IsServiceAccount() {
-		return u, errIAMActionNotAllowed
+	u := newUserIdentity(cred)
+	if err := sys.store.saveUserIdentity(context.Background(), accessKey, stsUser, u, options{ttl: ttl}); err != nil {
+		return time.Time{}, err
 	}
 
-	return madmin.UserInfo{
-		PolicyName: sys.iamUserPolicyMap[name].Policies,
-		Status: func() madmin.AccountStatus {
-			if cred.IsValid() {
-				return madmin.AccountEnabled
+	sys.Lock()
+	sys.iamUsersMap[accessKey] = cred
+	delete(sys.negativeUserCache, accessKey)
+	sys.cacheLDAPGroupMemberships(cred)
+	sys.Unlock()
+
+	sys.notifyCredential(context.Background(), IAMCredentialEvent{Action: IAMCredentialCreated, Type: IAMCredentialSTS, AccessKey: accessKey, ParentUser: cred.ParentUser})
+	return cred.Expiration, nil
+}
+
+// checkTempUserDuplicate - returns errSTSAccessKeyConflict if live
+// credentials with the access key exist, in memory or in the store,
+// and are not the same session as cred. IMPORTANT: Assumes that the
+// store lock is held by caller.
+func (sys *IAMSys) checkTempUserDuplicate(accessKey string, cred auth.Credentials) error {
+	sys.Lock()
+	existing, ok := sys.iamUsersMap[accessKey]
+	sys.Unlock()
+
+	if !ok {
+		// Not loaded on this server yet, ask the store.
+		for _, userType := range []IAMUserType{stsUser, srvAccUser, regularUser} {
+			u, err := sys.store.getUserIdentity(context.Background(), accessKey, userType)
+			if err == nil {
+				existing, ok = u.Credentials, true
+				break
 			}
-			return madmin.AccountDisabled
-		}(),
-		MemberOf: sys.iamUserGroupMemberships[name].ToSlice(),
-	}, nil
+			if !errors.Is(err, errNoSuchUser) {
+				return err
+			}
+		}
+	}
 
+	if !ok || existing.IsExpired() {
+		return nil
+	}
+	if existing.IsTemp() && existing.ParentUser == cred.ParentUser && existing.SessionToken == cred.SessionToken {
+		// Refresh of the same session.
+		return nil
+	}
+	return errSTSAccessKeyConflict
 }
 
-// SetUserStatus - sets current user status, supports disabled or enabled.
-func (sys *IAMSys) SetUserStatus(accessKey string, status madmin.AccountStatus) error {
+// DeleteExpiredSTSAccounts - deletes expired temporary users along with
+// their policy mappings from storage and memory, service accounts
+// whose parent is an expired temporary user are deleted as well.
+func (sys *IAMSys) DeleteExpiredSTSAccounts(ctx context.Context) (deleted int, err error) {
 	if !sys.Initialized() {
-		return errServerNotInitialized
+		return 0, errServerNotInitialized
 	}
 
-	if sys.usersSysType != MinIOUsersSysType {
-		return errIAMActionNotAllowed
+	if sys.readOnly {
+		return 0, errIAMReadOnly
 	}
 
-	if status != madmin.AccountEnabled && status != madmin.AccountDisabled {
-		return errInvalidArgument
+	if err := sys.lockStore(); err != nil {
+		return 0, err
 	}
-
-	sys.store.lock()
 	defer sys.store.unlock()
-	if err := sys.LoadUser(accessKey, regularUser); err != nil {
-		return err
-	}
 
 	sys.Lock()
-	cred, ok := sys.iamUsersMap[accessKey]
-	sys.Unlock()
-	if !ok {
-		return errNoSuchUser
+	expired := set.NewStringSet()
+	for k, v := range sys.iamUsersMap {
+		if v.IsTemp() && v.IsExpired() {
+			expired.Add(k)
+		}
+	}
+	var orphans []string
+	for k, v := range sys.iamUsersMap {
+		if v.IsServiceAccount() && expired.Contains(v.ParentUser) {
+			orphans = append(orphans, k)
+		}
 	}
+	sys.Unlock()
 
-	if cred.IsTemp() || cred.IsServiceAccount() {
-		return errIAMActionNotAllowed
+	for _, accessKey := range expired.ToSlice() {
+		if err := sys.store.deleteUserIdentity(ctx, accessKey, stsUser); err != nil && !errors.Is(err, errNoSuchUser) {
+			return deleted, err
+		}
+		// It is ok to ignore deletion error on the mapped policy
+		sys.store.deleteMappedPolicy(ctx, accessKey, stsUser, false)
+
+		sys.Lock()
+		delete(sys.iamUsersMap, accessKey)
+		delete(sys.iamUserPolicyMap, accessKey)
+		sys.Unlock()
+		deleted++
 	}
 
-	uinfo := newUserIdentity(auth.Credentials{
-		AccessKey: accessKey,
-		SecretKey: cred.SecretKey,
-		Status: func() string {
-			if status == madmin.AccountEnabled {
-				return auth.AccountOn
-			}
-			return auth.AccountOff
-		}(),
-	})
+	for _, accessKey := range orphans {
+		if err := sys.store.deleteUserIdentity(ctx, accessKey, srvAccUser); err != nil && !errors.Is(err, errNoSuchUser) {
+			return deleted, err
+		}
 
-	if err := sys.store.saveUserIdentity(context.Background(), accessKey, regularUser, uinfo); err != nil {
-		return err
+		sys.Lock()
+		delete(sys.iamUsersMap, accessKey)
+		sys.Unlock()
+		deleted++
 	}
 
-	sys.Lock()
-	defer sys.Unlock()
-	sys.iamUsersMap[accessKey] = uinfo.Credentials
-	return nil
+	return deleted, nil
 }
 
-type newServiceAccountOpts struct {
-	sessionPolicy *iampolicy.Policy
-	accessKey     string
-	secretKey     string
+// RevokeAllSTS - deletes all temporary credentials issued to the given
+// parent user from storage and memory, along with service accounts
+// created by those credentials, and returns how many were revoked.
+func (sys *IAMSys) RevokeAllSTS(parentUser string) (revoked int, err error) {
+	return sys.RevokeAllSTSWithOpts(context.Background(), parentUser, revokeSTSOpts{})
 }
 
-// NewServiceAccount - create a new service account
-func (sys *IAMSys) NewServiceAccount(ctx context.Context, parentUser string, groups []string, opts newServiceAccountOpts) (auth.Credentials, error) {
+type revokeSTSOpts struct {
+	// serviceAccounts revokes the service accounts of the parent
+	// user as well.
+	serviceAccounts bool
+}
+
+// RevokeAllSTSWithOpts - same as RevokeAllSTS, optionally revoking the
+// service accounts of the parent user too.
+func (sys *IAMSys) RevokeAllSTSWithOpts(ctx context.Context, parentUser string, opts revokeSTSOpts) (revoked int, err error) {
 	if !sys.Initialized() {
-		return auth.Credentials{}, errServerNotInitialized
+		return 0, errServerNotInitialized
 	}
 
-	var policyBuf []byte
-	if opts.sessionPolicy != nil {
-		err := opts.sessionPolicy.Validate()
-		if err != nil {
-			return auth.Credentials{}, err
-		}
-		policyBuf, err = json.Marshal(opts.sessionPolicy)
-		if err != nil {
-			return auth.Credentials{}, err
+	if sys.readOnly {
+		return 0, errIAMReadOnly
+	}
+
+	parentUser = sys.normalizeAccessKey(parentUser)
+	if parentUser == "" {
+		return 0, errInvalidArgument
+	}
+
+	if err := sys.lockStore(); err != nil {
+		return 0, err
+	}
+	defer sys.store.unlock()
+
+	sys.Lock()
+	temp := set.NewStringSet()
+	for k, v := range sys.iamUsersMap {
+		if v.IsTemp() && v.ParentUser == parentUser {
+			temp.Add(k)
+		}
+	}
+	var serviceAccounts []string
+	for k, v := range sys.iamUsersMap {
+		if !v.IsServiceAccount() {
+			continue
 		}
-		if len(policyBuf) > 16*humanize.KiByte {
-			return auth.Credentials{}, fmt.Errorf("Session policy should not exceed 16 KiB characters")
+		if temp.Contains(v.ParentUser) || (opts.serviceAccounts && v.ParentUser == parentUser) {
+			serviceAccounts = append(serviceAccounts, k)
 		}
 	}
+	sys.Unlock()
 
-	if parentUser == globalActiveCred.AccessKey {
-		return auth.Credentials{}, errIAMActionNotAllowed
+	for _, accessKey := range temp.ToSlice() {
+		if err := sys.store.deleteUserIdentity(ctx, accessKey, stsUser); err != nil && !errors.Is(err, errNoSuchUser) {
+			return revoked, err
+		}
+		// It is ok to ignore deletion error on the mapped policy
+		sys.store.deleteMappedPolicy(ctx, accessKey, stsUser, false)
+
+		sys.Lock()
+		delete(sys.iamUsersMap, accessKey)
+		delete(sys.iamUserPolicyMap, accessKey)
+		delete(sys.iamUserGroupMemberships, accessKey)
+		sys.Unlock()
+		revoked++
+
+		sys.notifyCredential(ctx, IAMCredentialEvent{Action: IAMCredentialDeleted, Type: IAMCredentialSTS, AccessKey: accessKey, ParentUser: parentUser})
+	}
+
+	for _, accessKey := range serviceAccounts {
+		if err := sys.store.deleteUserIdentity(ctx, accessKey, srvAccUser); err != nil && !errors.Is(err, errNoSuchUser) {
+			return revoked, err
+		}
+
+		sys.Lock()
+		sa := sys.iamUsersMap[accessKey]
+		delete(sys.iamUsersMap, accessKey)
+		sys.Unlock()
+		revoked++
+
+		sys.notifyCredential(ctx, IAMCredentialEvent{Action: IAMCredentialDeleted, Type: IAMCredentialServiceAccount, AccessKey: accessKey, ParentUser: sa.ParentUser})
+	}
+
+	return revoked, nil
+}
+
+// RepairOrphanedMappings - removes the policy mappings of users and
+// groups which do not exist anymore from storage and memory, and
+// returns how many were removed. Mappings of temporary users and
+// service accounts are keyed on their own access key and are kept as
+// long as the credentials exist, regardless of their parent user.
+func (sys *IAMSys) RepairOrphanedMappings(ctx context.Context) (removed int, err error) {
+	if !sys.Initialized() {
+		return 0, errServerNotInitialized
+	}
+
+	if sys.readOnly {
+		return 0, errIAMReadOnly
+	}
+
+	// Mappings of LDAP users and groups have no counterpart in
+	// the users and groups maps.
+	if sys.usersSysType != MinIOUsersSysType {
+		return 0, errIAMActionNotAllowed
 	}
 
-	sys.store.lock()
+	if err := sys.lockStore(); err != nil {
+		return 0, err
+	}
 	defer sys.store.unlock()
-	if err := sys.LoadAllTypeUsers(); err != nil {
-		return auth.Credentials{}, err
+
+	if err = sys.LoadAllTypeUsers(); err != nil {
+		return 0, err
+	}
+	if err = sys.loadGroups(); err != nil {
+		return 0, err
 	}
 
+	var users, groups []string
 	sys.Lock()
-	cr, ok := sys.iamUsersMap[parentUser]
-	if !ok {
-		// For LDAP users we would need this fallback
-		if sys.usersSysType != MinIOUsersSysType {
-			_, ok = sys.iamUserPolicyMap[parentUser]
-			if !ok {
-				var found bool
-				for _, group := range groups {
-					_, ok = sys.iamGroupPolicyMap[group]
-					if !ok {
-						continue
-					}
-					found = true
-					break
-				}
-				if !found {
-					sys.Unlock()
-					return auth.Credentials{}, errNoSuchUser
-				}
-			}
+	for u := range sys.iamUserPolicyMap {
+		if _, ok := sys.iamUsersMap[u]; !ok {
+			users = append(users, u)
+		}
+	}
+	for g := range sys.iamGroupPolicyMap {
+		if _, ok := sys.iamGroupsMap[g]; !ok {
+			groups = append(groups, g)
 		}
 	}
 	sys.Unlock()
 
-	// Disallow service accounts to further create more service accounts.
-	if cr.IsServiceAccount() {
-		return auth.Credentials{}, errIAMActionNotAllowed
+	for _, u := range users {
+		// The type of the user is unknown once its identity is
+		// gone, the mapping may be under either prefix.
+		for _, userType := range []IAMUserType{regularUser, stsUser} {
+			if err = sys.store.deleteMappedPolicy(ctx, u, userType, false); err != nil && !errors.Is(err, errNoSuchPolicy) {
+				return removed, err
+			}
+		}
+
+		sys.Lock()
+		delete(sys.iamUserPolicyMap, u)
+		sys.Unlock()
+		removed++
 	}
 
-	m := make(map[string]interface{})
-	m[parentClaim] = parentUser
+	for _, g := range groups {
+		if err = sys.store.deleteMappedPolicy(ctx, g, regularUser, true); err != nil && !errors.Is(err, errNoSuchPolicy) {
+			return removed, err
+		}
 
-	if len(policyBuf) > 0 {
-		m[iampolicy.SessionPolicyName] = base64.StdEncoding.EncodeToString(policyBuf)
-		m[iamPolicyClaimNameSA()] = "embedded-policy"
-	} else {
-		m[iamPolicyClaimNameSA()] = "inherited-policy"
+		sys.Lock()
+		delete(sys.iamGroupPolicyMap, g)
+		sys.Unlock()
+		removed++
 	}
 
-	var (
-		cred auth.Credentials
-		err  error
-	)
+	return removed, nil
+}
 
-	if len(opts.accessKey) > 0 {
-		cred, err = auth.CreateNewCredentialsWithMetadata(opts.accessKey, opts.secretKey, m, globalActiveCred.SecretKey)
-	} else {
-		cred, err = auth.GetNewCredentialsWithMetadata(m, globalActiveCred.SecretKey)
+// ListUsers - list all users.
+func (sys *IAMSys) ListUsers() (map[string]madmin.UserInfo, error) {
+	if !sys.Initialized() {
+		return nil, errServerNotInitialized
 	}
-	if err != nil {
-		return auth.Credentials{}, err
+
+	if sys.usersSysType != MinIOUsersSysType {
+		return nil, errIAMActionNotAllowed
 	}
-	cred.ParentUser = parentUser
-	cred.Groups = groups
-	cred.Status = string(auth.AccountOn)
 
-	u := newUserIdentity(cred)
+	<-sys.configLoaded
 
-	if err := sys.store.saveUserIdentity(context.Background(), u.Credentials.AccessKey, srvAccUser, u); err != nil {
-		return auth.Credentials{}, err
-	}
 	sys.Lock()
 	defer sys.Unlock()
-	sys.iamUsersMap[u.Credentials.AccessKey] = u.Credentials
 
-	return cred, nil
-}
+	var users = make(map[string]madmin.UserInfo)
 
-type updateServiceAccountOpts struct {
-	sessionPolicy *iampolicy.Policy
-	secretKey     string
-	status        string
+	for k, v := range sys.iamUsersMap {
+		if !v.IsTemp() && !v.IsServiceAccount() {
+			users[k] = madmin.UserInfo{
+				PolicyName: sys.iamUserPolicyMap[k].Policies,
+				Status: func() madmin.AccountStatus {
+					if v.IsValid() {
+						return madmin.AccountEnabled
+					}
+					return madmin.AccountDisabled
+				}(),
+			}
+		}
+	}
+
+	return users, nil
 }
 
-// UpdateServiceAccount - edit a service account
-func (sys *IAMSys) UpdateServiceAccount(ctx context.Context, accessKey string, opts updateServiceAccountOpts) error {
+// ListDisabledUsers - lists the sorted access keys of the regular users
+// which ListUsers reports as disabled.
+func (sys *IAMSys) ListDisabledUsers() ([]string, error) {
 	if !sys.Initialized() {
-		return errServerNotInitialized
+		return nil, errServerNotInitialized
 	}
 
-	// lock disk config
-	sys.store.lock()
-	defer sys.store.unlock()
-
-	if err := sys.LoadUser(accessKey, srvAccUser); err != nil {
-		return err
+	if sys.usersSysType != MinIOUsersSysType {
+		return nil, errIAMActionNotAllowed
 	}
 
+	<-sys.configLoaded
+
 	sys.Lock()
-	cr, ok := sys.iamUsersMap[accessKey]
-	if !ok || !cr.IsServiceAccount() {
-		sys.Unlock()
-		return errNoSuchServiceAccount
-	}
+	defer sys.Unlock()
 
-	if opts.secretKey != "" {
-		if !auth.IsSecretKeyValid(opts.secretKey) {
-			return auth.ErrInvalidSecretKeyLength
+	var users []string
+	for k, v := range sys.iamUsersMap {
+		if !v.IsTemp() && !v.IsServiceAccount() && !v.IsValid() {
+			users = append(users, k)
 		}
-		cr.SecretKey = opts.secretKey
 	}
+	sort.Strings(users)
 
-	if opts.status != "" {
-		cr.Status = opts.status
+	return users, nil
+}
+
+// ListUsersInGroup - lists the users which are direct members of the
+// group, as ListUsers would report them. Nested groups and members
+// which no longer exist are skipped.
+func (sys *IAMSys) ListUsersInGroup(group string) (map[string]madmin.UserInfo, error) {
+	if !sys.Initialized() {
+		return nil, errServerNotInitialized
 	}
-	sys.Unlock()
 
-	if opts.sessionPolicy != nil {
-		err := opts.sessionPolicy.Validate()
-		if err != nil {
-			return err
+	if sys.usersSysType != MinIOUsersSysType {
+		return nil, errIAMActionNotAllowed
+	}
+
+	<-sys.configLoaded
+
+	sys.Lock()
+	defer sys.Unlock()
+
+	gi, ok := sys.iamGroupsMap[group]
+	if !ok {
+		return nil, errNoSuchGroup
+	}
+
+	users := make(map[string]madmin.UserInfo, len(gi.Members))
+	for _, member := range gi.Members {
+		if _, ok := nestedGroupName(member); ok {
+			continue
 		}
-		policyBuf, err := json.Marshal(opts.sessionPolicy)
-		if err != nil {
-			return err
+		cred, found := sys.iamUsersMap[member]
+		if !found || cred.IsTemp() || cred.IsServiceAccount() {
+			continue
 		}
-		if len(policyBuf) > 16*humanize.KiByte {
-			return fmt.Errorf("Session policy should not exceed 16 KiB characters")
+		status := madmin.AccountDisabled
+		if cred.IsValid() {
+			status = madmin.AccountEnabled
 		}
-
-		m := make(map[string]interface{})
-		m[iampolicy.SessionPolicyName] = base64.StdEncoding.EncodeToString(policyBuf)
-		m[iamPolicyClaimNameSA()] = "embedded-policy"
-		m[parentClaim] = cr.ParentUser
-		cr.SessionToken, err = auth.JWTSignWithAccessKey(accessKey, m, globalActiveCred.SecretKey)
-		if err != nil {
-			return err
+		users[member] = madmin.UserInfo{
+			PolicyName: sys.iamUserPolicyMap[member].sortedPolicies(),
+			Status:     status,
+			MemberOf:   sys.iamUserGroupMemberships[member].ToSlice(),
 		}
 	}
 
-	// update disk config
-	u := newUserIdentity(cr)
-	if err := sys.store.saveUserIdentity(context.Background(), u.Credentials.AccessKey, srvAccUser, u); err != nil {
-		return err
+	return users, nil
+}
+
+// ForEachUser - calls fn for every user, temporary user and service
+// account in no particular order until fn returns false. fn is called
+// with sys.Lock held and must not call any IAMSys methods, or it will
+// deadlock.
+func (sys *IAMSys) ForEachUser(fn func(accessKey string, cred auth.Credentials) bool) error {
+	if !sys.Initialized() {
+		return errServerNotInitialized
 	}
 
-	// update cache
+	<-sys.configLoaded
+
 	sys.Lock()
 	defer sys.Unlock()
-	sys.iamUsersMap[u.Credentials.AccessKey] = u.Credentials
 
+	for k, v := range sys.iamUsersMap {
+		if !fn(k, v) {
+			break
+		}
+	}
 	return nil
 }
 
-// ListServiceAccounts - lists all services accounts associated to a specific user
-func (sys *IAMSys) ListServiceAccounts(ctx context.Context, accessKey string) ([]auth.Credentials, error) {
+// Stats - returns the number of users of each type, groups and
+// policies, without copying any of the maps.
+func (sys *IAMSys) Stats() madmin.IAMStats {
+	var stats madmin.IAMStats
 	if !sys.Initialized() {
-		return nil, errServerNotInitialized
+		return stats
 	}
 
-	<-sys.configLoaded
-
 	sys.Lock()
 	defer sys.Unlock()
 
-	var serviceAccounts []auth.Credentials
-	for _, v := range sys.iamUsersMap {
-		if v.IsServiceAccount() && v.ParentUser == accessKey {
-			// Hide secret key & session key here
-			v.SecretKey = ""
-			v.SessionToken = ""
-			serviceAccounts = append(serviceAccounts, v)
+	for _, cred := range sys.iamUsersMap {
+		switch {
+		case cred.IsTemp():
+			stats.STSUsers++
+		case cred.IsServiceAccount():
+			stats.ServiceAccounts++
+		default:
+			stats.Users++
 		}
 	}
+	stats.Groups = len(sys.iamGroupsMap)
+	stats.Policies = len(sys.iamPolicyDocsMap)
+	return stats
+}
 
-	return serviceAccounts, nil
+// IAMSnapshot - a consistent copy of the IAM state of the server.
+type IAMSnapshot struct {
+	Users          map[string]auth.Credentials
+	Groups         map[string]GroupInfo
+	Policies       map[string]iampolicy.Policy
+	UserPolicyMap  map[string]MappedPolicy
+	GroupPolicyMap map[string]MappedPolicy
 }
 
-// GetServiceAccount - gets information about a service account
-func (sys *IAMSys) GetServiceAccount(ctx context.Context, accessKey string) (auth.Credentials, *iampolicy.Policy, error) {
+// Snapshot - returns a copy of all users, groups, policies and policy
+// mappings taken under a single lock, so that they are consistent with
+// each other. Callers may modify the returned maps freely.
+func (sys *IAMSys) Snapshot() (IAMSnapshot, error) {
+	var snap IAMSnapshot
 	if !sys.Initialized() {
-		return auth.Credentials{}, nil, errServerNotInitialized
+		return snap, errServerNotInitialized
+	}
+
+	<-sys.configLoaded
+
+	if err := sys.loadLazyPolicyDocs(); err != nil {
+		return snap, err
 	}
 
 	sys.Lock()
 	defer sys.Unlock()
 
-	sa, ok := sys.iamUsersMap[accessKey]
-	if !ok || !sa.IsServiceAccount() {
-		return auth.Credentials{}, nil, errNoSuchServiceAccount
+	snap.Users = make(map[string]auth.Credentials, len(sys.iamUsersMap))
+	for k, v := range sys.iamUsersMap {
+		v.Groups = append([]string(nil), v.Groups...)
+		snap.Users[k] = v
 	}
 
-	var embeddedPolicy *iampolicy.Policy
+	snap.Groups = make(map[string]GroupInfo, len(sys.iamGroupsMap))
+	for k, v := range sys.iamGroupsMap {
+		v.Members = append([]string(nil), v.Members...)
+		snap.Groups[k] = v
+	}
 
-	jwtClaims, err := auth.ExtractClaims(sa.SessionToken, globalActiveCred.SecretKey)
-	if err == nil {
-		pt, ptok := jwtClaims.Lookup(iamPolicyClaimNameSA())
-		sp, spok := jwtClaims.Lookup(iampolicy.SessionPolicyName)
-		if ptok && spok && pt == "embedded-policy" {
-			policyBytes, err := base64.StdEncoding.DecodeString(sp)
-			if err == nil {
-				p, err := iampolicy.ParseConfig(bytes.NewReader(policyBytes))
-				if err == nil {
-					policy := iampolicy.Policy{}.Merge(*p)
-					embeddedPolicy = &policy
-				}
-			}
+	snap.Policies = make(map[string]iampolicy.Policy, len(sys.iamPolicyDocsMap))
+	for k, v := range sys.iamPolicyDocsMap {
+		statements := make([]iampolicy.Statement, 0, len(v.Statements))
+		for _, st := range v.Statements {
+			clone := st.Clone()
+			clone.SID = st.SID
+			statements = append(statements, clone)
 		}
+		v.Statements = statements
+		snap.Policies[k] = v
 	}
 
-	// Hide secret & session keys
-	sa.SecretKey = ""
-	sa.SessionToken = ""
+	snap.UserPolicyMap = make(map[string]MappedPolicy, len(sys.iamUserPolicyMap))
+	for k, v := range sys.iamUserPolicyMap {
+		snap.UserPolicyMap[k] = v
+	}
 
-	return sa, embeddedPolicy, nil
+	snap.GroupPolicyMap = make(map[string]MappedPolicy, len(sys.iamGroupPolicyMap))
+	for k, v := range sys.iamGroupPolicyMap {
+		snap.GroupPolicyMap[k] = v
+	}
+
+	return snap, nil
 }
 
-// DeleteServiceAccount - delete a service account
-func (sys *IAMSys) DeleteServiceAccount(ctx context.Context, accessKey string) error {
-	if !sys.Initialized() {
-		return errServerNotInitialized
+// IsTempUser - returns if given key is a temporary user.
+func (sys *IAMSys) IsTempUser(name string) (bool, string, error) {
+	if !sys.Initialized() {
+		return false, "", errServerNotInitialized
+	}
+
+	sys.Lock()
+	defer sys.Unlock()
+
+	cred, found := sys.iamUsersMap[name]
+	if !found {
+		return false, "", errNoSuchUser
+	}
+
+	if cred.IsTemp() {
+		return true, cred.ParentUser, nil
+	}
+
+	return false, "", nil
+}
+
+// IsServiceAccount - returns if given key is a service account
+func (sys *IAMSys) IsServiceAccount(name string) (bool, string, error) {
+	if !sys.Initialized() {
+		return false, "", errServerNotInitialized
+	}
+
+	sys.Lock()
+	defer sys.Unlock()
+
+	cred, found := sys.iamUsersMap[name]
+	if !found {
+		return false, "", errNoSuchUser
+	}
+
+	if cred.IsServiceAccount() {
+		return true, cred.ParentUser, nil
+	}
+
+	return false, "", nil
+}
+
+// GetUserInfo - get info on a user.
+func (sys *IAMSys) GetUserInfo(name string) (u madmin.UserInfo, err error) {
+	if !sys.Initialized() {
+		return u, errServerNotInitialized
+	}
+
+	name = sys.normalizeAccessKey(name)
+
+	select {
+	case <-sys.configLoaded:
+	default:
+		sys.loadUserFromStore(context.Background(), name)
+	}
+
+	sys.Lock()
+	defer sys.Unlock()
+
+	if sys.usersSysType != MinIOUsersSysType {
+		// If the user has a mapped policy or is a member of a group, we
+		// return that info. Otherwise we return error.
+		mappedPolicy, ok1 := sys.iamUserPolicyMap[name]
+		memberships, ok2 := sys.iamUserGroupMemberships[name]
+		//sys.Unlock()
+		if !ok1 && !ok2 {
+			return u, errNoSuchUser
+		}
+		return madmin.UserInfo{
+			PolicyName: mappedPolicy.sortedPolicies(),
+			MemberOf:   memberships.ToSlice(),
+		}, nil
+	}
+
+	cred, found := sys.iamUsersMap[name]
+	if !found {
+		return u, errNoSuchUser
+	}
+
+	if cred.IsTemp() || cred.IsServiceAccount() {
+		return u, errIAMActionNotAllowed
+	}
+
+	return madmin.UserInfo{
+		PolicyName: sys.iamUserPolicyMap[name].sortedPolicies(),
+		Status: func() madmin.AccountStatus {
+			if cred.IsValid() {
+				return madmin.AccountEnabled
+			}
+			return madmin.AccountDisabled
+		}(),
+		MemberOf: sys.iamUserGroupMemberships[name].ToSlice(),
+	}, nil
+
+}
+
+// GetAccountInfo - get info on any user, i.e. regular users, temporary
+// users and service accounts. Service accounts report the policy
+// inherited from their parent user.
+func (sys *IAMSys) GetAccountInfo(accessKey string) (u madmin.UserInfo, userType IAMUserType, err error) {
+	if !sys.Initialized() {
+		return u, regularUser, errServerNotInitialized
+	}
+
+	accessKey = sys.normalizeAccessKey(accessKey)
+
+	select {
+	case <-sys.configLoaded:
+	default:
+		sys.loadUserFromStore(context.Background(), accessKey)
+	}
+
+	sys.Lock()
+	defer sys.Unlock()
+
+	cred, found := sys.iamUsersMap[accessKey]
+	if !found {
+		return u, regularUser, errNoSuchUser
+	}
+
+	switch {
+	case cred.IsTemp():
+		userType = stsUser
+	case cred.IsServiceAccount():
+		userType = srvAccUser
+	default:
+		userType = regularUser
+	}
+
+	mp, ok := sys.iamUserPolicyMap[accessKey]
+	if !ok && cred.ParentUser != "" {
+		mp = sys.iamUserPolicyMap[cred.ParentUser]
+	}
+
+	memberOf := set.CreateStringSet(cred.Groups...)
+	memberOf = memberOf.Union(sys.iamUserGroupMemberships[accessKey])
+	if userType == srvAccUser {
+		memberOf = memberOf.Union(sys.iamUserGroupMemberships[cred.ParentUser])
+	}
+
+	return madmin.UserInfo{
+		PolicyName: mp.Policies,
+		Status: func() madmin.AccountStatus {
+			if cred.IsValid() {
+				return madmin.AccountEnabled
+			}
+			return madmin.AccountDisabled
+		}(),
+		MemberOf:       memberOf.ToSlice(),
+		ParentUser:     cred.ParentUser,
+		EmbeddedPolicy: userType == srvAccUser && getEmbeddedPolicy(cred) != nil,
+	}, userType, nil
+}
+
+// SetUserStatus - sets current user status, supports disabled or enabled.
+func (sys *IAMSys) SetUserStatus(accessKey string, status madmin.AccountStatus) error {
+	if !sys.Initialized() {
+		return errServerNotInitialized
+	}
+
+	if sys.readOnly {
+		return errIAMReadOnly
+	}
+
+	accessKey = sys.normalizeAccessKey(accessKey)
+
+	if sys.usersSysType != MinIOUsersSysType {
+		return errIAMActionNotAllowed
+	}
+
+	if status != madmin.AccountEnabled && status != madmin.AccountDisabled {
+		return errInvalidArgument
+	}
+
+	if err := sys.lockStore(); err != nil {
+		return err
+	}
+	defer sys.store.unlock()
+	if err := sys.LoadUser(accessKey, regularUser); err != nil {
+		return err
+	}
+
+	sys.Lock()
+	cred, ok := sys.iamUsersMap[accessKey]
+	sys.Unlock()
+	if !ok {
+		return errNoSuchUser
+	}
+
+	if cred.IsTemp() || cred.IsServiceAccount() {
+		return errIAMActionNotAllowed
+	}
+
+	uinfo := sys.persistedUserIdentity(auth.Credentials{
+		AccessKey: accessKey,
+		SecretKey: cred.SecretKey,
+		Status: func() string {
+			if status == madmin.AccountEnabled {
+				return auth.AccountOn
+			}
+			return auth.AccountOff
+		}(),
+	})
+
+	if err := sys.store.saveUserIdentity(context.Background(), accessKey, regularUser, uinfo); err != nil {
+		return err
+	}
+
+	sys.Lock()
+	defer sys.Unlock()
+	sys.iamUsersMap[accessKey] = uinfo.Credentials
+	return nil
+}
+
+type newServiceAccountOpts struct {
+	sessionPolicy *iampolicy.Policy
+	accessKey     string
+	secretKey     string
+
+	// expiry is optional, service accounts without an expiry
+	// never expire.
+	expiry time.Time
+
+	// inheritPolicies optionally restricts the service account to
+	// these policies of the parent user instead of all of them.
+	inheritPolicies []string
+
+	// name optionally derives the access key from the parent user
+	// and this name, cannot be combined with accessKey. Creating a
+	// service account again with the same name updates it in place
+	// and keeps its secret key unless secretKey is set.
+	name string
+}
+
+// deriveServiceAccountKey - returns the access key of the service
+// account of parentUser with the given name. The key only depends on
+// both names, so that the same name under different parents yields
+// different keys. Should a derived key ever clash with an existing
+// user or with a service account of another parent, creation fails
+// instead of taking it over.
+func deriveServiceAccountKey(parentUser, name string) string {
+	const alphaNumericTable = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+
+	sum := sha256.Sum256([]byte(parentUser + "\x00" + name))
+	key := make([]byte, 20)
+	for i := range key {
+		key[i] = alphaNumericTable[sum[i]%byte(len(alphaNumericTable))]
+	}
+	return string(key)
+}
+
+// NewServiceAccount - create a new service account
+func (sys *IAMSys) NewServiceAccount(ctx context.Context, parentUser string, groups []string, opts newServiceAccountOpts) (auth.Credentials, error) {
+	if !sys.Initialized() {
+		return auth.Credentials{}, errServerNotInitialized
+	}
+
+	if sys.readOnly {
+		return auth.Credentials{}, errIAMReadOnly
+	}
+
+	var policyBuf []byte
+	if opts.sessionPolicy != nil {
+		err := opts.sessionPolicy.Validate()
+		if err != nil {
+			return auth.Credentials{}, err
+		}
+		policyBuf, err = json.Marshal(opts.sessionPolicy)
+		if err != nil {
+			return auth.Credentials{}, err
+		}
+		if int64(len(policyBuf)) > sys.sessionPolicyMaxSize {
+			return auth.Credentials{}, fmt.Errorf("Session policy should not exceed %s characters", humanize.IBytes(uint64(sys.sessionPolicyMaxSize)))
+		}
+	}
+
+	if parentUser == globalActiveCred.AccessKey {
+		return auth.Credentials{}, errIAMActionNotAllowed
+	}
+
+	if !opts.expiry.IsZero() && !opts.expiry.After(UTCNow()) {
+		return auth.Credentials{}, errInvalidArgument
+	}
+
+	credPolicy := sys.getCredentialPolicy()
+	if opts.accessKey != "" {
+		if err := checkIAMName(opts.accessKey); err != nil {
+			return auth.Credentials{}, err
+		}
+		if err := checkAccessKeyName(opts.accessKey); err != nil {
+			return auth.Credentials{}, err
+		}
+		if err := credPolicy.validateAccessKey(opts.accessKey); err != nil {
+			return auth.Credentials{}, err
+		}
+	}
+	if opts.secretKey != "" {
+		if err := credPolicy.validateSecretKey(opts.secretKey); err != nil {
+			return auth.Credentials{}, err
+		}
+	}
+
+	if opts.name != "" {
+		if opts.accessKey != "" {
+			return auth.Credentials{}, errInvalidArgument
+		}
+		opts.accessKey = deriveServiceAccountKey(parentUser, opts.name)
+	}
+
+	if err := sys.lockStore(); err != nil {
+		return auth.Credentials{}, err
+	}
+	defer sys.store.unlock()
+	if err := sys.LoadAllTypeUsers(); err != nil {
+		return auth.Credentials{}, err
+	}
+
+	sys.Lock()
+	var existing *auth.Credentials
+	if opts.name != "" {
+		if ecr, found := sys.iamUsersMap[opts.accessKey]; found {
+			if !ecr.IsServiceAccount() || ecr.ParentUser != parentUser {
+				sys.Unlock()
+				return auth.Credentials{}, fmt.Errorf("access key %s derived from %s is already in use: %w", opts.accessKey, opts.name, errInvalidArgument)
+			}
+			existing = &ecr
+		}
+	}
+	if existing == nil && sys.maxServiceAccountsPerUser > 0 {
+		var count int
+		for _, v := range sys.iamUsersMap {
+			if v.IsServiceAccount() && v.ParentUser == parentUser {
+				count++
+			}
+		}
+		if count >= sys.maxServiceAccountsPerUser {
+			sys.Unlock()
+			return auth.Credentials{}, fmt.Errorf("user %s already has %d service accounts, the maximum allowed: %w", parentUser, count, errInvalidArgument)
+		}
+	}
+	cr, ok := sys.iamUsersMap[parentUser]
+	if !ok {
+		// For LDAP users we would need this fallback
+		if sys.usersSysType != MinIOUsersSysType {
+			_, ok = sys.iamUserPolicyMap[parentUser]
+			if !ok {
+				var found bool
+				for _, group := range groups {
+					_, ok = sys.iamGroupPolicyMap[group]
+					if !ok {
+						continue
+					}
+					found = true
+					break
+				}
+				if !found {
+					sys.Unlock()
+					return auth.Credentials{}, errNoSuchUser
+				}
+			}
+		}
+	}
+	sys.Unlock()
+
+	// Disallow service accounts to further create more service accounts.
+	if cr.IsServiceAccount() {
+		return auth.Credentials{}, errIAMActionNotAllowed
+	}
+
+	m := make(map[string]interface{})
+	m[parentClaim] = parentUser
+
+	if len(opts.inheritPolicies) > 0 {
+		parentPolicies, err := sys.PolicyDBGet(parentUser, false, groups...)
+		if err != nil {
+			return auth.Credentials{}, err
+		}
+		pset := set.CreateStringSet(parentPolicies...)
+		for _, pname := range opts.inheritPolicies {
+			if !pset.Contains(pname) {
+				return auth.Credentials{}, fmt.Errorf("policy %s is not mapped to %s: %w", pname, parentUser, errInvalidArgument)
+			}
+		}
+		m[saInheritedPoliciesClaim] = strings.Join(opts.inheritPolicies, ",")
+	}
+
+	if len(policyBuf) > 0 {
+		m[iampolicy.SessionPolicyName] = base64.StdEncoding.EncodeToString(policyBuf)
+		m[iamPolicyClaimNameSA()] = "embedded-policy"
+	} else {
+		m[iamPolicyClaimNameSA()] = "inherited-policy"
+	}
+
+	var opt options
+	if !opts.expiry.IsZero() {
+		// Setting the expiry claim makes the session token and
+		// cred.Expiration honor it as well.
+		m["exp"] = opts.expiry.Unix()
+		opt.ttl = int64(opts.expiry.Sub(UTCNow()).Seconds())
+	}
+
+	var (
+		cred auth.Credentials
+		err  error
+	)
+
+	switch {
+	case existing != nil && opts.secretKey == "":
+		cred, err = auth.CreateNewCredentialsWithMetadata(opts.accessKey, existing.SecretKey, m, globalActiveCred.SecretKey)
+	case opts.name != "" && opts.secretKey == "":
+		// Only the access key is derived, the secret stays random.
+		if cred, err = auth.GetNewCredentialsWithMetadata(m, globalActiveCred.SecretKey); err == nil {
+			cred, err = auth.CreateNewCredentialsWithMetadata(opts.accessKey, cred.SecretKey, m, globalActiveCred.SecretKey)
+		}
+	case len(opts.accessKey) > 0:
+		cred, err = auth.CreateNewCredentialsWithMetadata(opts.accessKey, opts.secretKey, m, globalActiveCred.SecretKey)
+	default:
+		cred, err = auth.GetNewCredentialsWithMetadata(m, globalActiveCred.SecretKey)
+	}
+	if err != nil {
+		return auth.Credentials{}, err
+	}
+	cred.ParentUser = parentUser
+	cred.Groups = groups
+	cred.Status = string(auth.AccountOn)
+	if existing != nil {
+		// Re-applying must not re-enable a suspended service account.
+		cred.Status = existing.Status
+	}
+	cred.ServiceAccount = !opts.expiry.IsZero()
+
+	u := newUserIdentity(cred)
+	if existing != nil {
+		u = sys.persistedUserIdentity(cred)
+	}
+
+	if err := sys.store.saveUserIdentity(context.Background(), u.Credentials.AccessKey, srvAccUser, u, opt); err != nil {
+		return auth.Credentials{}, err
+	}
+	sys.Lock()
+	sys.iamUsersMap[u.Credentials.AccessKey] = u.Credentials
+	delete(sys.negativeUserCache, u.Credentials.AccessKey)
+	sys.Unlock()
+
+	if existing == nil {
+		sys.notifyCredential(ctx, IAMCredentialEvent{Action: IAMCredentialCreated, Type: IAMCredentialServiceAccount, AccessKey: cred.AccessKey, ParentUser: parentUser})
+	}
+
+	return cred, nil
+}
+
+type updateServiceAccountOpts struct {
+	sessionPolicy *iampolicy.Policy
+	secretKey     string
+	status        string
+
+	// clearSessionPolicy removes the embedded session policy so
+	// that the service account inherits the parent's policies
+	// again, cannot be combined with sessionPolicy.
+	clearSessionPolicy bool
+}
+
+// UpdateServiceAccount - edit a service account
+func (sys *IAMSys) UpdateServiceAccount(ctx context.Context, accessKey string, opts updateServiceAccountOpts) error {
+	if !sys.Initialized() {
+		return errServerNotInitialized
+	}
+
+	if sys.readOnly {
+		return errIAMReadOnly
+	}
+
+	if opts.sessionPolicy != nil && opts.clearSessionPolicy {
+		return errInvalidArgument
+	}
+
+	if opts.secretKey != "" {
+		if err := sys.getCredentialPolicy().validateSecretKey(opts.secretKey); err != nil {
+			return err
+		}
+	}
+
+	// lock disk config
+	if err := sys.lockStore(); err != nil {
+		return err
+	}
+	defer sys.store.unlock()
+
+	if err := sys.LoadUser(accessKey, srvAccUser); err != nil {
+		return err
+	}
+
+	sys.Lock()
+	cr, ok := sys.iamUsersMap[accessKey]
+	if !ok || !cr.IsServiceAccount() {
+		sys.Unlock()
+		return errNoSuchServiceAccount
+	}
+
+	if opts.secretKey != "" {
+		if !auth.IsSecretKeyValid(opts.secretKey) {
+			return auth.ErrInvalidSecretKeyLength
+		}
+		cr.SecretKey = opts.secretKey
+	}
+
+	if opts.status != "" {
+		cr.Status = opts.status
+	}
+	sys.Unlock()
+
+	if opts.sessionPolicy != nil || opts.clearSessionPolicy {
+		claims, err := auth.ExtractClaims(cr.SessionToken, globalActiveCred.SecretKey)
+		if err != nil {
+			return err
+		}
+
+		// Keep the other claims, such as the inherited policies.
+		m := make(map[string]interface{}, len(claims.MapClaims))
+		for k, v := range claims.MapClaims {
+			m[k] = v
+		}
+		delete(m, iampolicy.SessionPolicyName)
+		m[iamPolicyClaimNameSA()] = "inherited-policy"
+		m[parentClaim] = cr.ParentUser
+
+		if opts.sessionPolicy != nil {
+			if err = opts.sessionPolicy.Validate(); err != nil {
+				return err
+			}
+			policyBuf, err := json.Marshal(opts.sessionPolicy)
+			if err != nil {
+				return err
+			}
+			if int64(len(policyBuf)) > sys.sessionPolicyMaxSize {
+				return fmt.Errorf("Session policy should not exceed %s characters", humanize.IBytes(uint64(sys.sessionPolicyMaxSize)))
+			}
+			m[iampolicy.SessionPolicyName] = base64.StdEncoding.EncodeToString(policyBuf)
+			m[iamPolicyClaimNameSA()] = "embedded-policy"
+		}
+
+		if cr.ServiceAccount {
+			// Preserve the expiry of the service account.
+			m["exp"] = cr.Expiration.Unix()
+		}
+		cr.SessionToken, err = auth.JWTSignWithAccessKey(accessKey, m, globalActiveCred.SecretKey)
+		if err != nil {
+			return err
+		}
+	}
+
+	// update disk config
+	u := sys.persistedUserIdentity(cr)
+	if err := sys.store.saveUserIdentity(context.Background(), u.Credentials.AccessKey, srvAccUser, u); err != nil {
+		return err
+	}
+
+	// update cache
+	sys.Lock()
+	defer sys.Unlock()
+	sys.iamUsersMap[u.Credentials.AccessKey] = u.Credentials
+
+	return nil
+}
+
+// SetServiceAccountStatus - enables or disables a service account,
+// keeping its secret key and policy. A disabled service account is
+// rejected by GetUser until it is enabled again.
+func (sys *IAMSys) SetServiceAccountStatus(ctx context.Context, accessKey string, enabled bool) error {
+	if !sys.Initialized() {
+		return errServerNotInitialized
+	}
+
+	if sys.readOnly {
+		return errIAMReadOnly
+	}
+
+	if err := sys.lockStore(); err != nil {
+		return err
+	}
+	defer sys.store.unlock()
+
+	if err := sys.loadUserCtx(ctx, accessKey, srvAccUser); err != nil {
+		return err
+	}
+
+	sys.Lock()
+	cr, ok := sys.iamUsersMap[accessKey]
+	sys.Unlock()
+	if !ok || !cr.IsServiceAccount() {
+		return errNoSuchServiceAccount
+	}
+
+	cr.Status = auth.AccountOff
+	if enabled {
+		cr.Status = auth.AccountOn
+	}
+
+	u := sys.persistedUserIdentity(cr)
+	if err := sys.store.saveUserIdentity(ctx, accessKey, srvAccUser, u); err != nil {
+		return err
+	}
+
+	sys.Lock()
+	defer sys.Unlock()
+	sys.iamUsersMap[accessKey] = u.Credentials
+	return nil
+}
+
+// ListServiceAccounts - lists all services accounts associated to a specific user
+func (sys *IAMSys) ListServiceAccounts(ctx context.Context, accessKey string) ([]auth.Credentials, error) {
+	if !sys.Initialized() {
+		return nil, errServerNotInitialized
+	}
+
+	<-sys.configLoaded
+
+	sys.Lock()
+	defer sys.Unlock()
+
+	var serviceAccounts []auth.Credentials
+	for _, v := range sys.iamUsersMap {
+		if v.IsServiceAccount() && v.ParentUser == accessKey {
+			// Hide secret key & session key here
+			v.SecretKey = ""
+			v.SessionToken = ""
+			serviceAccounts = append(serviceAccounts, v)
+		}
+	}
+
+	return serviceAccounts, nil
+}
+
+// ListAllServiceAccounts - lists the service accounts of all parent
+// users, sorted by access key. Secrets are never returned.
+func (sys *IAMSys) ListAllServiceAccounts() ([]madmin.ServiceAccountInfo, error) {
+	if !sys.Initialized() {
+		return nil, errServerNotInitialized
+	}
+
+	<-sys.configLoaded
+
+	sys.Lock()
+	defer sys.Unlock()
+
+	var serviceAccounts []madmin.ServiceAccountInfo
+	for _, v := range sys.iamUsersMap {
+		if !v.IsServiceAccount() {
+			continue
+		}
+		serviceAccounts = append(serviceAccounts, madmin.ServiceAccountInfo{
+			ParentUser:    v.ParentUser,
+			AccessKey:     v.AccessKey,
+			AccountStatus: v.Status,
+			ImpliedPolicy: getEmbeddedPolicy(v) == nil,
+			Tags:          sys.iamUserTags[v.AccessKey],
+		})
+	}
+	sort.Slice(serviceAccounts, func(i, j int) bool {
+		return serviceAccounts[i].AccessKey < serviceAccounts[j].AccessKey
+	})
+
+	return serviceAccounts, nil
+}
+
+// STSInfo - describes temporary credentials, without any secret.
+type STSInfo struct {
+	AccessKey  string    `json:"accessKey"`
+	ParentUser string    `json:"parentUser,omitempty"`
+	Expiration time.Time `json:"expiration"`
+	// Policies mapped to the credentials, if any.
+	Policies []string `json:"policies,omitempty"`
+	// Set for credentials which expired but were not purged yet.
+	Expired bool `json:"expired,omitempty"`
+}
+
+// ListSTSAccounts - lists the temporary credentials known to this
+// server, sorted by access key. Expired credentials are listed until
+// they are purged.
+func (sys *IAMSys) ListSTSAccounts() ([]STSInfo, error) {
+	if !sys.Initialized() {
+		return nil, errServerNotInitialized
+	}
+
+	<-sys.configLoaded
+
+	sys.Lock()
+	defer sys.Unlock()
+
+	var stsAccounts []STSInfo
+	for k, v := range sys.iamUsersMap {
+		if !v.IsTemp() {
+			continue
+		}
+		stsAccounts = append(stsAccounts, STSInfo{
+			AccessKey:  k,
+			ParentUser: v.ParentUser,
+			Expiration: v.Expiration,
+			Policies:   sys.iamUserPolicyMap[k].toSlice(),
+			Expired:    v.IsExpired(),
+		})
+	}
+	sort.Slice(stsAccounts, func(i, j int) bool {
+		return stsAccounts[i].AccessKey < stsAccounts[j].AccessKey
+	})
+
+	return stsAccounts, nil
+}
+
+// GetServiceAccount - gets information about a service account
+func (sys *IAMSys) GetServiceAccount(ctx context.Context, accessKey string) (auth.Credentials, *iampolicy.Policy, error) {
+	sa, embeddedPolicy, err := sys.GetServiceAccountWithSecret(ctx, accessKey)
+	if err != nil {
+		return auth.Credentials{}, nil, err
+	}
+
+	// Hide secret & session keys
+	sa.SecretKey = ""
+	sa.SessionToken = ""
+
+	return sa, embeddedPolicy, nil
+}
+
+// GetServiceAccountWithSecret - same as GetServiceAccount but returns the
+// secret key and session token as well. Callers must make sure that the
+// requester is authorized to see them.
+func (sys *IAMSys) GetServiceAccountWithSecret(ctx context.Context, accessKey string) (auth.Credentials, *iampolicy.Policy, error) {
+	if !sys.Initialized() {
+		return auth.Credentials{}, nil, errServerNotInitialized
+	}
+
+	sys.Lock()
+	defer sys.Unlock()
+
+	sa, ok := sys.iamUsersMap[accessKey]
+	if !ok || !sa.IsServiceAccount() {
+		return auth.Credentials{}, nil, errNoSuchServiceAccount
+	}
+
+	return sa, getEmbeddedPolicy(sa), nil
+}
+
+// getEmbeddedPolicy - returns the session policy embedded in the
+// session token of a service account, nil if it has none.
+func getEmbeddedPolicy(sa auth.Credentials) *iampolicy.Policy {
+	jwtClaims, err := auth.ExtractClaims(sa.SessionToken, globalActiveCred.SecretKey)
+	if err != nil {
+		return nil
+	}
+	pt, ptok := jwtClaims.Lookup(iamPolicyClaimNameSA())
+	sp, spok := jwtClaims.Lookup(iampolicy.SessionPolicyName)
+	if !ptok || !spok || pt != "embedded-policy" {
+		return nil
+	}
+	policyBytes, err := base64.StdEncoding.DecodeString(sp)
+	if err != nil {
+		return nil
+	}
+	p, err := iampolicy.ParseConfig(bytes.NewReader(policyBytes))
+	if err != nil {
+		return nil
+	}
+	policy := iampolicy.Policy{}.Merge(*p)
+	return &policy
+}
+
+// GetServiceAccountParentChain - returns the chain of parents of a
+// service account, starting with its immediate parent and ending with
+// a user which is not a service account.
+func (sys *IAMSys) GetServiceAccountParentChain(ctx context.Context, accessKey string) ([]string, error) {
+	if !sys.Initialized() {
+		return nil, errServerNotInitialized
+	}
+
+	sys.Lock()
+	defer sys.Unlock()
+
+	sa, ok := sys.iamUsersMap[accessKey]
+	if !ok || !sa.IsServiceAccount() {
+		return nil, errNoSuchServiceAccount
+	}
+
+	visited := set.CreateStringSet(accessKey)
+	var chain []string
+	for parent := sa.ParentUser; ; {
+		if visited.Contains(parent) {
+			return nil, fmt.Errorf("cycle detected in the parents of service account %s at %s", accessKey, parent)
+		}
+		visited.Add(parent)
+		chain = append(chain, parent)
+
+		cred, ok := sys.iamUsersMap[parent]
+		if !ok || !cred.IsServiceAccount() {
+			// Parent is a regular user, a temporary user or
+			// an external (LDAP) user.
+			return chain, nil
+		}
+		parent = cred.ParentUser
+	}
+}
+
+// DeleteServiceAccount - delete a service account
+func (sys *IAMSys) DeleteServiceAccount(ctx context.Context, accessKey string) error {
+	if !sys.Initialized() {
+		return errServerNotInitialized
+	}
+
+	if sys.readOnly {
+		return errIAMReadOnly
+	}
+
+	if err := sys.lockStore(); err != nil {
+		return err
+	}
+	defer sys.store.unlock()
+
+	sys.Lock()
+	sa, ok := sys.iamUsersMap[accessKey]
+	sys.Unlock()
+	if !ok || !sa.IsServiceAccount() {
+		return nil
+	}
+
+	// It is ok to ignore deletion error on the mapped policy
+	err := sys.store.deleteUserIdentity(context.Background(), accessKey, srvAccUser)
+	if err != nil {
+		// ignore if user is already deleted.
+		if errors.Is(err, errNoSuchUser) {
+			return nil
+		}
+		return err
+	}
+
+	sys.Lock()
+	delete(sys.iamUsersMap, accessKey)
+	delete(sys.iamUserTags, accessKey)
+	sys.Unlock()
+
+	sys.notifyCredential(ctx, IAMCredentialEvent{Action: IAMCredentialDeleted, Type: IAMCredentialServiceAccount, AccessKey: accessKey, ParentUser: sa.ParentUser})
+	return nil
+}
+
+// CreateUser - create new user credentials and policy, if user already exists
+// they shall be rewritten with new inputs.
+func (sys *IAMSys) CreateUser(accessKey string, uinfo madmin.UserInfo) error {
+	return sys.CreateUserWithOpts(accessKey, uinfo, createUserOpts{})
+}
+
+type createUserOpts struct {
+	// skipDefaultPolicies creates a new user without the default
+	// policies, see envIAMDefaultUserPolicies.
+	skipDefaultPolicies bool
+}
+
+// CreateUserWithOpts - same as CreateUser, a user which does not exist
+// yet is mapped to the default user policies as well unless disabled
+// in opts.
+func (sys *IAMSys) CreateUserWithOpts(accessKey string, uinfo madmin.UserInfo, opts createUserOpts) error {
+	if !sys.Initialized() {
+		return errServerNotInitialized
+	}
+
+	if sys.readOnly {
+		return errIAMReadOnly
+	}
+
+	accessKey = sys.normalizeAccessKey(accessKey)
+
+	if sys.usersSysType != MinIOUsersSysType {
+		return errIAMActionNotAllowed
+	}
+
+	if err := checkIAMName(accessKey); err != nil {
+		return err
+	}
+	if err := checkAccessKeyName(accessKey); err != nil {
+		return err
+	}
+
+	if err := sys.lockStore(); err != nil {
+		return err
+	}
+	defer sys.store.unlock()
+	if err := sys.LoadAllTypeUsers(); err != nil {
+		return err
+	}
+
+	sys.Lock()
+	cr, ok := sys.iamUsersMap[accessKey]
+	var nameErr error
+	if !ok && sys.strictNaming {
+		nameErr = sys.checkUserName(accessKey)
+	}
+	sys.Unlock()
+	if cr.IsTemp() && ok {
+		return errIAMActionNotAllowed
+	}
+	if nameErr != nil {
+		return nameErr
+	}
+	credPolicy := sys.getCredentialPolicy()
+	if !ok {
+		if err := credPolicy.validateAccessKey(accessKey); err != nil {
+			return err
+		}
+	}
+	if err := credPolicy.validateSecretKey(uinfo.SecretKey); err != nil {
+		return err
+	}
+
+	if !ok && !opts.skipDefaultPolicies && len(sys.defaultUserPolicies) > 0 {
+		if err := sys.loadPolicyDocs(); err != nil {
+			return err
+		}
+		pset := newMappedPolicy(uinfo.PolicyName).policySet()
+		for _, policy := range sys.defaultUserPolicies {
+			pset.Add(policy)
+		}
+		sys.Lock()
+		for _, policy := range pset.ToSlice() {
+			if _, found := sys.iamPolicyDocsMap[policy]; !found {
+				sys.Unlock()
+				logger.LogIf(GlobalContext, fmt.Errorf("%w: (%s)", errNoSuchPolicy, policy))
+				return errNoSuchPolicy
+			}
+		}
+		sys.Unlock()
+		uinfo.PolicyName = strings.Join(pset.ToSlice(), ",")
+	}
+
+	u := sys.persistedUserIdentity(auth.Credentials{
+		AccessKey: accessKey,
+		SecretKey: uinfo.SecretKey,
+		Status: func() string {
+			if uinfo.Status == madmin.AccountEnabled {
+				return auth.AccountOn
+			}
+			return auth.AccountOff
+		}(),
+	})
+	if u.Credentials.SecretKey != cr.SecretKey {
+		// A new secret key supersedes any rotation in progress.
+		u.PreviousSecretKey = ""
+		u.RotationExpiry = time.Time{}
+	}
+
+	if err := sys.store.saveUserIdentity(context.Background(), accessKey, regularUser, u); err != nil {
+		return err
+	}
+
+	sys.Lock()
+	sys.iamUsersMap[accessKey] = u.Credentials
+	delete(sys.negativeUserCache, accessKey)
+	sys.setSecretRotation(accessKey, u)
+	sys.Unlock()
+
+	if !ok {
+		sys.notifyCredential(context.Background(), IAMCredentialEvent{Action: IAMCredentialCreated, Type: IAMCredentialUser, AccessKey: accessKey})
+	}
+
+	// Set policy if specified.
+	if uinfo.PolicyName != "" {
+		if err := sys.LoadPolicyMapping(accessKey, regularUser, false); err != nil {
+			return err
+		}
+		if err := sys.loadPolicyDocs(); err != nil {
+			return err
+		}
+		return sys.policyDBSet(accessKey, uinfo.PolicyName, regularUser, false)
+	}
+	return nil
+}
+
+// SetUserSecretKey - sets user secret key
+func (sys *IAMSys) SetUserSecretKey(accessKey string, secretKey string) error {
+	return sys.SetUserSecretKeyWithOpts(accessKey, secretKey, setUserSecretKeyOpts{})
+}
+
+type setUserSecretKeyOpts struct {
+	// appendKey adds secretKey to the additional secret keys of
+	// the user instead of replacing the primary one.
+	appendKey bool
+}
+
+// SetUserSecretKeyWithOpts - same as SetUserSecretKey, optionally
+// adding the secret key to the ones already accepted for the user.
+func (sys *IAMSys) SetUserSecretKeyWithOpts(accessKey string, secretKey string, opts setUserSecretKeyOpts) error {
+	if !sys.Initialized() {
+		return errServerNotInitialized
+	}
+
+	if sys.readOnly {
+		return errIAMReadOnly
+	}
+
+	accessKey = sys.normalizeAccessKey(accessKey)
+
+	if sys.usersSysType != MinIOUsersSysType {
+		return errIAMActionNotAllowed
+	}
+
+	if !auth.IsSecretKeyValid(secretKey) {
+		return auth.ErrInvalidSecretKeyLength
+	}
+	if err := sys.getCredentialPolicy().validateSecretKey(secretKey); err != nil {
+		return err
 	}
 
-	sys.store.lock()
+	if err := sys.lockStore(); err != nil {
+		return err
+	}
 	defer sys.store.unlock()
-
+	if err := sys.LoadUser(accessKey, regularUser); err != nil {
+		return err
+	}
 	sys.Lock()
-	sa, ok := sys.iamUsersMap[accessKey]
+	cred, ok := sys.iamUsersMap[accessKey]
 	sys.Unlock()
-	if !ok || !sa.IsServiceAccount() {
-		return nil
+	if !ok {
+		return errNoSuchUser
 	}
 
-	// It is ok to ignore deletion error on the mapped policy
-	err := sys.store.deleteUserIdentity(context.Background(), accessKey, srvAccUser)
-	if err != nil {
-		// ignore if user is already deleted.
-		if errors.Is(err, errNoSuchUser) {
+	u := sys.persistedUserIdentity(cred)
+	if opts.appendKey {
+		if secretKey == cred.SecretKey || set.CreateStringSet(u.SecretKeys...).Contains(secretKey) {
 			return nil
 		}
+		if len(u.SecretKeys) >= maxAdditionalSecretKeys {
+			return fmt.Errorf("user %s already has %d additional secret keys: %w", accessKey, len(u.SecretKeys), errInvalidArgument)
+		}
+		u.SecretKeys = append(u.SecretKeys, secretKey)
+	} else {
+		u.Credentials.SecretKey = secretKey
+		// Any rotation in progress is superseded.
+		u.PreviousSecretKey = ""
+		u.RotationExpiry = time.Time{}
+	}
+	if err := sys.store.saveUserIdentity(context.Background(), accessKey, regularUser, u); err != nil {
 		return err
 	}
 
 	sys.Lock()
 	defer sys.Unlock()
-	delete(sys.iamUsersMap, accessKey)
+	sys.iamUsersMap[accessKey] = u.Credentials
+	sys.setAdditionalSecretKeys(accessKey, u)
+	sys.setSecretRotation(accessKey, u)
 	return nil
 }
 
-// CreateUser - create new user credentials and policy, if user already exists
-// they shall be rewritten with new inputs.
-func (sys *IAMSys) CreateUser(accessKey string, uinfo madmin.UserInfo) error {
+// PruneSecretKeys - removes all secret keys of a user but the primary
+// one, including the previous secret key of a rotation in progress.
+func (sys *IAMSys) PruneSecretKeys(accessKey string) error {
 	if !sys.Initialized() {
 		return errServerNotInitialized
 	}
 
+	if sys.readOnly {
+		return errIAMReadOnly
+	}
+
+	accessKey = sys.normalizeAccessKey(accessKey)
+
 	if sys.usersSysType != MinIOUsersSysType {
 		return errIAMActionNotAllowed
 	}
 
-	sys.store.lock()
+	if err := sys.lockStore(); err != nil {
+		return err
+	}
 	defer sys.store.unlock()
-	if err := sys.LoadAllTypeUsers(); err != nil {
+	if err := sys.LoadUser(accessKey, regularUser); err != nil {
 		return err
 	}
-
 	sys.Lock()
-	cr, ok := sys.iamUsersMap[accessKey]
+	cred, ok := sys.iamUsersMap[accessKey]
 	sys.Unlock()
-	if cr.IsTemp() && ok {
-		return errIAMActionNotAllowed
+	if !ok {
+		return errNoSuchUser
 	}
 
-	u := newUserIdentity(auth.Credentials{
-		AccessKey: accessKey,
-		SecretKey: uinfo.SecretKey,
-		Status: func() string {
-			if uinfo.Status == madmin.AccountEnabled {
-				return auth.AccountOn
-			}
-			return auth.AccountOff
-		}(),
-	})
-
+	u := sys.persistedUserIdentity(cred)
+	u.SecretKeys = nil
+	u.PreviousSecretKey = ""
+	u.RotationExpiry = time.Time{}
 	if err := sys.store.saveUserIdentity(context.Background(), accessKey, regularUser, u); err != nil {
 		return err
 	}
 
 	sys.Lock()
-	sys.iamUsersMap[accessKey] = u.Credentials
-	sys.Unlock()
-	// Set policy if specified.
-	if uinfo.PolicyName != "" {
-		if err := sys.LoadPolicyMapping(accessKey, regularUser, false); err != nil {
-			return err
-		}
-		if err := sys.loadPolicyDocs(); err != nil {
-			return err
-		}
-		return sys.policyDBSet(accessKey, uinfo.PolicyName, regularUser, false)
-	}
+	defer sys.Unlock()
+	delete(sys.iamAdditionalSecretKeys, accessKey)
+	delete(sys.iamSecretRotations, accessKey)
 	return nil
 }
 
-// SetUserSecretKey - sets user secret key
-func (sys *IAMSys) SetUserSecretKey(accessKey string, secretKey string) error {
+// RotateUserSecretKey - sets a new secret key for a user while keeping
+// the current one valid for the given grace window, so that clients can
+// switch over without downtime. The previous secret key is purged once
+// the window is over.
+func (sys *IAMSys) RotateUserSecretKey(accessKey, newSecret string, graceWindow time.Duration) error {
+	if graceWindow <= 0 {
+		return sys.SetUserSecretKey(accessKey, newSecret)
+	}
+
 	if !sys.Initialized() {
 		return errServerNotInitialized
 	}
 
+	if sys.readOnly {
+		return errIAMReadOnly
+	}
+
+	accessKey = sys.normalizeAccessKey(accessKey)
+
 	if sys.usersSysType != MinIOUsersSysType {
 		return errIAMActionNotAllowed
 	}
 
-	sys.store.lock()
+	if !auth.IsSecretKeyValid(newSecret) {
+		return auth.ErrInvalidSecretKeyLength
+	}
+	if err := sys.getCredentialPolicy().validateSecretKey(newSecret); err != nil {
+		return err
+	}
+
+	if err := sys.lockStore(); err != nil {
+		return err
+	}
 	defer sys.store.unlock()
 	if err := sys.LoadUser(accessKey, regularUser); err != nil {
 		return err
@@ -1440,59 +4011,238 @@ func (sys *IAMSys) SetUserSecretKey(accessKey string, secretKey string) error {
 		return errNoSuchUser
 	}
 
-	cred.SecretKey = secretKey
-	u := newUserIdentity(cred)
+	u := sys.persistedUserIdentity(cred)
+	u.Credentials.SecretKey = newSecret
+	u.PreviousSecretKey = cred.SecretKey
+	u.RotationExpiry = UTCNow().Add(graceWindow)
 	if err := sys.store.saveUserIdentity(context.Background(), accessKey, regularUser, u); err != nil {
 		return err
 	}
 
 	sys.Lock()
 	defer sys.Unlock()
-	sys.iamUsersMap[accessKey] = cred
+	sys.iamUsersMap[accessKey] = u.Credentials
+	sys.setSecretRotation(accessKey, u)
 	return nil
 }
 
-func (sys *IAMSys) loadUserFromStore(accessKey string) {
+// GetPreviousSecretKey - returns the previous secret key of a user
+// while its rotation grace window is open.
+func (sys *IAMSys) GetPreviousSecretKey(accessKey string) (string, bool) {
+	if !sys.Initialized() {
+		return "", false
+	}
+
+	sys.Lock()
+	defer sys.Unlock()
+
+	r, ok := sys.iamSecretRotations[accessKey]
+	if !ok || !UTCNow().Before(r.expiry) {
+		return "", false
+	}
+	return r.previousSecretKey, true
+}
+
+// GetAdditionalSecretKeys - returns the secret keys accepted for a
+// user besides the primary one.
+func (sys *IAMSys) GetAdditionalSecretKeys(accessKey string) []string {
+	if !sys.Initialized() {
+		return nil
+	}
+	return sys.getAdditionalSecretKeys(accessKey)
+}
+
+func (sys *IAMSys) getAdditionalSecretKeys(accessKey string) []string {
+	sys.Lock()
+	defer sys.Unlock()
+	return append([]string(nil), sys.iamAdditionalSecretKeys[accessKey]...)
+}
+
+// setAdditionalSecretKeys - records the additional secret keys of the
+// given identity. IMPORTANT: Assumes that sys.Lock is held by caller.
+func (sys *IAMSys) setAdditionalSecretKeys(accessKey string, u UserIdentity) {
+	if len(u.SecretKeys) == 0 {
+		delete(sys.iamAdditionalSecretKeys, accessKey)
+		return
+	}
+	sys.iamAdditionalSecretKeys[accessKey] = append([]string(nil), u.SecretKeys...)
+}
+
+// purgeSecretRotations - removes the previous secret key of the given
+// users from the store once their grace window is over. Identities are
+// read again under the store lock, a new rotation may have started
+// since they were loaded.
+func (sys *IAMSys) purgeSecretRotations(users []string) {
+	if err := sys.lockStore(); err != nil {
+		logger.LogIf(GlobalContext, fmt.Errorf("unable to purge previous secret keys: %w", err))
+		return
+	}
+	defer sys.store.unlock()
+
+	ctx := context.Background()
+	for _, user := range users {
+		u, err := sys.store.getUserIdentity(ctx, user, regularUser)
+		if err != nil {
+			if !errors.Is(err, errNoSuchUser) {
+				logger.LogIf(GlobalContext, fmt.Errorf("unable to purge the previous secret key of %s: %w", user, err))
+			}
+			continue
+		}
+		if u.PreviousSecretKey == "" || UTCNow().Before(u.RotationExpiry) {
+			continue
+		}
+		u.PreviousSecretKey = ""
+		u.RotationExpiry = time.Time{}
+		if err = sys.store.saveUserIdentity(ctx, user, regularUser, u); err != nil {
+			logger.LogIf(GlobalContext, fmt.Errorf("unable to purge the previous secret key of %s: %w", user, err))
+		}
+	}
+}
+
+// purgeExpiredServiceAccounts - deletes the identities and the policy
+// mappings of the given expired service accounts from the store.
+func (sys *IAMSys) purgeExpiredServiceAccounts(accessKeys []string) {
+	if err := sys.lockStore(); err != nil {
+		logger.LogIf(GlobalContext, fmt.Errorf("unable to purge expired service accounts: %w", err))
+		return
+	}
+	defer sys.store.unlock()
+
+	ctx := context.Background()
+	for _, accessKey := range accessKeys {
+		if err := sys.store.deleteUserIdentity(ctx, accessKey, srvAccUser); err != nil && !errors.Is(err, errNoSuchUser) {
+			logger.LogIf(GlobalContext, fmt.Errorf("unable to purge the expired service account %s: %w", accessKey, err))
+			continue
+		}
+		if err := sys.store.deleteMappedPolicy(ctx, accessKey, srvAccUser, false); err != nil && !errors.Is(err, errNoSuchPolicy) {
+			logger.LogIf(GlobalContext, fmt.Errorf("unable to purge the policy mapping of the expired service account %s: %w", accessKey, err))
+		}
+	}
+}
+
+// persistedUserIdentity - returns the identity of cred as it must be
+// saved, with the secret key rotation in progress, the additional secret
+// keys, the MFA flag and the tags recorded for its access key. Updates
+// of an identity start from it, so that none of these are lost.
+func (sys *IAMSys) persistedUserIdentity(cred auth.Credentials) UserIdentity {
+	sys.Lock()
+	defer sys.Unlock()
+
+	u := newUserIdentity(cred)
+	if r, ok := sys.iamSecretRotations[cred.AccessKey]; ok && UTCNow().Before(r.expiry) {
+		u.PreviousSecretKey = r.previousSecretKey
+		u.RotationExpiry = r.expiry
+	}
+	u.SecretKeys = append([]string(nil), sys.iamAdditionalSecretKeys[cred.AccessKey]...)
+	u.MFARequired = sys.iamMFARequired.Contains(cred.AccessKey)
+	u.Tags = sys.iamUserTags[cred.AccessKey]
+	return u
+}
+
+// setSecretRotation - records the rotation in progress of the given
+// identity, if any. IMPORTANT: Assumes that sys.Lock is held by caller.
+func (sys *IAMSys) setSecretRotation(accessKey string, u UserIdentity) {
+	if u.PreviousSecretKey == "" || !UTCNow().Before(u.RotationExpiry) {
+		delete(sys.iamSecretRotations, accessKey)
+		return
+	}
+	sys.iamSecretRotations[accessKey] = secretRotation{
+		previousSecretKey: u.PreviousSecretKey,
+		expiry:            u.RotationExpiry,
+	}
+}
+
+// loadUserFromStore - loads the user, service account or temporary user
+// with the given access key from the store if it is not in memory. When
+// it could not be found, returns the first retriable error the store
+// failed with, if any.
+// negativeUserCacheTTL - how long credentials which were not found in
+// the store are not looked up again, kept short for new credentials
+// created on other servers not to be missed for long.
+const negativeUserCacheTTL = 5 * time.Second
+
+// maxNegativeUserCacheEntries - bound of the negative cache, which is
+// cleared when full so that random access keys can't grow it.
+const maxNegativeUserCacheEntries = 10000
+
+// isNegativeCached - reports whether accessKey was recently not found
+// in the store.
+// IMPORTANT: Assumes that sys.Lock is held by caller.
+func (sys *IAMSys) isNegativeCached(accessKey string) bool {
+	until, ok := sys.negativeUserCache[accessKey]
+	if !ok {
+		return false
+	}
+	if UTCNow().After(until) {
+		delete(sys.negativeUserCache, accessKey)
+		return false
+	}
+	return true
+}
+
+// setNegativeCached - records that accessKey was not found in the
+// store.
+// IMPORTANT: Assumes that sys.Lock is held by caller.
+func (sys *IAMSys) setNegativeCached(accessKey string) {
+	if sys.negativeUserCache == nil || len(sys.negativeUserCache) >= maxNegativeUserCacheEntries {
+		sys.negativeUserCache = make(map[string]time.Time)
+	}
+	sys.negativeUserCache[accessKey] = UTCNow().Add(negativeUserCacheTTL)
+}
+
+func (sys *IAMSys) loadUserFromStore(ctx context.Context, accessKey string) error {
+	var storeErr error
+	retriable := func(err error) {
+		if storeErr == nil && err != nil && configRetriableErrors(err) {
+			storeErr = err
+		}
+	}
+
 	sys.Lock()
 	defer sys.Unlock()
 	// If user is already found proceed.
 	if _, found := sys.iamUsersMap[accessKey]; !found {
+		sys.stats.incCacheMiss()
+		if sys.isNegativeCached(accessKey) {
+			// Recently not found in the store either.
+			return nil
+		}
 		//sys.store.loadUser(context.Background(), accessKey, regularUser, sys.iamUsersMap)
 		sys.Unlock()
-		sys.LoadUser(accessKey, regularUser)
+		retriable(sys.loadUserCtx(ctx, accessKey, regularUser))
 		sys.Lock()
 		if _, found = sys.iamUsersMap[accessKey]; found {
 			// found user, load its mapped policies
 			//sys.store.loadMappedPolicy(context.Background(), accessKey, regularUser, false, sys.iamUserPolicyMap)
 			sys.Unlock()
-			sys.LoadPolicyMapping(accessKey, regularUser, false)
+			sys.loadPolicyMappingCtx(ctx, accessKey, regularUser, false)
 			sys.Lock()
 		} else {
 			//sys.store.loadUser(context.Background(), accessKey, srvAccUser, sys.iamUsersMap)
 			sys.Unlock()
-			sys.LoadUser(accessKey, srvAccUser)
+			retriable(sys.loadUserCtx(ctx, accessKey, srvAccUser))
 			sys.Lock()
 			if svc, found := sys.iamUsersMap[accessKey]; found {
 				sys.Unlock()
 				// Found service account, load its parent user and its mapped policies.
 				if sys.usersSysType == MinIOUsersSysType {
 					//sys.store.loadUser(context.Background(), svc.ParentUser, regularUser, sys.iamUsersMap)
-					sys.LoadUser(svc.ParentUser, regularUser)
+					sys.loadUserCtx(ctx, svc.ParentUser, regularUser)
 				}
 				//sys.store.loadMappedPolicy(context.Background(), svc.ParentUser, regularUser, false, sys.iamUserPolicyMap)
-				sys.LoadPolicyMapping(svc.ParentUser, regularUser, false)
+				sys.loadPolicyMappingCtx(ctx, svc.ParentUser, regularUser, false)
 				sys.Lock()
 			} else {
 				// None found fall back to STS users.
 				//sys.store.loadUser(context.Background(), accessKey, stsUser, sys.iamUsersMap)
 				sys.Unlock()
-				sys.LoadUser(accessKey, stsUser)
+				retriable(sys.loadUserCtx(ctx, accessKey, stsUser))
 				sys.Lock()
 				if _, found = sys.iamUsersMap[accessKey]; found {
 					// STS user found, load its mapped policy.
 					//sys.store.loadMappedPolicy(context.Background(), accessKey, stsUser, false, sys.iamUserPolicyMap)
 					sys.Unlock()
-					sys.LoadPolicyMapping(accessKey, stsUser, false)
+					sys.loadPolicyMappingCtx(ctx, accessKey, stsUser, false)
 					sys.Lock()
 				}
 			}
@@ -1504,73 +4254,257 @@ func (sys *IAMSys) loadUserFromStore(accessKey string) {
 		if _, found := sys.iamPolicyDocsMap[policy]; !found {
 			//sys.store.loadPolicyDoc(context.Background(), policy, sys.iamPolicyDocsMap)
 			sys.Unlock()
-			sys.LoadPolicy(policy)
+			sys.loadPolicyCtx(ctx, policy)
 			sys.Lock()
 		}
 	}
 
 	sys.buildUserGroupMemberships()
+
+	if _, found := sys.iamUsersMap[accessKey]; found {
+		return nil
+	}
+	if storeErr == nil {
+		// Only definitive misses are cached, transient errors
+		// are retried right away.
+		sys.setNegativeCached(accessKey)
+	}
+	return storeErr
 }
 
 // GetUser - get user credentials
 func (sys *IAMSys) GetUser(accessKey string) (cred auth.Credentials, ok bool) {
+	return sys.GetUserCtx(context.Background(), accessKey)
+}
+
+// GetUserCtx - same as GetUser, loading the user from the store with
+// the given context when it is not in memory, so that the caller can
+// abort a slow load.
+func (sys *IAMSys) GetUserCtx(ctx context.Context, accessKey string) (cred auth.Credentials, ok bool) {
+	cred, ok, _ = sys.getUser(ctx, accessKey)
+	return cred, ok
+}
+
+// GetUserWithErr - same as GetUserCtx, returning errNoSuchUser for
+// unknown or unusable credentials. When enabled by
+// envIAMStoreUnavailableError, errIAMTemporarilyUnavailable is
+// returned instead if the credentials could not be loaded because of
+// a transient store error, so that the request can be retried rather
+// than denied.
+func (sys *IAMSys) GetUserWithErr(ctx context.Context, accessKey string) (auth.Credentials, error) {
+	cred, ok, storeErr := sys.getUser(ctx, accessKey)
+	if ok {
+		return cred, nil
+	}
+	if storeErr != nil && sys.storeUnavailableError {
+		logger.LogIf(ctx, storeErr)
+		return auth.Credentials{}, errIAMTemporarilyUnavailable
+	}
+	return auth.Credentials{}, errNoSuchUser
+}
+
+// getUser - implements GetUserCtx, additionally returning the store
+// error which prevented loading the credentials, if any.
+func (sys *IAMSys) getUser(ctx context.Context, accessKey string) (cred auth.Credentials, ok bool, storeErr error) {
 	if !sys.Initialized() {
-		return cred, false
+		return cred, false, nil
 	}
 
+	accessKey = sys.normalizeAccessKey(accessKey)
+
 	fallback := false
 	select {
 	case <-sys.configLoaded:
 	default:
-		sys.loadUserFromStore(accessKey)
+		storeErr = sys.loadUserFromStore(ctx, accessKey)
 		fallback = true
 	}
 
 	sys.Lock()
 	defer sys.Unlock()
-	cred, ok = sys.iamUsersMap[accessKey]
-	if !ok && !fallback {
-		// accessKey not found, also
-		// IAM store is not in fallback mode
-		// we can try to reload again from
-		// the IAM store and see if credential
-		// exists now. If it doesn't proceed to
-		// fail.
-		sys.Unlock()
-		sys.loadUserFromStore(accessKey)
-		sys.Lock()
-		cred, ok = sys.iamUsersMap[accessKey]
-	}
-	if ok && cred.IsValid() {
-		if cred.ParentUser != "" && sys.usersSysType == MinIOUsersSysType {
-			_, ok = sys.iamUsersMap[cred.ParentUser]
-		}
-		// for LDAP service accounts with ParentUser set
-		// we have no way to validate, either because user
-		// doesn't need an explicit policy as it can come
-		// automatically from a group. We are safe to ignore
-		// this and continue as policies would fail eventually
-		// the policies are missing or not configured.
+	cred, ok = sys.iamUsersMap[accessKey]
+	if ok {
+		sys.stats.incCacheHit()
+	}
+	if !ok && !fallback {
+		// accessKey not found, also
+		// IAM store is not in fallback mode
+		// we can try to reload again from
+		// the IAM store and see if credential
+		// exists now. If it doesn't proceed to
+		// fail.
+		sys.Unlock()
+		storeErr = sys.loadUserFromStore(ctx, accessKey)
+		sys.Lock()
+		cred, ok = sys.iamUsersMap[accessKey]
+	}
+	if ok && cred.IsValid() {
+		if cred.ParentUser != "" && sys.usersSysType == MinIOUsersSysType {
+			_, ok = sys.iamUsersMap[cred.ParentUser]
+		}
+		// for LDAP service accounts with ParentUser set
+		// we have no way to validate, either because user
+		// doesn't need an explicit policy as it can come
+		// automatically from a group. We are safe to ignore
+		// this and continue as policies would fail eventually
+		// the policies are missing or not configured.
+	}
+	return cred, ok && cred.IsValid(), storeErr
+}
+
+// GetUserCached - same as GetUser, using only the credentials in
+// memory. It never reads from the store nor waits for IAM to load, so
+// credentials created very recently on other servers may be missing.
+func (sys *IAMSys) GetUserCached(accessKey string) (cred auth.Credentials, ok bool) {
+	if !sys.Initialized() {
+		return cred, false
+	}
+
+	accessKey = sys.normalizeAccessKey(accessKey)
+
+	sys.Lock()
+	defer sys.Unlock()
+
+	cred, ok = sys.iamUsersMap[accessKey]
+	if !ok || !cred.IsValid() {
+		return cred, false
+	}
+	if cred.ParentUser != "" && sys.usersSysType == MinIOUsersSysType {
+		_, ok = sys.iamUsersMap[cred.ParentUser]
+	}
+	return cred, ok
+}
+
+// ValidateCredentials - verifies that secretKey is the secret key of
+// the user, temporary user or service account with the given access
+// key and that the credentials are usable. The secret key is compared
+// in constant time whether or not the access key exists, the status
+// and expiry are only reported once the secret key matched.
+func (sys *IAMSys) ValidateCredentials(accessKey, secretKey string) (auth.Credentials, error) {
+	if !sys.Initialized() {
+		return auth.Credentials{}, errServerNotInitialized
+	}
+
+	accessKey = sys.normalizeAccessKey(accessKey)
+
+	<-sys.configLoaded
+
+	sys.Lock()
+	cred, ok := sys.iamUsersMap[accessKey]
+	sys.Unlock()
+
+	match := func(s string) bool {
+		return subtle.ConstantTimeCompare([]byte(s), []byte(secretKey)) == 1
+	}
+	if !ok {
+		// Spend the same effort as for an existing user.
+		match(secretKey)
+		sys.GetPreviousSecretKey(accessKey)
+		sys.GetAdditionalSecretKeys(accessKey)
+		return auth.Credentials{}, errNoSuchUser
+	}
+	if _, ok = matchSecretKey(cred, match); !ok {
+		return auth.Credentials{}, errInvalidSecret
+	}
+
+	switch {
+	case cred.IsExpired():
+		return auth.Credentials{}, errExpired
+	case cred.Status == auth.AccountOff:
+		return auth.Credentials{}, errAccountDisabled
+	case !cred.IsValid():
+		return auth.Credentials{}, errInvalidSecret
+	}
+	return cred, nil
+}
+
+// UserExists - returns whether a user, temporary user or service
+// account with the given access key exists, along with its type. Unlike
+// GetUser the store is only consulted while IAM is still loading.
+func (sys *IAMSys) UserExists(accessKey string) (bool, IAMUserType, error) {
+	if !sys.Initialized() {
+		return false, regularUser, errServerNotInitialized
+	}
+
+	accessKey = sys.normalizeAccessKey(accessKey)
+
+	select {
+	case <-sys.configLoaded:
+	default:
+		sys.loadUserFromStore(context.Background(), accessKey)
+	}
+
+	sys.Lock()
+	defer sys.Unlock()
+
+	cred, ok := sys.iamUsersMap[accessKey]
+	if !ok {
+		return false, regularUser, nil
+	}
+	switch {
+	case cred.IsTemp():
+		return true, stsUser, nil
+	case cred.IsServiceAccount():
+		return true, srvAccUser, nil
+	}
+	return true, regularUser, nil
+}
+
+// GroupExists - returns whether the given group exists. Groups are not
+// stored when users come from LDAP, in that case a group exists if it
+// has a policy mapped to it.
+func (sys *IAMSys) GroupExists(group string) (bool, error) {
+	if !sys.Initialized() {
+		return false, errServerNotInitialized
+	}
+
+	sys.Lock()
+	defer sys.Unlock()
+
+	if sys.usersSysType != MinIOUsersSysType {
+		_, ok := sys.iamGroupPolicyMap[group]
+		return ok, nil
 	}
-	return cred, ok && cred.IsValid()
+
+	_, ok := sys.iamGroupsMap[group]
+	return ok, nil
 }
 
 // AddUsersToGroup - adds users to a group, creating the group if
 // needed. No error if user(s) already are in the group.
 func (sys *IAMSys) AddUsersToGroup(group string, members []string) error {
+	return sys.AddMembersToGroup(group, members, false)
+}
+
+// AddMembersToGroup - same as AddUsersToGroup, additionally allows
+// temporary users to be added to the group when allowTemp is set.
+// Members must exist regardless.
+func (sys *IAMSys) AddMembersToGroup(group string, members []string, allowTemp bool) error {
 	if !sys.Initialized() {
 		return errServerNotInitialized
 	}
 
+	if sys.readOnly {
+		return errIAMReadOnly
+	}
+
+	members = sys.normalizeGroupMembers(members)
+
 	if group == "" {
 		return errInvalidArgument
 	}
 
+	if err := checkIAMName(group); err != nil {
+		return err
+	}
+
 	if sys.usersSysType != MinIOUsersSysType {
 		return errIAMActionNotAllowed
 	}
 
-	sys.store.lock()
+	if err := sys.lockStore(); err != nil {
+		return err
+	}
 	defer sys.store.unlock()
 
 	if err := sys.LoadAllTypeUsers(); err != nil {
@@ -1583,12 +4517,23 @@ func (sys *IAMSys) AddUsersToGroup(group string, members []string) error {
 	sys.Lock()
 	// Validate that all members exist.
 	for _, member := range members {
+		if nested, ok := nestedGroupName(member); ok {
+			if _, ok = sys.iamGroupsMap[nested]; !ok {
+				sys.Unlock()
+				return errNoSuchGroup
+			}
+			if nested == group || sys.groupParents(group).Contains(nested) {
+				sys.Unlock()
+				return fmt.Errorf("adding group %s to %s would create a cycle: %w", nested, group, errInvalidArgument)
+			}
+			continue
+		}
 		cr, ok := sys.iamUsersMap[member]
 		if !ok {
 			sys.Unlock()
 			return errNoSuchUser
 		}
-		if cr.IsTemp() {
+		if cr.IsTemp() && !allowTemp {
 			sys.Unlock()
 			return errIAMActionNotAllowed
 		}
@@ -1634,6 +4579,12 @@ func (sys *IAMSys) RemoveUsersFromGroup(group string, members []string) error {
 		return errServerNotInitialized
 	}
 
+	if sys.readOnly {
+		return errIAMReadOnly
+	}
+
+	members = sys.normalizeGroupMembers(members)
+
 	if sys.usersSysType != MinIOUsersSysType {
 		return errIAMActionNotAllowed
 	}
@@ -1643,7 +4594,9 @@ func (sys *IAMSys) RemoveUsersFromGroup(group string, members []string) error {
 	}
 
 	// lock all write config action
-	sys.store.lock()
+	if err := sys.lockStore(); err != nil {
+		return err
+	}
 	defer sys.store.unlock()
 
 	// update user cache
@@ -1658,6 +4611,10 @@ func (sys *IAMSys) RemoveUsersFromGroup(group string, members []string) error {
 	sys.Lock()
 	// Validate that all members exist.
 	for _, member := range members {
+		if _, ok := nestedGroupName(member); ok {
+			// The nested group may be gone already.
+			continue
+		}
 		cr, ok := sys.iamUsersMap[member]
 		if !ok {
 			sys.Unlock()
@@ -1726,12 +4683,175 @@ func (sys *IAMSys) RemoveUsersFromGroup(group string, members []string) error {
 	return nil
 }
 
+// SetGroupMembers - replaces all members of a group with the given
+// ones, creating the group if it doesn't exist. Members must exist
+// like for AddUsersToGroup, an empty list leaves the group empty.
+func (sys *IAMSys) SetGroupMembers(group string, members []string) error {
+	if !sys.Initialized() {
+		return errServerNotInitialized
+	}
+
+	if sys.readOnly {
+		return errIAMReadOnly
+	}
+
+	members = sys.normalizeGroupMembers(members)
+
+	if group == "" {
+		return errInvalidArgument
+	}
+
+	if err := checkIAMName(group); err != nil {
+		return err
+	}
+
+	if sys.usersSysType != MinIOUsersSysType {
+		return errIAMActionNotAllowed
+	}
+
+	if err := sys.lockStore(); err != nil {
+		return err
+	}
+	defer sys.store.unlock()
+
+	if err := sys.LoadAllTypeUsers(); err != nil {
+		return err
+	}
+	if err := sys.LoadGroup(group); err != nil {
+		return err
+	}
+
+	sys.Lock()
+	// Validate that all members exist.
+	for _, member := range members {
+		if nested, ok := nestedGroupName(member); ok {
+			if _, ok = sys.iamGroupsMap[nested]; !ok {
+				sys.Unlock()
+				return errNoSuchGroup
+			}
+			if nested == group || sys.groupParents(group).Contains(nested) {
+				sys.Unlock()
+				return fmt.Errorf("adding group %s to %s would create a cycle: %w", nested, group, errInvalidArgument)
+			}
+			continue
+		}
+		cr, ok := sys.iamUsersMap[member]
+		if !ok {
+			sys.Unlock()
+			return errNoSuchUser
+		}
+		if cr.IsTemp() {
+			sys.Unlock()
+			return errIAMActionNotAllowed
+		}
+	}
+
+	gi, ok := sys.iamGroupsMap[group]
+	departed := set.CreateStringSet(gi.Members...)
+	if !ok {
+		// Set group as enabled by default when it doesn't
+		// exist.
+		gi = newGroupInfo(members)
+	} else {
+		gi.Members = members
+		gi = gi.normalize()
+	}
+	sys.Unlock()
+
+	if err := sys.store.saveGroupInfo(context.Background(), group, gi); err != nil {
+		return err
+	}
+
+	sys.Lock()
+	defer sys.Unlock()
+
+	sys.iamGroupsMap[group] = gi
+	// update user-group membership map
+	departed = departed.Difference(set.CreateStringSet(gi.Members...))
+	for _, member := range departed.ToSlice() {
+		gset := sys.iamUserGroupMemberships[member]
+		if gset == nil {
+			continue
+		}
+		gset.Remove(group)
+		sys.iamUserGroupMemberships[member] = gset
+	}
+	sys.updateGroupMembershipsMap(group, &gi)
+
+	return nil
+}
+
+// DeleteGroup - deletes a group along with its mapped policy. Unless
+// force is set the group must not have any members, like
+// RemoveUsersFromGroup with no members. With force, all members are
+// removed from the group first.
+func (sys *IAMSys) DeleteGroup(group string, force bool) error {
+	if !force {
+		return sys.RemoveUsersFromGroup(group, nil)
+	}
+
+	if !sys.Initialized() {
+		return errServerNotInitialized
+	}
+
+	if sys.readOnly {
+		return errIAMReadOnly
+	}
+
+	if sys.usersSysType != MinIOUsersSysType {
+		return errIAMActionNotAllowed
+	}
+
+	if group == "" {
+		return errInvalidArgument
+	}
+
+	// lock all write config action
+	if err := sys.lockStore(); err != nil {
+		return err
+	}
+	defer sys.store.unlock()
+
+	if err := sys.LoadGroup(group); err != nil {
+		return err
+	}
+
+	sys.Lock()
+	_, ok := sys.iamGroupsMap[group]
+	sys.Unlock()
+	if !ok {
+		return errNoSuchGroup
+	}
+
+	// Remove the group from storage. First delete the
+	// mapped policy. No-mapped-policy case is ignored.
+	if err := sys.store.deleteMappedPolicy(context.Background(), group, regularUser, true); err != nil && !errors.Is(err, errNoSuchPolicy) {
+		return err
+	}
+	if err := sys.store.deleteGroupInfo(context.Background(), group); err != nil && !errors.Is(err, errNoSuchGroup) {
+		return err
+	}
+
+	sys.Lock()
+	// Delete from server memory, including the memberships of
+	// all its members.
+	sys.removeGroupFromMembershipsMap(group)
+	delete(sys.iamGroupsMap, group)
+	delete(sys.iamGroupPolicyMap, group)
+	sys.Unlock()
+	return nil
+}
+
 // SetGroupStatus - enable/disabled a group
 func (sys *IAMSys) SetGroupStatus(group string, enabled bool) error {
 	if !sys.Initialized() {
 		return errServerNotInitialized
 	}
 
+	if sys.readOnly {
+		return errIAMReadOnly
+	}
+
 	if sys.usersSysType != MinIOUsersSysType {
 		return errIAMActionNotAllowed
 	}
@@ -1740,7 +4860,9 @@ func (sys *IAMSys) SetGroupStatus(group string, enabled bool) error {
 		return errInvalidArgument
 	}
 
-	sys.store.lock()
+	if err := sys.lockStore(); err != nil {
+		return err
+	}
 	defer sys.store.unlock()
 
 	if err := sys.LoadGroup(group); err != nil {
@@ -1777,12 +4899,17 @@ func (sys *IAMSys) GetGroupDescription(group string) (gd madmin.GroupDesc, err e
 		return gd, err
 	}
 
-	policy := strings.Join(ps, ",")
+	policy := strings.Join(set.CreateStringSet(ps...).ToSlice(), ",")
 
 	if sys.usersSysType != MinIOUsersSysType {
+		sys.Lock()
+		members := sys.cachedLDAPGroupMembers(group)
+		sys.Unlock()
+
 		return madmin.GroupDesc{
-			Name:   group,
-			Policy: policy,
+			Name:    group,
+			Members: members,
+			Policy:  policy,
 		}, nil
 	}
 
@@ -1794,10 +4921,20 @@ func (sys *IAMSys) GetGroupDescription(group string) (gd madmin.GroupDesc, err e
 		return gd, errNoSuchGroup
 	}
 
+	var users, groups []string
+	for _, member := range gi.Members {
+		if nested, ok := nestedGroupName(member); ok {
+			groups = append(groups, nested)
+			continue
+		}
+		users = append(users, member)
+	}
+
 	return madmin.GroupDesc{
 		Name:    group,
 		Status:  gi.Status,
-		Members: gi.Members,
+		Members: users,
+		Groups:  groups,
 		Policy:  policy,
 	}, nil
 }
@@ -1831,14 +4968,148 @@ func (sys *IAMSys) PolicyDBSet(name, policy string, isGroup bool) error {
 		return errServerNotInitialized
 	}
 
-	sys.store.lock()
-	defer sys.store.unlock()
-
-	if sys.usersSysType == LDAPUsersSysType {
-		return sys.policyDBSet(name, policy, stsUser, isGroup)
+	if sys.readOnly {
+		return errIAMReadOnly
+	}
+
+	if !isGroup {
+		name = sys.normalizeAccessKey(name)
+	}
+
+	if err := sys.lockStore(); err != nil {
+		return err
+	}
+	defer sys.store.unlock()
+
+	if sys.usersSysType == LDAPUsersSysType {
+		return sys.policyDBSet(name, policy, stsUser, isGroup)
+	}
+
+	return sys.policyDBSet(name, policy, regularUser, isGroup)
+}
+
+// PolicyDBSetChecked - like PolicyDBSet, but refuses to remove the
+// policy mapping of a user which has no policies through its groups
+// either, unless force is set.
+func (sys *IAMSys) PolicyDBSetChecked(name, policy string, isGroup, force bool) error {
+	if !sys.Initialized() {
+		return errServerNotInitialized
+	}
+
+	if sys.readOnly {
+		return errIAMReadOnly
+	}
+
+	if !isGroup {
+		name = sys.normalizeAccessKey(name)
+	}
+
+	if err := sys.lockStore(); err != nil {
+		return err
+	}
+	defer sys.store.unlock()
+
+	userType := regularUser
+	if sys.usersSysType == LDAPUsersSysType {
+		userType = stsUser
+	}
+
+	return sys.policyDBSetChecked(name, policy, userType, isGroup, force)
+}
+
+// AttachPolicy - attaches the given policies to a user or group, in
+// addition to the policies already mapped to it. Attaching a policy
+// which is already attached is a no-op.
+func (sys *IAMSys) AttachPolicy(name string, isGroup bool, policies ...string) error {
+	return sys.updateMappedPolicy(name, isGroup, true, policies)
+}
+
+// DetachPolicy - detaches the given policies from a user or group,
+// leaving other mapped policies in place. Detaching a policy which is
+// not attached is a no-op.
+func (sys *IAMSys) DetachPolicy(name string, isGroup bool, policies ...string) error {
+	return sys.updateMappedPolicy(name, isGroup, false, policies)
+}
+
+// updateMappedPolicy - adds or removes policies from the current policy
+// mapping of a user or group and persists the result.
+func (sys *IAMSys) updateMappedPolicy(name string, isGroup, attach bool, policies []string) error {
+	if !sys.Initialized() {
+		return errServerNotInitialized
+	}
+
+	if sys.readOnly {
+		return errIAMReadOnly
+	}
+
+	if !isGroup {
+		name = sys.normalizeAccessKey(name)
+	}
+	policies = newMappedPolicy(strings.Join(policies, ",")).toSlice()
+
+	if err := sys.lockStore(); err != nil {
+		return err
+	}
+	defer sys.store.unlock()
+
+	if attach {
+		sys.Lock()
+		for _, policy := range policies {
+			if _, found := sys.iamPolicyDocsMap[policy]; !found {
+				sys.Unlock()
+				return errNoSuchPolicy
+			}
+		}
+		sys.Unlock()
+	}
+
+	userType := regularUser
+	if sys.usersSysType == LDAPUsersSysType {
+		userType = stsUser
+	}
+
+	// Start from the stored mapping, read under the store lock, as the
+	// in-memory one may miss a change just made by another server.
+	mp, err := sys.store.getMappedPolicy(context.Background(), name, userType, isGroup)
+	if err != nil && !errors.Is(err, errNoSuchPolicy) {
+		return err
+	}
+
+	current := mp.toSlice()
+	currentSet := set.CreateStringSet(current...)
+	var updated []string
+	if attach {
+		updated = current
+		for _, policy := range policies {
+			if !currentSet.Contains(policy) {
+				currentSet.Add(policy)
+				updated = append(updated, policy)
+			}
+		}
+	} else {
+		detached := set.CreateStringSet(policies...)
+		for _, policy := range current {
+			if !detached.Contains(policy) {
+				updated = append(updated, policy)
+			}
+		}
+	}
+
+	if len(updated) == len(current) {
+		// Nothing changed, catch up with the stored mapping.
+		sys.Lock()
+		m := sys.iamUserPolicyMap
+		if isGroup {
+			m = sys.iamGroupPolicyMap
+		}
+		if _, ok := m[name]; ok || mp.Policies != "" {
+			m[name] = mp
+		}
+		sys.Unlock()
+		return nil
 	}
 
-	return sys.policyDBSet(name, policy, regularUser, isGroup)
+	return sys.policyDBSet(name, strings.Join(updated, ","), userType, isGroup)
 }
 
 // iamUsersMap  iamGroupsMap iamPolicyDocsMap
@@ -1849,6 +5120,10 @@ func (sys *IAMSys) policyDBSet(name, policyName string, userType IAMUserType, is
 		return errInvalidArgument
 	}
 
+	if err := checkIAMName(name); err != nil {
+		return err
+	}
+
 	sys.Lock()
 	if sys.usersSysType == MinIOUsersSysType {
 		if !isGroup {
@@ -1909,6 +5184,52 @@ func (sys *IAMSys) policyDBSet(name, policyName string, userType IAMUserType, is
 	return nil
 }
 
+// policyDBSetChecked - same as policyDBSet, except that the removal of
+// the policy mapping of a user is rejected with errLastPolicyMapping if
+// none of its groups has a policy mapped, unless force is set.
+// IMPORTANT: Assumes that sys.store.lock is held by caller.
+func (sys *IAMSys) policyDBSetChecked(name, policyName string, userType IAMUserType, isGroup, force bool) error {
+	if policyName == "" && !isGroup && !force {
+		sys.Lock()
+		hasGroupPolicy := false
+		for _, group := range sys.iamUserGroupMemberships[name].ToSlice() {
+			if len(sys.iamGroupPolicyMap[group].toSlice()) > 0 && !sys.isGroupDisabled(group) {
+				hasGroupPolicy = true
+				break
+			}
+		}
+		_, mapped := sys.iamUserPolicyMap[name]
+		sys.Unlock()
+		if mapped && !hasGroupPolicy {
+			return errLastPolicyMapping
+		}
+	}
+	return sys.policyDBSet(name, policyName, userType, isGroup)
+}
+
+// GetStoredMappedPolicy - reads the policy mapping of a user or group
+// directly from the store, bypassing the in-memory maps, to compare it
+// against what this server has loaded.
+func (sys *IAMSys) GetStoredMappedPolicy(name string, userType IAMUserType, isGroup bool) (MappedPolicy, error) {
+	if !sys.Initialized() {
+		return MappedPolicy{}, errServerNotInitialized
+	}
+
+	if name == "" {
+		return MappedPolicy{}, errInvalidArgument
+	}
+	if !isGroup {
+		name = sys.normalizeAccessKey(name)
+	}
+
+	if err := sys.rlockStore(); err != nil {
+		return MappedPolicy{}, err
+	}
+	defer sys.store.runlock()
+
+	return sys.store.getMappedPolicy(context.Background(), name, userType, isGroup)
+}
+
 // PolicyDBGet - gets policy set on a user or group. If a list of groups is
 // given, policies associated with them are included as well.
 func (sys *IAMSys) PolicyDBGet(name string, isGroup bool, groups ...string) ([]string, error) {
@@ -1923,14 +5244,171 @@ func (sys *IAMSys) PolicyDBGet(name string, isGroup bool, groups ...string) ([]s
 	sys.Lock()
 	defer sys.Unlock()
 
-	policies, err := sys.policyDBGet(name, isGroup)
+	return sys.policyDBGetWithGroups(name, isGroup, groups...)
+}
+
+// PolicyRef - a policy in effect for a user or group, along with where
+// it comes from: "user" for policies mapped to the user itself,
+// "parent" for those of the parent user of temporary credentials and
+// service accounts, and "group:<name>" for those of a group.
+type PolicyRef struct {
+	Name   string `json:"name"`
+	Source string `json:"source"`
+}
+
+// Sources of a PolicyRef.
+const (
+	policySourceUser        = "user"
+	policySourceParent      = "parent"
+	policySourceGroupPrefix = "group:"
+)
+
+// PolicyDBGetWithSource - same as PolicyDBGet, along with the source
+// of each policy.
+func (sys *IAMSys) PolicyDBGetWithSource(name string, isGroup bool, groups ...string) ([]PolicyRef, error) {
+	if !sys.Initialized() {
+		return nil, errServerNotInitialized
+	}
+
+	if name == "" {
+		return nil, errInvalidArgument
+	}
+
+	sys.Lock()
+	defer sys.Unlock()
+
+	return sys.policyDBGetRefsWithGroups(name, isGroup, groups...)
+}
+
+// policyRefNames - returns the names of the given policies.
+func policyRefNames(refs []PolicyRef) []string {
+	if refs == nil {
+		return nil
+	}
+	names := make([]string, 0, len(refs))
+	for _, ref := range refs {
+		names = append(names, ref.Name)
+	}
+	return names
+}
+
+// PolicyDBGetEffective - gets the combined policy in effect for a user
+// or group along with the names of the policies contributing to it.
+// Group policies are resolved the same way as in PolicyDBGet.
+func (sys *IAMSys) PolicyDBGetEffective(name string, isGroup bool, groups ...string) (iampolicy.Policy, []string, error) {
+	if !sys.Initialized() {
+		return iampolicy.Policy{}, nil, errServerNotInitialized
+	}
+
+	if name == "" {
+		return iampolicy.Policy{}, nil, errInvalidArgument
+	}
+
+	sys.Lock()
+	policies, err := sys.policyDBGetWithGroups(name, isGroup, groups...)
+	sys.Unlock()
+	if err != nil {
+		return iampolicy.Policy{}, nil, err
+	}
+
+	if err = sys.loadLazyPolicies(policies...); err != nil {
+		return iampolicy.Policy{}, nil, err
+	}
+
+	sys.Lock()
+	defer sys.Unlock()
+
+	var contributing []string
+	for _, pname := range policies {
+		if _, found := sys.iamPolicyDocsMap[pname]; found {
+			contributing = append(contributing, pname)
+		}
+	}
+
+	return sys.getCombinedPolicy(contributing...), contributing, nil
+}
+
+// GetCombinedPolicyForUser - returns the combined policy in effect for
+// a user, temporary user or service account, including the policies of
+// the given groups, like PolicyDBGet followed by GetCombinedPolicy but
+// under a single lock. Temporary users and service accounts without
+// policies of their own get those of their parent user.
+func (sys *IAMSys) GetCombinedPolicyForUser(accessKey string, groups ...string) (iampolicy.Policy, error) {
+	p, _, err := sys.PolicyDBGetEffective(sys.normalizeAccessKey(accessKey), false, groups...)
+	return p, err
+}
+
+// SummarizeDenies - returns the Deny statements of the combined policy
+// in effect for a user, see GetCombinedPolicyForUser, without
+// duplicates. They are sorted by actions and then by resources so that
+// the statements denying the same actions are next to each other.
+func (sys *IAMSys) SummarizeDenies(accessKey string) ([]iampolicy.Statement, error) {
+	if !sys.Initialized() {
+		return nil, errServerNotInitialized
+	}
+
+	p, err := sys.GetCombinedPolicyForUser(accessKey)
+	if err != nil {
+		return nil, err
+	}
+
+	var denies []iampolicy.Statement
+	for _, statement := range p.Statements {
+		if statement.Effect != policy.Deny {
+			continue
+		}
+		duplicate := false
+		for _, deny := range denies {
+			if deny.Equals(statement) {
+				duplicate = true
+				break
+			}
+		}
+		if !duplicate {
+			clone := statement.Clone()
+			clone.SID = statement.SID
+			denies = append(denies, clone)
+		}
+	}
+	sort.SliceStable(denies, func(i, j int) bool {
+		ai, aj := denies[i].Actions.String(), denies[j].Actions.String()
+		if ai != aj {
+			return ai < aj
+		}
+		return denies[i].Resources.String() < denies[j].Resources.String()
+	})
+
+	return denies, nil
+}
+
+// policyDBGetWithGroups - same as policyDBGet, additionally includes
+// the policies of the given groups for a user. This call assumes that
+// caller has the sys.Lock().
+func (sys *IAMSys) policyDBGetWithGroups(name string, isGroup bool, groups ...string) ([]string, error) {
+	refs, err := sys.policyDBGetRefsWithGroups(name, isGroup, groups...)
+	return policyRefNames(refs), err
+}
+
+// policyDBGetRefsWithGroups - same as policyDBGetWithGroups, along with
+// the source of each policy. This call assumes that caller has the
+// sys.Lock().
+func (sys *IAMSys) policyDBGetRefsWithGroups(name string, isGroup bool, groups ...string) ([]PolicyRef, error) {
+	policies, err := sys.policyDBGetRefs(name, isGroup)
 	if err != nil {
 		return nil, err
 	}
 
 	if !isGroup {
+		if sys.usersSysType == MinIOUsersSysType {
+			groups = sys.expandGroups(groups...)
+		}
+		if sys.strictDisabledGroups && sys.isGroupDisabled(groups...) {
+			// Membership in a disabled group overrides all
+			// other policies, resolve to none to deny.
+			return nil, nil
+		}
 		for _, group := range groups {
-			ps, err := sys.policyDBGet(group, true)
+			ps, err := sys.policyDBGetRefs(group, true)
 			if err != nil {
 				return nil, err
 			}
@@ -1953,6 +5431,22 @@ func (sys *IAMSys) PolicyDBGet(name string, isGroup bool, groups ...string) ([]s
 // generated credentials. Thus we skip looking up group memberships, user map,
 // and group map and check the appropriate policy maps directly.
 func (sys *IAMSys) policyDBGet(name string, isGroup bool) (policies []string, err error) {
+	refs, err := sys.policyDBGetRefs(name, isGroup)
+	return policyRefNames(refs), err
+}
+
+// policyRefs - returns the policies of mp, all with the given source.
+func policyRefs(mp MappedPolicy, source string) []PolicyRef {
+	var refs []PolicyRef
+	for _, policy := range mp.toSlice() {
+		refs = append(refs, PolicyRef{Name: policy, Source: source})
+	}
+	return refs
+}
+
+// policyDBGetRefs - same as policyDBGet, along with the source of each
+// policy. This call assumes that caller has the sys.Lock().
+func (sys *IAMSys) policyDBGetRefs(name string, isGroup bool) (policies []PolicyRef, err error) {
 	if isGroup {
 		if sys.usersSysType == MinIOUsersSysType {
 			g, ok := sys.iamGroupsMap[name]
@@ -1967,7 +5461,7 @@ func (sys *IAMSys) policyDBGet(name string, isGroup bool) (policies []string, er
 			}
 		}
 
-		return sys.iamGroupPolicyMap[name].toSlice(), nil
+		return policyRefs(sys.iamGroupPolicyMap[name], policySourceGroupPrefix+name), nil
 	}
 
 	var u auth.Credentials
@@ -1985,61 +5479,256 @@ func (sys *IAMSys) policyDBGet(name string, isGroup bool) (policies []string, er
 		}
 	}
 
+	source := policySourceUser
 	mp, ok := sys.iamUserPolicyMap[name]
 	if !ok {
 		if u.ParentUser != "" {
 			mp = sys.iamUserPolicyMap[u.ParentUser]
+			source = policySourceParent
 		}
 	}
 
 	// returned policy could be empty
-	policies = append(policies, mp.toSlice()...)
+	policies = append(policies, policyRefs(mp, source)...)
+
+	// In LDAP mode the groups of a user come from the claims of
+	// its credentials, memberships found in the map are only
+	// cached for display and must not grant any policies.
+	if sys.usersSysType != MinIOUsersSysType {
+		return policies, nil
+	}
+
+	memberOf := sys.expandGroups(sys.iamUserGroupMemberships[name].ToSlice()...)
+	if sys.strictDisabledGroups && sys.isGroupDisabled(memberOf...) {
+		// Membership in a disabled group overrides the user's
+		// own policies as well, resolve to none to deny.
+		return nil, nil
+	}
 
-	for _, group := range sys.iamUserGroupMemberships[name].ToSlice() {
+	for _, group := range memberOf {
 		// Skip missing or disabled groups
 		gi, ok := sys.iamGroupsMap[group]
 		if !ok || gi.Status == statusDisabled {
 			continue
 		}
 
-		policies = append(policies, sys.iamGroupPolicyMap[group].toSlice()...)
+		policies = append(policies, policyRefs(sys.iamGroupPolicyMap[group], policySourceGroupPrefix+group)...)
 	}
 
 	return policies, nil
 }
 
+// ListPoliciesForUser - returns the policies mapped directly to a user,
+// falling back to the parent user's for temporary users and service
+// accounts like policyDBGet, and separately the policies inherited
+// from each enabled group the user is a member of.
+func (sys *IAMSys) ListPoliciesForUser(accessKey string) (direct []string, viaGroups map[string][]string, err error) {
+	if !sys.Initialized() {
+		return nil, nil, errServerNotInitialized
+	}
+
+	accessKey = sys.normalizeAccessKey(accessKey)
+
+	sys.Lock()
+	defer sys.Unlock()
+
+	isMinIOUsersSys := sys.usersSysType == MinIOUsersSysType
+
+	u, ok := sys.iamUsersMap[accessKey]
+	if !ok && isMinIOUsersSys {
+		return nil, nil, errNoSuchUser
+	}
+
+	mp, ok := sys.iamUserPolicyMap[accessKey]
+	if !ok && u.ParentUser != "" {
+		mp = sys.iamUserPolicyMap[u.ParentUser]
+	}
+	direct = mp.toSlice()
+
+	groups := set.CreateStringSet(u.Groups...)
+	if isMinIOUsersSys {
+		groups = groups.Union(sys.iamUserGroupMemberships[accessKey])
+		if u.IsServiceAccount() {
+			// Service accounts are evaluated with the
+			// groups of their parent user.
+			groups = groups.Union(sys.iamUserGroupMemberships[u.ParentUser])
+		}
+	}
+
+	viaGroups = make(map[string][]string)
+	for _, group := range groups.ToSlice() {
+		if isMinIOUsersSys {
+			// Skip missing or disabled groups
+			gi, ok := sys.iamGroupsMap[group]
+			if !ok || gi.Status == statusDisabled {
+				continue
+			}
+		}
+		if policies := sys.iamGroupPolicyMap[group].toSlice(); len(policies) > 0 {
+			viaGroups[group] = policies
+		}
+	}
+
+	return direct, viaGroups, nil
+}
+
+// ListUsersByPolicy - returns the sorted users and groups the given
+// policy is mapped to, the inverse of ListPoliciesForUser. Members of
+// the returned groups are not expanded.
+func (sys *IAMSys) ListUsersByPolicy(policyName string) (directUsers []string, groups []string, err error) {
+	if !sys.Initialized() {
+		return nil, nil, errServerNotInitialized
+	}
+
+	if policyName == "" {
+		return nil, nil, errInvalidArgument
+	}
+
+	<-sys.configLoaded
+
+	sys.Lock()
+	defer sys.Unlock()
+
+	for u, mp := range sys.iamUserPolicyMap {
+		if mp.policySet().Contains(policyName) {
+			directUsers = append(directUsers, u)
+		}
+	}
+	for g, mp := range sys.iamGroupPolicyMap {
+		if mp.policySet().Contains(policyName) {
+			groups = append(groups, g)
+		}
+	}
+	sort.Strings(directUsers)
+	sort.Strings(groups)
+
+	return directUsers, groups, nil
+}
+
+// AuditDanglingMappings - returns the names of the policies mapped to
+// users and groups which no longer exist, such as policies deleted
+// before mappings were cleaned up. They don't grant anything. Keys are
+// "user:<name>" or "group:<name>", users and groups without dangling
+// policies are omitted.
+func (sys *IAMSys) AuditDanglingMappings() (map[string][]string, error) {
+	if !sys.Initialized() {
+		return nil, errServerNotInitialized
+	}
+
+	<-sys.configLoaded
+
+	sys.Lock()
+	defer sys.Unlock()
+
+	dangling := make(map[string][]string)
+	audit := func(key string, mp MappedPolicy) {
+		for _, policy := range mp.policySet().ToSlice() {
+			if _, ok := sys.iamPolicyDocsMap[policy]; !ok {
+				dangling[key] = append(dangling[key], policy)
+			}
+		}
+	}
+	for name, mp := range sys.iamUserPolicyMap {
+		audit("user:"+name, mp)
+	}
+	for name, mp := range sys.iamGroupPolicyMap {
+		audit("group:"+name, mp)
+	}
+	return dangling, nil
+}
+
+// ListGroupsByPolicy - returns the sorted groups the given policy is
+// mapped to, such as the groups affected by deleting the policy.
+func (sys *IAMSys) ListGroupsByPolicy(policyName string) ([]string, error) {
+	if !sys.Initialized() {
+		return nil, errServerNotInitialized
+	}
+
+	if policyName == "" {
+		return nil, errInvalidArgument
+	}
+
+	<-sys.configLoaded
+
+	sys.Lock()
+	defer sys.Unlock()
+
+	var groups []string
+	for g, mp := range sys.iamGroupPolicyMap {
+		if mp.policySet().Contains(policyName) {
+			groups = append(groups, g)
+		}
+	}
+	sort.Strings(groups)
+
+	return groups, nil
+}
+
 // IsAllowedServiceAccount - checks if the given service account is allowed to perform
 // actions. The permission of the parent user is checked first
 func (sys *IAMSys) IsAllowedServiceAccount(args iampolicy.Args, parent string) bool {
+	allowed, policies := sys.isAllowedServiceAccount(args, parent)
+	if allowed {
+		sys.updatePolicyLastUsed(policies...)
+	}
+	return allowed
+}
+
+// isAllowedServiceAccount - same as IsAllowedServiceAccount without
+// recording the usage of policies, additionally returns the evaluated
+// policies.
+func (sys *IAMSys) isAllowedServiceAccount(args iampolicy.Args, parent string) (bool, []string) {
 	// Now check if we have a subject claim
 	p, ok := args.Claims[parentClaim]
 	if ok {
 		parentInClaim, ok := p.(string)
 		if !ok {
 			// Reject malformed/malicious requests.
-			return false
+			return false, nil
 		}
 		// The parent claim in the session token should be equal
 		// to the parent detected in the backend
 		if parentInClaim != parent {
-			return false
+			return false, nil
 		}
 	} else {
 		// This is needed so a malicious user cannot
 		// use a leaked session key of another user
 		// to widen its privileges.
-		return false
+		return false, nil
 	}
 
 	// Check policy for this service account.
 	svcPolicies, err := sys.PolicyDBGet(parent, false, args.Groups...)
 	if err != nil {
 		logger.LogIf(GlobalContext, err)
-		return false
+		return false, nil
+	}
+
+	// Restrict to the inherited policies if the service account
+	// was created with a subset of the parent's policies.
+	if inherited, ok := args.Claims[saInheritedPoliciesClaim]; ok {
+		inheritedStr, ok := inherited.(string)
+		if !ok {
+			// Reject malformed/malicious requests.
+			return false, nil
+		}
+		allowedSet := newMappedPolicy(inheritedStr).policySet()
+		var subset []string
+		for _, pname := range svcPolicies {
+			if allowedSet.Contains(pname) {
+				subset = append(subset, pname)
+			}
+		}
+		svcPolicies = subset
 	}
 
 	if len(svcPolicies) == 0 {
-		return false
+		return false, nil
+	}
+
+	if err := sys.loadLazyPolicies(svcPolicies...); err != nil {
+		return false, nil
 	}
 
 	var availablePolicies []iampolicy.Policy
@@ -2047,7 +5736,7 @@ func (sys *IAMSys) IsAllowedServiceAccount(args iampolicy.Args, parent string) b
 	// Policies were found, evaluate all of them.
 	sys.Lock()
 	for _, pname := range svcPolicies {
-		p, found := sys.iamPolicyDocsMap[pname]
+		p, found := sys.policyDoc(pname)
 		if found {
 			availablePolicies = append(availablePolicies, p)
 		}
@@ -2055,7 +5744,7 @@ func (sys *IAMSys) IsAllowedServiceAccount(args iampolicy.Args, parent string) b
 	sys.Unlock()
 
 	if len(availablePolicies) == 0 {
-		return false
+		return false, nil
 	}
 
 	combinedPolicy := availablePolicies[0]
@@ -2064,36 +5753,36 @@ func (sys *IAMSys) IsAllowedServiceAccount(args iampolicy.Args, parent string) b
 			availablePolicies[i].Statements...)
 	}
 
-	parentArgs := args
+	parentArgs := withUsername(args, parent)
 	parentArgs.AccountName = parent
 
 	saPolicyClaim, ok := args.Claims[iamPolicyClaimNameSA()]
 	if !ok {
-		return false
+		return false, nil
 	}
 
 	saPolicyClaimStr, ok := saPolicyClaim.(string)
 	if !ok {
 		// Sub policy if set, should be a string reject
 		// malformed/malicious requests.
-		return false
+		return false, nil
 	}
 
 	if saPolicyClaimStr == "inherited-policy" {
-		return combinedPolicy.IsAllowed(parentArgs)
+		return combinedPolicy.IsAllowed(parentArgs), svcPolicies
 	}
 
 	// Now check if we have a sessionPolicy.
 	spolicy, ok := args.Claims[iampolicy.SessionPolicyName]
 	if !ok {
-		return false
+		return false, nil
 	}
 
 	spolicyStr, ok := spolicy.(string)
 	if !ok {
 		// Sub policy if set, should be a string reject
 		// malformed/malicious requests.
-		return false
+		return false, nil
 	}
 
 	// Check if policy is parseable.
@@ -2101,44 +5790,58 @@ func (sys *IAMSys) IsAllowedServiceAccount(args iampolicy.Args, parent string) b
 	if err != nil {
 		// Log any error in input session policy config.
 		logger.LogIf(GlobalContext, err)
-		return false
+		return false, nil
 	}
 
 	// Policy without Version string value reject it.
 	if subPolicy.Version == "" {
-		return false
+		return false, nil
 	}
 
-	return combinedPolicy.IsAllowed(parentArgs) && subPolicy.IsAllowed(parentArgs)
+	return combinedPolicy.IsAllowed(parentArgs) && subPolicy.IsAllowed(parentArgs), svcPolicies
 }
 
 // IsAllowedLDAPSTS - checks for LDAP specific claims and values
 func (sys *IAMSys) IsAllowedLDAPSTS(args iampolicy.Args, parentUser string) bool {
+	allowed, policies := sys.isAllowedLDAPSTS(args, parentUser)
+	if allowed {
+		sys.updatePolicyLastUsed(policies...)
+	}
+	return allowed
+}
+
+// isAllowedLDAPSTS - same as IsAllowedLDAPSTS without recording the
+// usage of policies, additionally returns the evaluated policies.
+func (sys *IAMSys) isAllowedLDAPSTS(args iampolicy.Args, parentUser string) (bool, []string) {
 	parentInClaimIface, ok := args.Claims[ldapUser]
 	if ok {
 		parentInClaim, ok := parentInClaimIface.(string)
 		if !ok {
 			// ldap parentInClaim name is not a string reject it.
-			return false
+			return false, nil
 		}
 
 		if parentInClaim != parentUser {
 			// ldap claim has been modified maliciously reject it.
-			return false
+			return false, nil
 		}
 	} else {
 		// no ldap parentInClaim claim present reject it.
-		return false
+		return false, nil
 	}
 
 	// Check policy for this LDAP user.
 	ldapPolicies, err := sys.PolicyDBGet(parentUser, false, args.Groups...)
 	if err != nil {
-		return false
+		return false, nil
 	}
 
 	if len(ldapPolicies) == 0 {
-		return false
+		return false, nil
+	}
+
+	if err := sys.loadLazyPolicies(ldapPolicies...); err != nil {
+		return false, nil
 	}
 
 	var availablePolicies []iampolicy.Policy
@@ -2146,7 +5849,7 @@ func (sys *IAMSys) IsAllowedLDAPSTS(args iampolicy.Args, parentUser string) bool
 	// Policies were found, evaluate all of them.
 	sys.Lock()
 	for _, pname := range ldapPolicies {
-		p, found := sys.iamPolicyDocsMap[pname]
+		p, found := sys.policyDoc(pname)
 		if found {
 			availablePolicies = append(availablePolicies, p)
 		}
@@ -2154,7 +5857,7 @@ func (sys *IAMSys) IsAllowedLDAPSTS(args iampolicy.Args, parentUser string) bool
 	sys.Unlock()
 
 	if len(availablePolicies) == 0 {
-		return false
+		return false, nil
 	}
 
 	combinedPolicy := availablePolicies[0]
@@ -2164,57 +5867,104 @@ func (sys *IAMSys) IsAllowedLDAPSTS(args iampolicy.Args, parentUser string) bool
 				availablePolicies[i].Statements...)
 	}
 
-	return combinedPolicy.IsAllowed(args)
+	return combinedPolicy.IsAllowed(withUsername(args, parentUser)), ldapPolicies
+}
+
+// IAMClaimValidator - validates the JWT claims of an OpenID STS request,
+// returning false denies the request.
+type IAMClaimValidator func(claims map[string]interface{}) bool
+
+// SetClaimValidator - registers fn to be called by IsAllowedSTS with the
+// claims of OpenID STS requests whose policy claim matches, replacing
+// any previous validator. A nil fn disables claim validation. fn is
+// called on the request path with IAM locks held, so it must be fast
+// and must not call back into IAMSys.
+func (sys *IAMSys) SetClaimValidator(fn IAMClaimValidator) {
+	sys.claimValidator.Store(fn)
 }
 
 // IsAllowedSTS is meant for STS based temporary credentials,
 // which implements claims validation and verification other than
 // applying policies.
 func (sys *IAMSys) IsAllowedSTS(args iampolicy.Args, parentUser string) bool {
+	allowed, policies := sys.isAllowedSTS(args, parentUser)
+	if allowed {
+		sys.updatePolicyLastUsed(policies...)
+	}
+	return allowed
+}
+
+// isAllowedSTS - same as IsAllowedSTS without recording the usage of
+// policies, additionally returns the evaluated policies.
+func (sys *IAMSys) isAllowedSTS(args iampolicy.Args, parentUser string) (bool, []string) {
 	// If it is an LDAP request, check that user and group
 	// policies allow the request.
 	if sys.usersSysType == LDAPUsersSysType {
-		return sys.IsAllowedLDAPSTS(args, parentUser)
+		return sys.isAllowedLDAPSTS(args, parentUser)
 	}
 
 	policies, ok := args.GetPolicies(iamPolicyClaimNameOpenID())
 	if !ok {
 		// When claims are set, it should have a policy claim field.
-		return false
+		return false, nil
 	}
 
 	// When claims are set, it should have policies as claim.
 	if policies.IsEmpty() {
 		// No policy, no access!
-		return false
+		return false, nil
 	}
 
-	sys.Lock()
-	defer sys.Unlock()
-
-	// If policy is available for given user, check the policy.
-	mp, ok := sys.iamUserPolicyMap[args.AccountName]
-	if !ok {
-		// No policy set for the user that we can find, no access!
-		return false
+	if err := sys.loadLazyPolicies(policies.ToSlice()...); err != nil {
+		return false, nil
 	}
 
-	if !policies.Equals(mp.policySet()) {
-		// When claims has a policy, it should match the
-		// policy of args.AccountName which server remembers.
-		// if not reject such requests.
-		return false
-	}
+	// Policy variables resolve to the parent user.
+	args = withUsername(args, parentUser)
+
+	// Policies are evaluated without holding sys.Lock.
+	availablePolicies := func() []iampolicy.Policy {
+		sys.Lock()
+		defer sys.Unlock()
+
+		if sys.strictDisabledGroups && sys.isGroupDisabled(sys.iamUserGroupMemberships[parentUser].ToSlice()...) {
+			// Parent user is a member of a disabled group.
+			return nil
+		}
+
+		// If policy is available for given user, check the policy.
+		mp, ok := sys.iamUserPolicyMap[args.AccountName]
+		if !ok {
+			// No policy set for the user that we can find, no access!
+			return nil
+		}
+
+		if !policies.Equals(mp.policySet()) {
+			// When claims has a policy, it should match the
+			// policy of args.AccountName which server remembers.
+			// if not reject such requests.
+			return nil
+		}
 
-	var availablePolicies []iampolicy.Policy
-	for pname := range policies {
-		p, found := sys.iamPolicyDocsMap[pname]
-		if !found {
-			// all policies presented in the claim should exist
-			logger.LogIf(GlobalContext, fmt.Errorf("expected policy (%s) missing from the JWT claim %s, rejecting the request", pname, iamPolicyClaimNameOpenID()))
-			return false
+		if validate, _ := sys.claimValidator.Load().(IAMClaimValidator); validate != nil && !validate(args.Claims) {
+			// Claims rejected by the operator's validator.
+			return nil
 		}
-		availablePolicies = append(availablePolicies, p)
+
+		var availablePolicies []iampolicy.Policy
+		for pname := range policies {
+			p, found := sys.policyDoc(pname)
+			if !found {
+				// all policies presented in the claim should exist
+				logger.LogIf(GlobalContext, fmt.Errorf("expected policy (%s) missing from the JWT claim %s, rejecting the request", pname, iamPolicyClaimNameOpenID()))
+				return nil
+			}
+			availablePolicies = append(availablePolicies, p)
+		}
+		return availablePolicies
+	}()
+	if len(availablePolicies) == 0 {
+		return false, nil
 	}
 
 	combinedPolicy := availablePolicies[0]
@@ -2230,7 +5980,7 @@ func (sys *IAMSys) IsAllowedSTS(args iampolicy.Args, parentUser string) bool {
 		if !ok {
 			// Sub policy if set, should be a string reject
 			// malformed/malicious requests.
-			return false
+			return false, nil
 		}
 
 		// Check if policy is parseable.
@@ -2238,97 +5988,449 @@ func (sys *IAMSys) IsAllowedSTS(args iampolicy.Args, parentUser string) bool {
 		if err != nil {
 			// Log any error in input session policy config.
 			logger.LogIf(GlobalContext, err)
-			return false
+			return false, nil
 		}
 
 		// Policy without Version string value reject it.
 		if subPolicy.Version == "" {
-			return false
+			return false, nil
 		}
 
 		// Sub policy is set and valid.
-		return combinedPolicy.IsAllowed(args) && subPolicy.IsAllowed(args)
+		return combinedPolicy.IsAllowed(args) && subPolicy.IsAllowed(args), policies.ToSlice()
 	}
 
 	// Sub policy not set, this is most common since subPolicy
 	// is optional, use the inherited policies.
-	return combinedPolicy.IsAllowed(args)
+	return combinedPolicy.IsAllowed(args), policies.ToSlice()
 }
 
 // GetCombinedPolicy returns a combined policy combining all policies
 func (sys *IAMSys) GetCombinedPolicy(policies ...string) iampolicy.Policy {
+	// Policies which cannot be read are left out, see getCombinedPolicy.
+	sys.loadLazyPolicies(policies...)
+
 	// Policies were found, evaluate all of them.
 	sys.Lock()
 	defer sys.Unlock()
 
+	return sys.getCombinedPolicy(policies...)
+}
+
+// SimulatePolicy - evaluates the given policy, which need not be saved,
+// against each of samples as for a regular user. The named stored
+// policies in combineWith are merged into it first, like the policies
+// of the groups of a user would be. Neither the store nor the
+// in-memory state are modified.
+func (sys *IAMSys) SimulatePolicy(p iampolicy.Policy, samples []iampolicy.Args, combineWith ...string) ([]bool, error) {
+	if !sys.Initialized() {
+		return nil, errServerNotInitialized
+	}
+
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+
+	if len(combineWith) > 0 {
+		<-sys.configLoaded
+
+		if err := sys.loadLazyPolicies(combineWith...); err != nil {
+			return nil, err
+		}
+
+		sys.Lock()
+		for _, name := range combineWith {
+			if _, ok := sys.iamPolicyDocsMap[name]; !ok {
+				sys.Unlock()
+				return nil, fmt.Errorf("%w: (%s)", errNoSuchPolicy, name)
+			}
+		}
+		stored, _ := sys.combinePolicies(combineWith...)
+		sys.Unlock()
+
+		// Never append to the statements of the cached policy.
+		statements := make([]iampolicy.Statement, 0, len(p.Statements)+len(stored.Statements))
+		statements = append(statements, p.Statements...)
+		p.Statements = append(statements, stored.Statements...)
+	}
+
+	results := make([]bool, len(samples))
+	for i, args := range samples {
+		results[i] = p.IsAllowed(withUsername(args, args.AccountName))
+	}
+	return results, nil
+}
+
+// getCombinedPolicy - same as GetCombinedPolicy, assumes that caller
+// has the sys.Lock().
+func (sys *IAMSys) getCombinedPolicy(policies ...string) iampolicy.Policy {
+	names := make([]string, len(policies))
+	copy(names, policies)
+	sort.Strings(names)
+	key := strings.Join(names, ",")
+	if combinedPolicy, ok := sys.combinedPolicyCache[key]; ok {
+		return combinedPolicy
+	}
+
+	combinedPolicy, complete := sys.combinePolicies(policies...)
+	if !complete || combinedPolicy.IsEmpty() {
+		// Don't cache the policy without the documents which could
+		// not be loaded.
+		return combinedPolicy
+	}
+	if len(sys.combinedPolicyCache) >= maxCombinedPolicyCacheEntries {
+		sys.invalidateCombinedPolicies()
+	}
+	sys.combinedPolicyCache[key] = combinedPolicy
+	return combinedPolicy
+}
+
+// combinePolicies - same as getCombinedPolicy without using the cache,
+// additionally returns whether the documents of all lazily loaded
+// policies were available. IMPORTANT: Assumes that sys.Lock is held by
+// caller.
+func (sys *IAMSys) combinePolicies(policies ...string) (iampolicy.Policy, bool) {
 	var availablePolicies []iampolicy.Policy
+	complete := true
 	for _, pname := range policies {
-		p, found := sys.iamPolicyDocsMap[pname]
+		p, found := sys.policyDoc(pname)
 		if found {
 			availablePolicies = append(availablePolicies, p)
+		} else if sys.iamLazyPolicies.Contains(pname) {
+			complete = false
 		}
 	}
 
 	if len(availablePolicies) == 0 {
-		return iampolicy.Policy{}
+		return iampolicy.Policy{}, complete
 	}
 
-	combinedPolicy := availablePolicies[0]
-	for i := 1; i < len(availablePolicies); i++ {
-		combinedPolicy.Statements = append(combinedPolicy.Statements,
-			availablePolicies[i].Statements...)
+	var statements []iampolicy.Statement
+	for _, p := range availablePolicies {
+		statements = append(statements, p.Statements...)
 	}
+	combinedPolicy := availablePolicies[0]
+	// Limit the capacity so that callers appending statements never
+	// write to the backing array of the cached policy.
+	combinedPolicy.Statements = statements[:len(statements):len(statements)]
+	return combinedPolicy, complete
+}
 
-	return combinedPolicy
+// invalidateCombinedPolicies - clears the combined policy cache, must be
+// called whenever iamPolicyDocsMap changes. IMPORTANT: Assumes that
+// sys.Lock is held by caller.
+func (sys *IAMSys) invalidateCombinedPolicies() {
+	sys.combinedPolicyCache = make(map[string]iampolicy.Policy)
 }
 
 // IsAllowed - checks given policy args is allowed to continue the Rest API.
 func (sys *IAMSys) IsAllowed(args iampolicy.Args) bool {
+	if sys == nil {
+		// Policies don't apply to the owner, nobody else is allowed
+		// before the IAM sub-system is set up.
+		return args.IsOwner
+	}
+
+	allowed, policies := sys.isAllowedWithBucketPolicy(args)
+	if allowed {
+		sys.updatePolicyLastUsed(policies...)
+	}
+	sys.logDecision(args, allowed)
+	return allowed
+}
+
+// IsAllowedBatch - checks each of variants like IsAllowed, for the
+// account of base. The account fields of base (AccountName, Groups,
+// Claims, IsOwner and DenyOnly) override those of every variant, and
+// so do the condition values of base for variants without any. The
+// account is resolved only once, as are the policies of a regular
+// user. The result is in the order of variants. Probing permissions
+// does not count as using the policies, see PolicyLastUsed.
+func (sys *IAMSys) IsAllowedBatch(base iampolicy.Args, variants []iampolicy.Args) []bool {
+	results := make([]bool, len(variants))
+	if len(variants) == 0 {
+		return results
+	}
+
+	// Don't modify the variants of the caller.
+	variants = append([]iampolicy.Args(nil), variants...)
+	for i := range variants {
+		variants[i].AccountName = base.AccountName
+		variants[i].Groups = base.Groups
+		variants[i].Claims = base.Claims
+		variants[i].IsOwner = base.IsOwner
+		variants[i].DenyOnly = base.DenyOnly
+		if variants[i].ConditionValues == nil {
+			variants[i].ConditionValues = base.ConditionValues
+		}
+	}
+
+	defer func() {
+		for i, args := range variants {
+			sys.logDecision(args, results[i])
+		}
+	}()
+
+	// Bucket policies are evaluated per variant.
+	if sys.getBucketPolicyProvider() != nil && globalPolicyOPA == nil && !base.IsOwner {
+		for i, args := range variants {
+			results[i], _ = sys.isAllowedWithBucketPolicy(args)
+		}
+		return results
+	}
+
+	// OPA and owner requests don't need any policy resolution.
+	if globalPolicyOPA != nil || base.IsOwner {
+		for i, args := range variants {
+			results[i], _ = sys.isAllowed(args)
+		}
+		return results
+	}
+
+	if !sys.mfaSatisfied(base) {
+		return results
+	}
+
+	evaluate := func(eval func(args iampolicy.Args) bool) []bool {
+		for i, args := range variants {
+			sys.stats.incPolicyEvaluation()
+			results[i] = eval(args)
+		}
+		return results
+	}
+
+	ok, parentUser, err := sys.IsTempUser(base.AccountName)
+	if err != nil {
+		return results
+	}
+	if ok {
+		return evaluate(func(args iampolicy.Args) bool {
+			allowed, _ := sys.isAllowedSTS(args, parentUser)
+			return allowed
+		})
+	}
+
+	ok, parentUser, err = sys.IsServiceAccount(base.AccountName)
+	if err != nil {
+		return results
+	}
+	if ok {
+		return evaluate(func(args iampolicy.Args) bool {
+			allowed, _ := sys.isAllowedServiceAccount(args, parentUser)
+			return allowed
+		})
+	}
+
+	policies, err := sys.PolicyDBGet(base.AccountName, false, base.Groups...)
+	if err != nil || len(policies) == 0 {
+		return results
+	}
+
+	combinedPolicy := sys.GetCombinedPolicy(policies...)
+	return evaluate(func(args iampolicy.Args) bool {
+		return combinedPolicy.IsAllowed(withUsername(args, args.AccountName))
+	})
+}
+
+// isAllowed - same as IsAllowed without the bucket policy, the decision
+// log and recording the usage of policies, additionally returns the
+// evaluated policies.
+func (sys *IAMSys) isAllowed(args iampolicy.Args) (bool, []string) {
+	sys.stats.incPolicyEvaluation()
+
 	// If opa is configured, use OPA always.
 	if globalPolicyOPA != nil {
 		ok, err := globalPolicyOPA.IsAllowed(args)
 		if err != nil {
 			logger.LogIf(GlobalContext, err)
 		}
-		return ok
+		return ok, nil
 	}
 
 	// Policies don't apply to the owner.
 	if args.IsOwner {
-		return true
+		return true, nil
+	}
+
+	if !sys.mfaSatisfied(args) {
+		return false, nil
 	}
 
 	// If the credential is temporary, perform STS related checks.
 	ok, parentUser, err := sys.IsTempUser(args.AccountName)
 	if err != nil {
-		return false
+		return false, nil
 	}
 	if ok {
-		return sys.IsAllowedSTS(args, parentUser)
+		return sys.isAllowedSTS(args, parentUser)
 	}
 
 	// If the credential is for a service account, perform related check
 	ok, parentUser, err = sys.IsServiceAccount(args.AccountName)
 	if err != nil {
-		return false
+		return false, nil
 	}
 	if ok {
-		return sys.IsAllowedServiceAccount(args, parentUser)
+		return sys.isAllowedServiceAccount(args, parentUser)
 	}
 
 	// Continue with the assumption of a regular user
 	policies, err := sys.PolicyDBGet(args.AccountName, false, args.Groups...)
 	if err != nil {
-		return false
+		return false, nil
 	}
 
 	if len(policies) == 0 {
 		// No policy found.
-		return false
+		return false, nil
 	}
 
 	// Policies were found, evaluate all of them.
-	return sys.GetCombinedPolicy(policies...).IsAllowed(args)
+	return sys.GetCombinedPolicy(policies...).IsAllowed(withUsername(args, args.AccountName)), policies
+}
+
+// withUsername - returns args with the username used to resolve policy
+// variables such as ${aws:username} set to the given user. Temporary
+// users and service accounts resolve to their parent user. The
+// condition values of args are copied, never modified.
+func withUsername(args iampolicy.Args, username string) iampolicy.Args {
+	if v := args.ConditionValues["username"]; len(v) == 1 && v[0] == username {
+		return args
+	}
+
+	conditionValues := make(map[string][]string, len(args.ConditionValues)+1)
+	for k, v := range args.ConditionValues {
+		conditionValues[k] = v
+	}
+	conditionValues["username"] = []string{username}
+	args.ConditionValues = conditionValues
+	return args
+}
+
+// ExplainAccess - runs the same checks as IsAllowed and additionally
+// reports the policy and the statement which decided the outcome. This
+// is meant for debugging access denials and does not modify any state.
+func (sys *IAMSys) ExplainAccess(args iampolicy.Args) (allowed bool, matchedPolicy string, reason string, err error) {
+	if !sys.Initialized() {
+		return false, "", "", errServerNotInitialized
+	}
+
+	if globalPolicyOPA != nil {
+		allowed, err = globalPolicyOPA.IsAllowed(args)
+		return allowed, "", "decided by the configured OPA policy engine", err
+	}
+
+	if args.IsOwner {
+		return true, "", "policies do not apply to the owner", nil
+	}
+
+	var policies []string
+	var isRegular bool
+	evalArgs := args
+
+	ok, parentUser, err := sys.IsTempUser(args.AccountName)
+	if err != nil {
+		return false, "", "", err
+	}
+	if ok {
+		allowed, _ = sys.isAllowedSTS(args, parentUser)
+		evalArgs = withUsername(args, parentUser)
+		if sys.usersSysType == LDAPUsersSysType {
+			policies, err = sys.PolicyDBGet(parentUser, false, args.Groups...)
+		} else if ps, found := args.GetPolicies(iamPolicyClaimNameOpenID()); found {
+			policies = ps.ToSlice()
+		}
+	} else {
+		ok, parentUser, err = sys.IsServiceAccount(args.AccountName)
+		if err != nil {
+			return false, "", "", err
+		}
+		if ok {
+			allowed, _ = sys.isAllowedServiceAccount(args, parentUser)
+			evalArgs.AccountName = parentUser
+			evalArgs = withUsername(evalArgs, parentUser)
+			policies, err = sys.PolicyDBGet(parentUser, false, args.Groups...)
+		} else {
+			isRegular = true
+			evalArgs = withUsername(args, args.AccountName)
+			policies, err = sys.PolicyDBGet(args.AccountName, false, args.Groups...)
+		}
+	}
+	if err != nil {
+		return false, "", "", err
+	}
+
+	if len(policies) == 0 {
+		account := args.AccountName
+		if parentUser != "" {
+			account = parentUser
+		}
+		if sys.inDisabledGroup(account, args.Groups...) {
+			return false, "", "the account is a member of a disabled group", nil
+		}
+		return allowed, "", "no policy is attached to the account", nil
+	}
+
+	if err = sys.loadLazyPolicies(policies...); err != nil {
+		return false, "", "", err
+	}
+
+	sys.Lock()
+	if isRegular {
+		combinedPolicy, _ := sys.combinePolicies(policies...)
+		allowed = combinedPolicy.IsAllowed(evalArgs)
+	}
+	pname, idx, effect, found := sys.explainPolicies(policies, evalArgs)
+	sys.Unlock()
+
+	switch {
+	case !found:
+		return allowed, "", fmt.Sprintf("no statement in policies %s matches the request", strings.Join(policies, ",")), nil
+	case effect == policy.Deny:
+		return allowed, pname, fmt.Sprintf("denied by statement %d of policy %s", idx, pname), nil
+	case allowed:
+		return allowed, pname, fmt.Sprintf("allowed by statement %d of policy %s", idx, pname), nil
+	default:
+		// The canned policies allow the request, the denial comes
+		// from claims validation or from the session policy.
+		return allowed, pname, fmt.Sprintf("allowed by statement %d of policy %s but rejected by the session policy or the credential claims", idx, pname), nil
+	}
+}
+
+// explainPolicies - returns the policy and the index of the statement
+// which decides the outcome for the given args, a matching Deny takes
+// precedence over any Allow, as in iampolicy.Policy.IsAllowed.
+// IMPORTANT: Assumes that sys.Lock is held by caller.
+func (sys *IAMSys) explainPolicies(policies []string, args iampolicy.Args) (pname string, idx int, effect policy.Effect, found bool) {
+	for _, name := range policies {
+		p, ok := sys.policyDoc(name)
+		if !ok {
+			continue
+		}
+		for i, statement := range p.Statements {
+			if statement.Effect == policy.Deny && !statement.IsAllowed(args) {
+				return name, i, policy.Deny, true
+			}
+		}
+	}
+
+	if args.DenyOnly {
+		return "", 0, "", false
+	}
+
+	for _, name := range policies {
+		p, ok := sys.policyDoc(name)
+		if !ok {
+			continue
+		}
+		for i, statement := range p.Statements {
+			if statement.Effect == policy.Allow && statement.IsAllowed(args) {
+				return name, i, policy.Allow, true
+			}
+		}
+	}
+
+	return "", 0, "", false
 }
 
 // Set default canned policies only if not already overridden by users.
@@ -2351,6 +6453,118 @@ func setDefaultCannedPolicies(policies map[string]iampolicy.Policy) {
 	}
 }
 
+// isGroupDisabled - returns whether any of the given groups exists and
+// is disabled. IMPORTANT: Assumes that sys.Lock is held by caller.
+func (sys *IAMSys) isGroupDisabled(groups ...string) bool {
+	for _, group := range groups {
+		if gi, ok := sys.iamGroupsMap[group]; ok && gi.Status == statusDisabled {
+			return true
+		}
+	}
+	return false
+}
+
+// inDisabledGroup - returns whether a user, or the given groups of it,
+// are members of a disabled group while disabled groups are strictly
+// enforced.
+func (sys *IAMSys) inDisabledGroup(name string, groups ...string) bool {
+	if !sys.strictDisabledGroups {
+		return false
+	}
+
+	sys.Lock()
+	defer sys.Unlock()
+
+	groups = append(groups, sys.iamUserGroupMemberships[name].ToSlice()...)
+	return sys.isGroupDisabled(sys.expandGroups(groups...)...)
+}
+
+// expandGroups - returns the given groups along with the groups they
+// are nested in, transitively, without duplicates. Nesting is not
+// followed through missing or disabled groups, nor deeper than
+// maxGroupNestingDepth. IMPORTANT: Assumes that sys.Lock is held by
+// caller.
+func (sys *IAMSys) expandGroups(groups ...string) []string {
+	seen := set.CreateStringSet(groups...)
+	expanded := append([]string(nil), groups...)
+	level := groups
+	for depth := 0; depth < maxGroupNestingDepth && len(level) > 0; depth++ {
+		var next []string
+		for _, group := range level {
+			if gi, ok := sys.iamGroupsMap[group]; !ok || gi.Status == statusDisabled {
+				continue
+			}
+			for _, parent := range sys.iamUserGroupMemberships[nestedGroupPrefix+group].ToSlice() {
+				if seen.Contains(parent) {
+					// Already resolved, also breaks cycles.
+					continue
+				}
+				seen.Add(parent)
+				expanded = append(expanded, parent)
+				next = append(next, parent)
+			}
+		}
+		level = next
+	}
+	return expanded
+}
+
+// groupParents - returns the groups the given group is nested in,
+// transitively. Unlike expandGroups, disabled groups and the nesting
+// depth are not considered, so that enabling a group or nesting it
+// deeper can never complete a cycle. IMPORTANT: Assumes that sys.Lock
+// is held by caller.
+func (sys *IAMSys) groupParents(group string) set.StringSet {
+	parents := set.NewStringSet()
+	level := []string{group}
+	for len(level) > 0 {
+		var next []string
+		for _, g := range level {
+			for _, parent := range sys.iamUserGroupMemberships[nestedGroupPrefix+g].ToSlice() {
+				if !parents.Contains(parent) {
+					parents.Add(parent)
+					next = append(next, parent)
+				}
+			}
+		}
+		level = next
+	}
+	return parents
+}
+
+// normalizeGroupMembers - same as normalizeAccessKeys, leaving the
+// names of nested groups untouched.
+func (sys *IAMSys) normalizeGroupMembers(members []string) []string {
+	if !sys.caseInsensitiveAccessKeys {
+		return members
+	}
+	normalized := make([]string, 0, len(members))
+	for _, member := range members {
+		if _, ok := nestedGroupName(member); ok {
+			normalized = append(normalized, member)
+			continue
+		}
+		normalized = append(normalized, sys.normalizeAccessKey(member))
+	}
+	return normalized
+}
+
+// checkUserName - returns an error if a new user with the given access
+// key could be confused with a policy, a group or a reserved name.
+// IMPORTANT: Assumes that sys.Lock is held by caller.
+func (sys *IAMSys) checkUserName(accessKey string) error {
+	if iamReservedNames.Contains(accessKey) || accessKey == globalActiveCred.AccessKey {
+		return fmt.Errorf("access key %s is reserved: %w", accessKey, errInvalidArgument)
+	}
+	if _, ok := sys.iamPolicyDocsMap[accessKey]; ok {
+		return fmt.Errorf("access key %s is the name of a canned policy: %w", accessKey, errInvalidArgument)
+	}
+	if _, ok := sys.iamGroupsMap[accessKey]; ok {
+		return fmt.Errorf("access key %s is the name of a group: %w", accessKey, errInvalidArgument)
+	}
+	return nil
+}
+
 // buildUserGroupMemberships - builds the memberships map. IMPORTANT:
 // Assumes that sys.Lock is held by caller.
 func (sys *IAMSys) buildUserGroupMemberships() {
@@ -2359,6 +6573,77 @@ func (sys *IAMSys) buildUserGroupMemberships() {
 	}
 }
 
+// groupMembershipsRebuildRatio - when more than 1/N of the groups
+// changed in a reload, the memberships map is rebuilt from scratch
+// instead of being updated group by group.
+const groupMembershipsRebuildRatio = 4
+
+// setGroupsMap - replaces the groups map with m, recomputing the
+// memberships only for the groups whose members changed. Falls back
+// to a full rebuild when most groups changed. IMPORTANT: Assumes that
+// sys.Lock is held by caller.
+func (sys *IAMSys) setGroupsMap(m map[string]GroupInfo) {
+	old := sys.iamGroupsMap
+	sys.iamGroupsMap = m
+
+	var changed []string
+	for group, gi := range m {
+		if ogi, ok := old[group]; !ok || !equalGroupMembers(ogi.Members, gi.Members) {
+			changed = append(changed, group)
+		}
+	}
+	for group := range old {
+		if _, ok := m[group]; !ok {
+			changed = append(changed, group)
+		}
+	}
+
+	if len(changed) == 0 {
+		return
+	}
+
+	if len(old) == 0 || len(changed)*groupMembershipsRebuildRatio > len(m) {
+		sys.iamUserGroupMemberships = make(map[string]set.StringSet)
+		sys.buildUserGroupMemberships()
+		for _, cred := range sys.iamUsersMap {
+			sys.cacheLDAPGroupMemberships(cred)
+		}
+		return
+	}
+
+	for _, group := range changed {
+		if ogi, ok := old[group]; ok {
+			for _, member := range ogi.Members {
+				groups := sys.iamUserGroupMemberships[member]
+				if groups == nil {
+					continue
+				}
+				groups.Remove(group)
+				if groups.IsEmpty() {
+					delete(sys.iamUserGroupMemberships, member)
+				}
+			}
+		}
+		if gi, ok := m[group]; ok {
+			sys.updateGroupMembershipsMap(group, &gi)
+		}
+	}
+}
+
+// equalGroupMembers - reports whether both member lists are the same,
+// in the same order.
+func equalGroupMembers(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
 // updateGroupMembershipsMap - updates the memberships map for a
 // group. IMPORTANT: Assumes sys.Lock() is held by caller.
 func (sys *IAMSys) updateGroupMembershipsMap(group string, gi *GroupInfo) {
@@ -2376,6 +6661,53 @@ func (sys *IAMSys) updateGroupMembershipsMap(group string, gi *GroupInfo) {
 	}
 }
 
+// cacheLDAPGroupMemberships - records the LDAP groups of the given STS
+// credentials in the memberships map when the LDAP group membership
+// cache is enabled. Entries are keyed by the temporary access key and
+// go away with the credentials, they are never used for authorization.
+// IMPORTANT: Assumes sys.Lock() is held by caller.
+func (sys *IAMSys) cacheLDAPGroupMemberships(cred auth.Credentials) {
+	if !sys.ldapGroupMembershipCache || sys.usersSysType != LDAPUsersSysType {
+		return
+	}
+	if !cred.IsTemp() || cred.IsExpired() {
+		return
+	}
+	for _, group := range cred.Groups {
+		v := sys.iamUserGroupMemberships[cred.AccessKey]
+		if v == nil {
+			v = set.CreateStringSet(group)
+		} else {
+			v.Add(group)
+		}
+		sys.iamUserGroupMemberships[cred.AccessKey] = v
+	}
+}
+
+// cachedLDAPGroupMembers - returns the LDAP users which hold valid STS
+// credentials for the given group, as recorded by
+// cacheLDAPGroupMemberships. The list is approximate. IMPORTANT:
+// Assumes sys.Lock() is held by caller.
+func (sys *IAMSys) cachedLDAPGroupMembers(group string) []string {
+	if !sys.ldapGroupMembershipCache {
+		return nil
+	}
+	members := set.NewStringSet()
+	for accessKey, groups := range sys.iamUserGroupMemberships {
+		if !groups.Contains(group) {
+			continue
+		}
+		cred, ok := sys.iamUsersMap[accessKey]
+		if !ok || cred.IsExpired() {
+			// Credentials are gone, so is the cache entry.
+			delete(sys.iamUserGroupMemberships, accessKey)
+			continue
+		}
+		members.Add(cred.ParentUser)
+	}
+	return members.ToSlice()
+}
+
 // removeGroupFromMembershipsMap - removes the group from every member
 // in the cache. IMPORTANT: Assumes sys.Lock() is held by caller.
 func (sys *IAMSys) removeGroupFromMembershipsMap(group string) {
@@ -2395,15 +6727,19 @@ func (sys *IAMSys) EnableLDAPSys() {
 
 func (sys *IAMSys) loadPolicyDocs() error {
 	m := make(map[string]iampolicy.Policy)
-	if err := sys.store.loadPolicyDocs(context.Background(), m); err != nil {
+	lazyPolicies, err := sys.loadPolicyDocsFrom(context.Background(), sys.store, m)
+	if err != nil {
 		return err
 	}
+	sys.rewriteAllResources(m)
 
 	// Sets default canned policies, if none are set.
 	setDefaultCannedPolicies(m)
 	sys.Lock()
 	defer sys.Unlock()
 	sys.iamPolicyDocsMap = m
+	sys.iamLazyPolicies = lazyPolicies
+	sys.invalidateCombinedPolicies()
 	return nil
 }
 
@@ -2414,20 +6750,35 @@ func (sys *IAMSys) loadGroups() error {
 	}
 	sys.Lock()
 	defer sys.Unlock()
-	sys.iamGroupsMap = m
+	sys.setGroupsMap(m)
 	return nil
 }
 
 // NewIAMSys - creates new config system object.
 func NewIAMSys() *IAMSys {
 	return &IAMSys{
-		usersSysType:            MinIOUsersSysType,
-		iamUsersMap:             make(map[string]auth.Credentials),
-		iamPolicyDocsMap:        make(map[string]iampolicy.Policy),
-		iamUserPolicyMap:        make(map[string]MappedPolicy),
-		iamGroupPolicyMap:       make(map[string]MappedPolicy),
-		iamGroupsMap:            make(map[string]GroupInfo),
-		iamUserGroupMemberships: make(map[string]set.StringSet),
-		configLoaded:            make(chan struct{}),
+		usersSysType:              MinIOUsersSysType,
+		policyMaxStatements:       defaultPolicyMaxStatements,
+		maxServiceAccountsPerUser: defaultMaxServiceAccountsPerUser,
+		storeLockTimeout:          NewDynamicTimeout(defaultIAMLockTimeout, defaultIAMLockTimeoutMin),
+		storeWriteRetries:         defaultIAMStoreWriteRetries,
+		stsRateLimit:              defaultSTSRateLimit,
+		sessionPolicyMaxSize:      16 * humanize.KiByte,
+		iamUsersMap:               make(map[string]auth.Credentials),
+		iamPolicyDocsMap:          make(map[string]iampolicy.Policy),
+		iamLazyPolicies:           set.NewStringSet(),
+		iamUserPolicyMap:          make(map[string]MappedPolicy),
+		iamGroupPolicyMap:         make(map[string]MappedPolicy),
+		iamGroupsMap:              make(map[string]GroupInfo),
+		iamUserGroupMemberships:   make(map[string]set.StringSet),
+		combinedPolicyCache:       make(map[string]iampolicy.Policy),
+		iamSecretRotations:        make(map[string]secretRotation),
+		iamAdditionalSecretKeys:   make(map[string][]string),
+		iamUserQuotas:             make(map[string]UserQuota),
+		iamUserBuckets:            make(map[string][]string),
+		iamMFARequired:            set.NewStringSet(),
+		iamUserTags:               make(map[string]map[string]string),
+		stsRateLimiters:           make(map[string]*stsRateLimiter),
+		configLoaded:              make(chan struct{}),
 	}
 }
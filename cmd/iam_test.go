@@ -0,0 +1,2281 @@
+/*
+ * MinIO Cloud Storage, (C) 2021 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	humanize "github.com/dustin/go-humanize"
+	"github.com/minio/minio-go/v7/pkg/set"
+	"github.com/minio/minio/pkg/auth"
+	"github.com/minio/minio/pkg/bucket/policy"
+	iampolicy "github.com/minio/minio/pkg/iam/policy"
+	"github.com/minio/minio/pkg/madmin"
+)
+
+func TestIAMPolicyUsernameVariable(t *testing.T) {
+	p, err := iampolicy.ParseConfig(strings.NewReader(`{
+  "Version": "2012-10-17",
+  "Statement": [
+    {
+      "Effect": "Allow",
+      "Action": ["s3:GetObject"],
+      "Resource": ["arn:aws:s3:::home/${aws:username}/*"]
+    }
+  ]
+}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const (
+		parent         = "parentuser"
+		serviceAccount = "SVCACCOUNTKEY"
+	)
+
+	// Condition values as computed by the handlers for a request
+	// signed by the service account.
+	conditionValues := map[string][]string{
+		"username": {serviceAccount},
+	}
+
+	testCases := []struct {
+		username   string
+		objectName string
+		allowed    bool
+	}{
+		// Service account resolves to the parent.
+		{parent, parent + "/file", true},
+		{parent, serviceAccount + "/file", false},
+		// Regular user resolves to itself.
+		{serviceAccount, serviceAccount + "/file", true},
+		{serviceAccount, parent + "/file", false},
+	}
+
+	for i, testCase := range testCases {
+		args := withUsername(iampolicy.Args{
+			AccountName:     serviceAccount,
+			Action:          iampolicy.GetObjectAction,
+			BucketName:      "home",
+			ObjectName:      testCase.objectName,
+			ConditionValues: conditionValues,
+		}, testCase.username)
+		if allowed := p.IsAllowed(args); allowed != testCase.allowed {
+			t.Fatalf("test %v: expected: %v, got: %v", i+1, testCase.allowed, allowed)
+		}
+	}
+
+	if v := conditionValues["username"]; len(v) != 1 || v[0] != serviceAccount {
+		t.Fatalf("condition values of the caller were modified: %v", conditionValues)
+	}
+}
+
+// testTenantPolicy - allows reading the objects of the tenant bucket.
+const testTenantPolicy = `{
+  "Version": "2012-10-17",
+  "Statement": [
+    {
+      "Effect": "Allow",
+      "Action": ["s3:GetObject"],
+      "Resource": ["arn:aws:s3:::tenant/*"]
+    }
+  ]
+}`
+
+// newTestIAMSys - returns an IAM sub-system loaded from an empty
+// in-memory store.
+func newTestIAMSys(t *testing.T) *IAMSys {
+	t.Helper()
+
+	sys := NewIAMSys()
+	sys.InitStoreWith(NewInMemoryIAMStore())
+	if err := sys.store.loadAll(context.Background(), sys); err != nil {
+		t.Fatal(err)
+	}
+	return sys
+}
+
+// testMemoryStore - returns the in-memory store behind sys.store,
+// unwrapping the retrying store InitStoreWith adds by default.
+func testMemoryStore(t *testing.T, sys *IAMSys) *IAMMemoryStore {
+	t.Helper()
+
+	store := sys.store
+	if rs, ok := store.(*iamRetryStore); ok {
+		store = rs.IAMStorageAPI
+	}
+	ms, ok := store.(*IAMMemoryStore)
+	if !ok {
+		t.Fatalf("expected an in-memory IAM store, got %T", sys.store)
+	}
+	return ms
+}
+
+// setTestPolicy - parses and saves a canned policy.
+func setTestPolicy(t *testing.T, sys *IAMSys, name, policyJSON string) {
+	t.Helper()
+
+	p, err := iampolicy.ParseConfig(strings.NewReader(policyJSON))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = sys.SetPolicy(name, *p); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestIAMDeletePolicyMappingCleanup(t *testing.T) {
+	sys := newTestIAMSys(t)
+	setTestPolicy(t, sys, "tenant", testTenantPolicy)
+
+	var err error
+	if err = sys.CreateUser("alice", madmin.UserInfo{
+		SecretKey:  "alicesecretkey",
+		PolicyName: "tenant,readonly",
+		Status:     madmin.AccountEnabled,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err = sys.AddUsersToGroup("tenants", []string{"alice"}); err != nil {
+		t.Fatal(err)
+	}
+	if err = sys.PolicyDBSet("tenants", "tenant", true); err != nil {
+		t.Fatal(err)
+	}
+
+	err = sys.DeletePolicyWithOpts("tenant", deletePolicyOpts{rejectInUse: true})
+	if !errors.Is(err, errPolicyInUse) {
+		t.Fatalf("expected %v, got %v", errPolicyInUse, err)
+	}
+	if _, err = sys.InfoPolicy("tenant"); err != nil {
+		t.Fatalf("expected the policy in use to be kept, got %v", err)
+	}
+
+	if err = sys.DeletePolicy("tenant"); err != nil {
+		t.Fatal(err)
+	}
+
+	policies, err := sys.PolicyDBGet("alice", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(policies) != 1 || policies[0] != "readonly" {
+		t.Fatalf("expected user mapping [readonly], got %v", policies)
+	}
+	if policies, err = sys.PolicyDBGet("tenants", true); err != nil || len(policies) != 0 {
+		t.Fatalf("expected no group mapping, got %v, %v", policies, err)
+	}
+
+	// The cleanup must have reached the store as well.
+	mp, err := sys.GetStoredMappedPolicy("alice", regularUser, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mp.Policies != "readonly" {
+		t.Fatalf("expected stored user mapping readonly, got %s", mp.Policies)
+	}
+}
+
+func TestIAMLazyPolicyLoad(t *testing.T) {
+	sys := newTestIAMSys(t)
+	setTestPolicy(t, sys, "tenant", testTenantPolicy)
+
+	var err error
+	sys.lazyPolicyLoad = true
+	if err = sys.Load(context.Background(), sys.store); err != nil {
+		t.Fatal(err)
+	}
+	if !sys.iamLazyPolicies.Contains("tenant") {
+		t.Fatal("expected the tenant policy document not to be loaded")
+	}
+
+	combined := sys.GetCombinedPolicy("tenant")
+	if len(combined.Statements) != 1 {
+		t.Fatalf("expected 1 statement, got %d", len(combined.Statements))
+	}
+	if sys.iamLazyPolicies.Contains("tenant") {
+		t.Fatal("expected the tenant policy document to be loaded on first use")
+	}
+
+	if err = sys.DeletePolicy("tenant"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = sys.InfoPolicy("tenant"); err != errNoSuchPolicy {
+		t.Fatalf("expected %v, got %v", errNoSuchPolicy, err)
+	}
+}
+
+// failingPolicyDocStore - fails reads of single policy documents with
+// err, unless it is nil.
+type failingPolicyDocStore struct {
+	IAMStorageAPI
+	err error
+}
+
+func (s *failingPolicyDocStore) loadPolicyDoc(ctx context.Context, policy string, m map[string]iampolicy.Policy) error {
+	if s.err != nil {
+		return s.err
+	}
+	return s.IAMStorageAPI.loadPolicyDoc(ctx, policy, m)
+}
+
+func TestIAMLazyPolicyLoadError(t *testing.T) {
+	sys := newTestIAMSys(t)
+	setTestPolicy(t, sys, "tenant", testTenantPolicy)
+
+	var err error
+	sys.lazyPolicyLoad = true
+	if err = sys.Load(context.Background(), sys.store); err != nil {
+		t.Fatal(err)
+	}
+	store := &failingPolicyDocStore{IAMStorageAPI: sys.store, err: errErasureReadQuorum}
+	sys.store = store
+
+	if _, err = sys.ListPolicies(); !errors.Is(err, errErasureReadQuorum) {
+		t.Fatalf("expected %v, got %v", errErasureReadQuorum, err)
+	}
+	if _, err = sys.InfoPolicy("tenant"); !errors.Is(err, errErasureReadQuorum) {
+		t.Fatalf("expected %v, got %v", errErasureReadQuorum, err)
+	}
+	if combined := sys.GetCombinedPolicy("tenant"); len(combined.Statements) != 0 {
+		t.Fatalf("expected no statements, got %d", len(combined.Statements))
+	}
+
+	// The policy is read again once the store recovers.
+	store.err = nil
+	p, err := sys.InfoPolicy("tenant")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(p.Statements) != 1 {
+		t.Fatalf("expected 1 statement, got %d", len(p.Statements))
+	}
+}
+
+// flakyIAMStore - fails the first failures saves of user identities
+// with err.
+type flakyIAMStore struct {
+	IAMStorageAPI
+	failures int
+	err      error
+	attempts int
+}
+
+func (s *flakyIAMStore) saveUserIdentity(ctx context.Context, name string, userType IAMUserType, u UserIdentity, opts ...options) error {
+	s.attempts++
+	if s.attempts <= s.failures {
+		return s.err
+	}
+	return s.IAMStorageAPI.saveUserIdentity(ctx, name, userType, u, opts...)
+}
+
+func TestIAMRetryStore(t *testing.T) {
+	testCases := []struct {
+		failures         int
+		err              error
+		expectedAttempts int
+		expectedErr      error
+	}{
+		// Succeeds on the second attempt.
+		{1, errErasureWriteQuorum, 2, nil},
+		// Gives up once the retries are exhausted.
+		{10, errErasureWriteQuorum, 4, errErasureWriteQuorum},
+		// Not a transient error, no retry.
+		{1, errNoSuchUser, 1, errNoSuchUser},
+	}
+
+	for i, testCase := range testCases {
+		flaky := &flakyIAMStore{
+			IAMStorageAPI: NewInMemoryIAMStore(),
+			failures:      testCase.failures,
+			err:           testCase.err,
+		}
+		store := newIAMRetryStore(flaky, 3)
+		store.backoff = time.Millisecond
+		store.maxBackoff = time.Millisecond
+
+		u := newUserIdentity(auth.Credentials{AccessKey: "alice", SecretKey: "alicesecretkey"})
+		err := store.saveUserIdentity(context.Background(), "alice", regularUser, u)
+		if !errors.Is(err, testCase.expectedErr) {
+			t.Fatalf("test %v: expected error %v, got %v", i+1, testCase.expectedErr, err)
+		}
+		if flaky.attempts != testCase.expectedAttempts {
+			t.Fatalf("test %v: expected %v attempts, got %v", i+1, testCase.expectedAttempts, flaky.attempts)
+		}
+		if err == nil {
+			if _, err = store.getUserIdentity(context.Background(), "alice", regularUser); err != nil {
+				t.Fatalf("test %v: user was not saved: %v", i+1, err)
+			}
+		}
+	}
+}
+
+func TestIAMRetryStoreTimeout(t *testing.T) {
+	flaky := &flakyIAMStore{
+		IAMStorageAPI: NewInMemoryIAMStore(),
+		failures:      10,
+		err:           errErasureWriteQuorum,
+	}
+	store := newIAMRetryStore(flaky, 10)
+	store.backoff = 20 * time.Millisecond
+	store.maxBackoff = 20 * time.Millisecond
+	store.timeout = 50 * time.Millisecond
+
+	u := newUserIdentity(auth.Credentials{AccessKey: "alice", SecretKey: "alicesecretkey"})
+	if err := store.saveUserIdentity(context.Background(), "alice", regularUser, u); !errors.Is(err, errErasureWriteQuorum) {
+		t.Fatalf("expected error %v, got %v", errErasureWriteQuorum, err)
+	}
+	// No retry may start past the timeout.
+	if flaky.attempts >= 10 {
+		t.Fatalf("expected retries to stop at the timeout, got %v attempts", flaky.attempts)
+	}
+}
+
+func TestIAMNamePaths(t *testing.T) {
+	testCases := []struct {
+		name    string
+		valid   bool
+		docPath string
+	}{
+		{"readwrite", true, "config/iam/policies/readwrite/policy.json"},
+		{"team.dev", true, "config/iam/policies/team.dev/policy.json"},
+		{"..", false, "config/iam/policies/policy.json"},
+		{"a/b", false, "config/iam/policies/a/b/policy.json"},
+		{"../../format", false, "config/iam/policies/format/policy.json"},
+	}
+
+	for i, testCase := range testCases {
+		err := checkIAMName(testCase.name)
+		if testCase.valid && err != nil {
+			t.Errorf("case %d: unexpected error %v", i+1, err)
+		}
+		if !testCase.valid && !errors.Is(err, errInvalidArgument) {
+			t.Errorf("case %d: expected %v, got %v", i+1, errInvalidArgument, err)
+		}
+		if p := getPolicyDocPath(testCase.name); p != testCase.docPath {
+			t.Errorf("case %d: expected path %s, got %s", i+1, testCase.docPath, p)
+		}
+	}
+}
+
+// failingMappingStore - fails saves of the policy mapping of name with
+// err.
+type failingMappingStore struct {
+	IAMStorageAPI
+	name string
+	err  error
+}
+
+func (s *failingMappingStore) saveMappedPolicy(ctx context.Context, name string, userType IAMUserType, isGroup bool, mp MappedPolicy, opts ...options) error {
+	if name == s.name {
+		return s.err
+	}
+	return s.IAMStorageAPI.saveMappedPolicy(ctx, name, userType, isGroup, mp, opts...)
+}
+
+func TestIAMRenamePolicy(t *testing.T) {
+	sys := newTestIAMSys(t)
+	setTestPolicy(t, sys, "tenant", testTenantPolicy)
+
+	var err error
+	if err = sys.CreateUser("alice", madmin.UserInfo{
+		SecretKey:  "alicesecretkey",
+		PolicyName: "tenant,readonly",
+		Status:     madmin.AccountEnabled,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err = sys.AddUsersToGroup("devs", []string{"alice"}); err != nil {
+		t.Fatal(err)
+	}
+	if err = sys.PolicyDBSet("devs", "tenant", true); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, newName := range []string{"", "tenant", "readonly", "a/b", ".."} {
+		if err = sys.RenamePolicy("tenant", newName); !errors.Is(err, errInvalidArgument) {
+			t.Errorf("%q: expected %v, got %v", newName, errInvalidArgument, err)
+		}
+	}
+	if err = sys.RenamePolicy("missing", "other"); err != errNoSuchPolicy {
+		t.Fatalf("expected %v, got %v", errNoSuchPolicy, err)
+	}
+
+	// The group mapping can not be saved, alice is mapped back to the
+	// old policy and the new policy is dropped.
+	store := sys.store
+	sys.store = &failingMappingStore{IAMStorageAPI: store, name: "devs", err: errErasureWriteQuorum}
+	if err = sys.RenamePolicy("tenant", "team"); !errors.Is(err, errErasureWriteQuorum) {
+		t.Fatalf("expected %v, got %v", errErasureWriteQuorum, err)
+	}
+	sys.store = store
+	if _, err = sys.InfoPolicy("team"); err != errNoSuchPolicy {
+		t.Fatalf("expected the new policy to be deleted, got %v", err)
+	}
+	if err = sys.Load(context.Background(), sys.store); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = sys.InfoPolicy("team"); err != errNoSuchPolicy {
+		t.Fatalf("expected the new policy to be deleted from the store, got %v", err)
+	}
+	policies, err := sys.PolicyDBGet("alice", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := set.CreateStringSet(policies...); !got.Equals(set.CreateStringSet("tenant", "readonly")) {
+		t.Fatalf("expected alice to be mapped to tenant and readonly, got %v", policies)
+	}
+
+	if err = sys.RenamePolicy("tenant", "team"); err != nil {
+		t.Fatal(err)
+	}
+	if err = sys.Load(context.Background(), sys.store); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = sys.InfoPolicy("tenant"); err != errNoSuchPolicy {
+		t.Fatalf("expected the old policy to be deleted, got %v", err)
+	}
+	if policies, err = sys.PolicyDBGet("alice", false); err != nil {
+		t.Fatal(err)
+	}
+	if got := set.CreateStringSet(policies...); !got.Equals(set.CreateStringSet("team", "readonly")) {
+		t.Fatalf("expected alice to be mapped to team and readonly, got %v", policies)
+	}
+	if policies, err = sys.PolicyDBGet("devs", true); err != nil {
+		t.Fatal(err)
+	}
+	if len(policies) != 1 || policies[0] != "team" {
+		t.Fatalf("expected devs to be mapped to team, got %v", policies)
+	}
+}
+
+func TestIAMUserIdentityPreserved(t *testing.T) {
+	sys := newTestIAMSys(t)
+	ctx := context.Background()
+
+	var err error
+	if err = sys.CreateUser("alice", madmin.UserInfo{
+		SecretKey: "alicepreviousecret",
+		Status:    madmin.AccountEnabled,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err = sys.SetUserTags("alice", map[string]string{"team": "storage"}); err != nil {
+		t.Fatal(err)
+	}
+	if err = sys.SetUserMFARequired("alice", true); err != nil {
+		t.Fatal(err)
+	}
+	if err = sys.RotateUserSecretKey("alice", "aliceprimarysecret", time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if err = sys.SetUserSecretKeyWithOpts("alice", "aliceadditionalsecret", setUserSecretKeyOpts{appendKey: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	checkIdentity := func(previous string) UserIdentity {
+		t.Helper()
+		u, err := sys.store.getUserIdentity(ctx, "alice", regularUser)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if u.Credentials.SecretKey != "aliceprimarysecret" || u.PreviousSecretKey != previous {
+			t.Fatalf("unexpected secret keys: %s, previous %s", u.Credentials.SecretKey, u.PreviousSecretKey)
+		}
+		if len(u.SecretKeys) != 1 || u.SecretKeys[0] != "aliceadditionalsecret" {
+			t.Fatalf("unexpected additional secret keys: %v", u.SecretKeys)
+		}
+		if !u.MFARequired || u.Tags["team"] != "storage" {
+			t.Fatalf("unexpected identity: %+v", u)
+		}
+		return u
+	}
+
+	// Updates of the user must keep everything else saved with it.
+	if err = sys.SetUserStatus("alice", madmin.AccountDisabled); err != nil {
+		t.Fatal(err)
+	}
+	checkIdentity("alicepreviousecret")
+	if err = sys.CreateUser("alice", madmin.UserInfo{
+		SecretKey: "aliceprimarysecret",
+		Status:    madmin.AccountEnabled,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	u := checkIdentity("alicepreviousecret")
+
+	// Once the grace window is over only the previous secret key is
+	// purged.
+	u.RotationExpiry = UTCNow().Add(-time.Second)
+	if err = sys.store.saveUserIdentity(ctx, "alice", regularUser, u); err != nil {
+		t.Fatal(err)
+	}
+	sys.purgeSecretRotations([]string{"alice"})
+	checkIdentity("")
+}
+
+func TestIAMAdditionalSecretKeys(t *testing.T) {
+	// Secret keys are matched through globalIAMSys.
+	oldIAMSys := globalIAMSys
+	defer func() { globalIAMSys = oldIAMSys }()
+	sys := newTestIAMSys(t)
+	globalIAMSys = sys
+
+	var err error
+	if err = sys.CreateUser("alice", madmin.UserInfo{
+		SecretKey: "aliceprimarysecret",
+		Status:    madmin.AccountEnabled,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	var secretKeys []string
+	for i := 0; i < maxAdditionalSecretKeys; i++ {
+		secretKey := fmt.Sprintf("aliceadditionalsecret%d", i)
+		if err = sys.SetUserSecretKeyWithOpts("alice", secretKey, setUserSecretKeyOpts{appendKey: true}); err != nil {
+			t.Fatal(err)
+		}
+		secretKeys = append(secretKeys, secretKey)
+	}
+	// Adding a key already accepted is a no-op.
+	if err = sys.SetUserSecretKeyWithOpts("alice", secretKeys[0], setUserSecretKeyOpts{appendKey: true}); err != nil {
+		t.Fatal(err)
+	}
+	if err = sys.SetUserSecretKeyWithOpts("alice", "aliceonetoomanysecret", setUserSecretKeyOpts{appendKey: true}); !errors.Is(err, errInvalidArgument) {
+		t.Fatalf("expected %v, got %v", errInvalidArgument, err)
+	}
+
+	checkAccepted := func(accepted []string, rejected []string) {
+		t.Helper()
+		for _, secretKey := range accepted {
+			if _, err := sys.ValidateCredentials("alice", secretKey); err != nil {
+				t.Fatalf("secret key %s: unexpected error %v", secretKey, err)
+			}
+		}
+		for _, secretKey := range rejected {
+			if _, err := sys.ValidateCredentials("alice", secretKey); !errors.Is(err, errInvalidSecret) {
+				t.Fatalf("secret key %s: expected %v, got %v", secretKey, errInvalidSecret, err)
+			}
+		}
+	}
+
+	checkAccepted(append([]string{"aliceprimarysecret"}, secretKeys...), []string{"aliceonetoomanysecret"})
+
+	// Additional secret keys are persisted.
+	if err = sys.store.loadAll(context.Background(), sys); err != nil {
+		t.Fatal(err)
+	}
+	checkAccepted(append([]string{"aliceprimarysecret"}, secretKeys...), nil)
+
+	// Replacing the primary secret key keeps the additional ones.
+	if err = sys.SetUserSecretKey("alice", "alicenewprimarysecret"); err != nil {
+		t.Fatal(err)
+	}
+	checkAccepted(append([]string{"alicenewprimarysecret"}, secretKeys...), []string{"aliceprimarysecret"})
+
+	if err = sys.PruneSecretKeys("alice"); err != nil {
+		t.Fatal(err)
+	}
+	checkAccepted([]string{"alicenewprimarysecret"}, secretKeys)
+}
+
+func TestIAMPolicyMaxStatements(t *testing.T) {
+	sys := newTestIAMSys(t)
+	sys.policyMaxStatements = 1
+
+	p, err := iampolicy.ParseConfig(strings.NewReader(`{
+  "Version": "2012-10-17",
+  "Statement": [
+    {
+      "Effect": "Allow",
+      "Action": ["s3:GetObject"],
+      "Resource": ["arn:aws:s3:::tenant/*"]
+    },
+    {
+      "Effect": "Allow",
+      "Action": ["s3:PutObject"],
+      "Resource": ["arn:aws:s3:::tenant/*"]
+    }
+  ]
+}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = sys.SetPolicy("tenant", *p); !errors.Is(err, errPolicyTooManyStatements) {
+		t.Fatalf("expected %v, got %v", errPolicyTooManyStatements, err)
+	}
+
+	// Policies saved before the limit was lowered are still loaded.
+	if err = sys.store.savePolicyDoc(context.Background(), "tenant", PolicyDoc{Version: 1, Policy: *p}); err != nil {
+		t.Fatal(err)
+	}
+	if err = sys.store.loadAll(context.Background(), sys); err != nil {
+		t.Fatal(err)
+	}
+	stored, err := sys.InfoPolicy("tenant")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stored.Statements) != 2 {
+		t.Fatalf("expected 2 statements, got %d", len(stored.Statements))
+	}
+}
+
+func TestIAMPolicyMaxSize(t *testing.T) {
+	resources := make([]string, 0, 1000)
+	for i := 0; i < cap(resources); i++ {
+		resources = append(resources, fmt.Sprintf(`"arn:aws:s3:::tenant/prefix-%04d/*"`, i))
+	}
+	p, err := iampolicy.ParseConfig(strings.NewReader(`{
+  "Version": "2012-10-17",
+  "Statement": [
+    {
+      "Effect": "Allow",
+      "Action": ["s3:GetObject"],
+      "Resource": [` + strings.Join(resources, ",") + `]
+    }
+  ]
+}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// No limit unless one is configured.
+	sys := newTestIAMSys(t)
+	if err = sys.SetPolicy("tenant", *p); err != nil {
+		t.Fatal(err)
+	}
+
+	sys.policyMaxSize = 20 * humanize.KiByte
+	if err = sys.SetPolicy("tenant", *p); err == nil {
+		t.Fatal("expected the policy to exceed the configured maximum size")
+	}
+}
+
+func TestIAMNestedGroups(t *testing.T) {
+	sys := newTestIAMSys(t)
+	setTestPolicy(t, sys, "tenant", testTenantPolicy)
+
+	var err error
+	if err = sys.CreateUser("alice", madmin.UserInfo{
+		SecretKey: "alicesecretkey",
+		Status:    madmin.AccountEnabled,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err = sys.AddUsersToGroup("devs", []string{"alice"}); err != nil {
+		t.Fatal(err)
+	}
+	if err = sys.AddUsersToGroup("staff", []string{nestedGroupPrefix + "devs"}); err != nil {
+		t.Fatal(err)
+	}
+	if err = sys.AddUsersToGroup("all", []string{nestedGroupPrefix + "staff"}); err != nil {
+		t.Fatal(err)
+	}
+	if err = sys.PolicyDBSet("all", "tenant", true); err != nil {
+		t.Fatal(err)
+	}
+
+	// Members of devs inherit the policies of the groups it is
+	// nested in, transitively.
+	policies, err := sys.PolicyDBGet("alice", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(policies) != 1 || policies[0] != "tenant" {
+		t.Fatalf("expected the tenant policy, got %v", policies)
+	}
+
+	// Cycles are rejected, even through a disabled group.
+	if err = sys.AddUsersToGroup("devs", []string{nestedGroupPrefix + "all"}); !errors.Is(err, errInvalidArgument) {
+		t.Fatalf("expected %v, got %v", errInvalidArgument, err)
+	}
+	if err = sys.SetGroupStatus("staff", false); err != nil {
+		t.Fatal(err)
+	}
+	if err = sys.AddUsersToGroup("devs", []string{nestedGroupPrefix + "all"}); !errors.Is(err, errInvalidArgument) {
+		t.Fatalf("expected %v, got %v", errInvalidArgument, err)
+	}
+	if err = sys.AddUsersToGroup("devs", []string{nestedGroupPrefix + "devs"}); !errors.Is(err, errInvalidArgument) {
+		t.Fatalf("expected %v, got %v", errInvalidArgument, err)
+	}
+
+	// Access keys cannot be confused with nested groups.
+	if err = sys.CreateUser(nestedGroupPrefix+"devs", madmin.UserInfo{
+		SecretKey: "groupsecretkey",
+		Status:    madmin.AccountEnabled,
+	}); !errors.Is(err, errInvalidArgument) {
+		t.Fatalf("expected %v, got %v", errInvalidArgument, err)
+	}
+}
+
+func TestIAMUserBucketQuota(t *testing.T) {
+	sys := newTestIAMSys(t)
+	setTestPolicy(t, sys, "tenant", testTenantPolicy)
+
+	var err error
+	for _, user := range []string{"alice", "bob"} {
+		if err = sys.CreateUser(user, madmin.UserInfo{
+			SecretKey: user + "secretkey",
+			Status:    madmin.AccountEnabled,
+		}); err != nil {
+			t.Fatal(err)
+		}
+		if err = sys.PolicyDBSet(user, "tenant", false); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = sys.SetUserQuota("alice", UserQuota{MaxBuckets: 2}); err != nil {
+		t.Fatal(err)
+	}
+	svc, err := sys.NewServiceAccount(context.Background(), "alice", nil, newServiceAccountOpts{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reserve := func(accessKey, bucket string, wantReserved bool, wantErr error) {
+		t.Helper()
+		reserved, err := sys.ReserveUserBucket(accessKey, bucket)
+		if !errors.Is(err, wantErr) {
+			t.Fatalf("%s: expected %v, got %v", bucket, wantErr, err)
+		}
+		if reserved != wantReserved {
+			t.Fatalf("%s: expected reserved %v, got %v", bucket, wantReserved, reserved)
+		}
+	}
+
+	// Service accounts count against the quota of their parent user,
+	// buckets already counted are not counted twice.
+	reserve("alice", "bucket1", true, nil)
+	reserve("alice", "bucket1", false, nil)
+	reserve(svc.AccessKey, "bucket2", true, nil)
+	reserve("alice", "bucket3", false, errUserBucketQuotaExceeded)
+	reserve(svc.AccessKey, "bucket3", false, errUserBucketQuotaExceeded)
+
+	// Users without a quota are not limited and not counted.
+	reserve("bob", "bucket4", false, nil)
+
+	if err = sys.ReleaseUserBucket("alice", "bucket1"); err != nil {
+		t.Fatal(err)
+	}
+	reserve("alice", "bucket3", true, nil)
+
+	// Counted buckets survive a reload.
+	if err = sys.store.loadAll(context.Background(), sys); err != nil {
+		t.Fatal(err)
+	}
+	buckets, err := sys.GetUserBuckets("alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(buckets) != 2 || buckets[0] != "bucket2" || buckets[1] != "bucket3" {
+		t.Fatalf("expected bucket2 and bucket3, got %v", buckets)
+	}
+
+	// Removing the quota stops counting the buckets of the user.
+	if err = sys.SetUserQuota("alice", UserQuota{}); err != nil {
+		t.Fatal(err)
+	}
+	if buckets, err = sys.GetUserBuckets("alice"); err != nil {
+		t.Fatal(err)
+	}
+	if len(buckets) != 0 {
+		t.Fatalf("expected no buckets, got %v", buckets)
+	}
+
+	// A failed attempt to create a bucket owned by another user keeps
+	// it counted for its owner.
+	if err = sys.SetUserQuota("alice", UserQuota{MaxBuckets: 2}); err != nil {
+		t.Fatal(err)
+	}
+	if err = sys.SetUserQuota("bob", UserQuota{MaxBuckets: 1}); err != nil {
+		t.Fatal(err)
+	}
+	reserve("bob", "shared", true, nil)
+	reserve("alice", "shared", true, nil)
+	if err = sys.ReleaseUserBucket("alice", "shared"); err != nil {
+		t.Fatal(err)
+	}
+	if buckets, err = sys.GetUserBuckets("bob"); err != nil {
+		t.Fatal(err)
+	}
+	if len(buckets) != 1 || buckets[0] != "shared" {
+		t.Fatalf("expected shared to stay counted for bob, got %v", buckets)
+	}
+	reserve("bob", "bucket5", false, errUserBucketQuotaExceeded)
+
+	// Deleted buckets stop counting for their owner.
+	if err = sys.ReleaseUserBucket("", "shared"); err != nil {
+		t.Fatal(err)
+	}
+	reserve("bob", "bucket5", true, nil)
+}
+
+func TestIAMChangeHooksDelete(t *testing.T) {
+	sys := newTestIAMSys(t)
+
+	var events []IAMChangeEvent
+	sys.RegisterChangeHook(func(ev IAMChangeEvent) {
+		if ev.Action == IAMChangeDelete {
+			events = append(events, ev)
+		}
+	})
+	expectDelete := func(objectType IAMObjectType, name string) {
+		t.Helper()
+		if len(events) != 1 || events[0].ObjectType != objectType || events[0].Name != name {
+			t.Fatalf("expected the deletion of %s %s, got %v", objectType, name, events)
+		}
+		events = nil
+	}
+
+	ctx := context.Background()
+	var err error
+	for _, user := range []string{"alice", "bob"} {
+		if err = sys.CreateUser(user, madmin.UserInfo{
+			SecretKey: user + "secretkey",
+			Status:    madmin.AccountEnabled,
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	setTestPolicy(t, sys, "tenant", testTenantPolicy)
+	setTestPolicy(t, sys, "other", testTenantPolicy)
+
+	if err = sys.DeleteUser("alice"); err != nil {
+		t.Fatal(err)
+	}
+	expectDelete(IAMObjectUser, "alice")
+
+	if err = sys.DeletePolicy("tenant"); err != nil {
+		t.Fatal(err)
+	}
+	expectDelete(IAMObjectPolicy, "tenant")
+
+	// Objects deleted by another server are found deleted on reload.
+	if err = sys.store.deleteUserIdentity(ctx, "bob", regularUser); err != nil {
+		t.Fatal(err)
+	}
+	if err = sys.LoadUser("bob", regularUser); !errors.Is(err, errNoSuchUser) {
+		t.Fatalf("expected %v, got %v", errNoSuchUser, err)
+	}
+	expectDelete(IAMObjectUser, "bob")
+	if _, ok := sys.GetUser("bob"); ok {
+		t.Fatal("expected bob to be removed from memory")
+	}
+
+	if err = sys.store.deletePolicyDoc(ctx, "other"); err != nil {
+		t.Fatal(err)
+	}
+	if err = sys.LoadPolicy("other"); !errors.Is(err, errNoSuchPolicy) {
+		t.Fatalf("expected %v, got %v", errNoSuchPolicy, err)
+	}
+	expectDelete(IAMObjectPolicy, "other")
+}
+
+func TestIAMStrictDisabledGroups(t *testing.T) {
+	sys := newTestIAMSys(t)
+	sys.strictDisabledGroups = true
+	setTestPolicy(t, sys, "tenant", testTenantPolicy)
+
+	var err error
+	if err = sys.CreateUser("alice", madmin.UserInfo{
+		SecretKey: "alicesecretkey",
+		Status:    madmin.AccountEnabled,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err = sys.PolicyDBSet("alice", "tenant", false); err != nil {
+		t.Fatal(err)
+	}
+	if err = sys.AddUsersToGroup("devs", []string{"alice"}); err != nil {
+		t.Fatal(err)
+	}
+
+	args := iampolicy.Args{
+		AccountName: "alice",
+		Action:      iampolicy.GetObjectAction,
+		BucketName:  "tenant",
+		ObjectName:  "file",
+	}
+	if !sys.IsAllowed(args) {
+		t.Fatal("expected alice to be allowed")
+	}
+
+	// Members of a disabled group resolve to no policies at all,
+	// without an error.
+	if err = sys.SetGroupStatus("devs", false); err != nil {
+		t.Fatal(err)
+	}
+	policies, err := sys.PolicyDBGet("alice", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(policies) != 0 {
+		t.Fatalf("expected no policies, got %v", policies)
+	}
+	if sys.IsAllowed(args) {
+		t.Fatal("expected alice to be denied")
+	}
+	allowed, _, reason, err := sys.ExplainAccess(args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if allowed || reason != "the account is a member of a disabled group" {
+		t.Fatalf("expected a denial for the disabled group, got %v %q", allowed, reason)
+	}
+}
+
+func TestIAMReEncrypt(t *testing.T) {
+	oldCred := globalActiveCred
+	defer func() { globalActiveCred = oldCred }()
+	globalActiveCred = auth.Credentials{AccessKey: "minioadmin", SecretKey: "oldrootsecretkey"}
+	const newSecret = "newrootsecretkey"
+
+	sys := newTestIAMSys(t)
+	setTestPolicy(t, sys, "tenant", testTenantPolicy)
+
+	ctx := context.Background()
+	var err error
+	if err = sys.CreateUser("alice", madmin.UserInfo{
+		SecretKey: "alicesecretkey",
+		Status:    madmin.AccountEnabled,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err = sys.PolicyDBSet("alice", "tenant", false); err != nil {
+		t.Fatal(err)
+	}
+	permanent, err := sys.NewServiceAccount(ctx, "alice", nil, newServiceAccountOpts{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expiring, err := sys.NewServiceAccount(ctx, "alice", nil, newServiceAccountOpts{
+		expiry: UTCNow().Add(time.Hour),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tags := map[string]string{"team": "storage"}
+	if err = sys.SetUserTags(permanent.AccessKey, tags); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = sys.ReEncryptIAM(globalActiveCred.SecretKey, newSecret); err != nil {
+		t.Fatal(err)
+	}
+	// Resuming an already finished rotation changes nothing.
+	if err = sys.ReEncryptIAM(globalActiveCred.SecretKey, newSecret); err != nil {
+		t.Fatal(err)
+	}
+
+	store := testMemoryStore(t, sys)
+	for _, cred := range []auth.Credentials{permanent, expiring} {
+		u, err := store.getUserIdentity(ctx, cred.AccessKey, srvAccUser)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = auth.ExtractClaims(u.Credentials.SessionToken, newSecret); err != nil {
+			t.Fatalf("%s: expected a token signed with the new secret: %v", cred.AccessKey, err)
+		}
+		if u.Credentials.SecretKey != cred.SecretKey {
+			t.Fatalf("%s: expected the secret key to be kept", cred.AccessKey)
+		}
+	}
+
+	// Tags are read from the store and kept.
+	u, err := store.getUserIdentity(ctx, permanent.AccessKey, srvAccUser)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(u.Tags) != 1 || u.Tags["team"] != "storage" {
+		t.Fatalf("expected the tags to be kept, got %v", u.Tags)
+	}
+
+	// Expiring service accounts keep their TTL.
+	store.mu.Lock()
+	it := store.items[getUserIdentityPath(expiring.AccessKey, srvAccUser)]
+	store.mu.Unlock()
+	if it.expiry.IsZero() || it.expiry.After(UTCNow().Add(time.Hour)) {
+		t.Fatalf("expected the expiring service account to keep its TTL, got %v", it.expiry)
+	}
+}
+
+// testBucketPolicyProvider - bucket policies by bucket name.
+type testBucketPolicyProvider map[string]*policy.Policy
+
+func (p testBucketPolicyProvider) Get(bucket string) (*policy.Policy, error) {
+	bp, ok := p[bucket]
+	if !ok {
+		return nil, BucketPolicyNotFound{Bucket: bucket}
+	}
+	return bp, nil
+}
+
+func TestIAMIsAllowedWithBucketPolicy(t *testing.T) {
+	sys := newTestIAMSys(t)
+	setTestPolicy(t, sys, "tenant", testTenantPolicy)
+	setTestPolicy(t, sys, "denyshared", `{
+  "Version": "2012-10-17",
+  "Statement": [
+    {
+      "Effect": "Deny",
+      "Action": ["s3:GetObject"],
+      "Resource": ["arn:aws:s3:::shared/*"]
+    }
+  ]
+}`)
+
+	var err error
+	for user, policyName := range map[string]string{
+		"alice": "tenant",
+		"bob":   "denyshared",
+		"carol": "",
+	} {
+		if err = sys.CreateUser(user, madmin.UserInfo{
+			SecretKey: user + "secretkey",
+			Status:    madmin.AccountEnabled,
+		}); err != nil {
+			t.Fatal(err)
+		}
+		if policyName == "" {
+			continue
+		}
+		if err = sys.PolicyDBSet(user, policyName, false); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	bucketPolicies := make(testBucketPolicyProvider)
+	for bucket, policyJSON := range map[string]string{
+		// Denies the secret objects of the tenant bucket to everyone.
+		"tenant": `{
+  "Version": "2012-10-17",
+  "Statement": [
+    {
+      "Effect": "Deny",
+      "Principal": {"AWS": ["*"]},
+      "Action": ["s3:GetObject"],
+      "Resource": ["arn:aws:s3:::tenant/secret/*"]
+    }
+  ]
+}`,
+		// Allows everyone to read the objects of the shared bucket.
+		"shared": `{
+  "Version": "2012-10-17",
+  "Statement": [
+    {
+      "Effect": "Allow",
+      "Principal": {"AWS": ["*"]},
+      "Action": ["s3:GetObject"],
+      "Resource": ["arn:aws:s3:::shared/*"]
+    }
+  ]
+}`,
+	} {
+		bp, err := policy.ParseConfig(strings.NewReader(policyJSON), bucket)
+		if err != nil {
+			t.Fatal(err)
+		}
+		bucketPolicies[bucket] = bp
+	}
+
+	testCases := []struct {
+		account string
+		bucket  string
+		object  string
+		allowed bool
+	}{
+		// Allowed by the identity policy, unless the bucket policy
+		// denies it.
+		{"alice", "tenant", "file", true},
+		{"alice", "tenant", "secret/file", false},
+		// Allowed by the bucket policy alone, unless the identity
+		// policy denies it.
+		{"carol", "shared", "file", true},
+		{"bob", "shared", "file", false},
+		// Buckets without a policy are evaluated with the identity
+		// policies only.
+		{"carol", "other", "file", false},
+		{"alice", "other", "file", false},
+	}
+
+	check := func(label string, account, bucket, object string, expected bool) {
+		t.Helper()
+		allowed := sys.IsAllowed(iampolicy.Args{
+			AccountName: account,
+			Action:      iampolicy.GetObjectAction,
+			BucketName:  bucket,
+			ObjectName:  object,
+		})
+		if allowed != expected {
+			t.Fatalf("%s: %s reading %s/%s: expected %v, got %v", label, account, bucket, object, expected, allowed)
+		}
+	}
+
+	// Without a provider only the identity policies apply.
+	check("no provider", "alice", "tenant", "secret/file", true)
+	check("no provider", "carol", "shared", "file", false)
+
+	sys.SetBucketPolicyProvider(bucketPolicies)
+	for i, testCase := range testCases {
+		check(fmt.Sprintf("test %d", i+1), testCase.account, testCase.bucket, testCase.object, testCase.allowed)
+	}
+
+	// The bucket policy does not grant anything to disabled accounts.
+	if err = sys.SetUserStatus("carol", madmin.AccountDisabled); err != nil {
+		t.Fatal(err)
+	}
+	check("disabled account", "carol", "shared", "file", false)
+
+	// Removing the provider restores identity-only evaluation.
+	sys.SetBucketPolicyProvider(nil)
+	check("provider removed", "alice", "tenant", "secret/file", true)
+}
+
+func TestIAMDeleteAndRestoreUsers(t *testing.T) {
+	sys := newTestIAMSys(t)
+	sys.userUndoWindow = time.Hour
+	setTestPolicy(t, sys, "tenant", testTenantPolicy)
+
+	var err error
+	for _, user := range []string{"alice", "bob", "carol"} {
+		if err = sys.CreateUser(user, madmin.UserInfo{
+			SecretKey: user + "secretkey",
+			Status:    madmin.AccountEnabled,
+		}); err != nil {
+			t.Fatal(err)
+		}
+		if err = sys.PolicyDBSet(user, "tenant", false); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = sys.AddUsersToGroup("devs", []string{"alice", "bob"}); err != nil {
+		t.Fatal(err)
+	}
+
+	groupMembers := func() []string {
+		t.Helper()
+		gd, err := sys.GetGroupDescription("devs")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return gd.Members
+	}
+
+	if err = sys.DeleteUser("alice"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := sys.GetUser("alice"); ok {
+		t.Fatal("expected alice to be deleted")
+	}
+	if members := groupMembers(); len(members) != 1 || members[0] != "bob" {
+		t.Fatalf("expected bob to be the only member, got %v", members)
+	}
+
+	results, err := sys.DeleteUsers([]string{"bob", "carol", "nobody"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if results["bob"] != nil || results["carol"] != nil || !errors.Is(results["nobody"], errNoSuchUser) {
+		t.Fatalf("unexpected results %v", results)
+	}
+	if members := groupMembers(); len(members) != 0 {
+		t.Fatalf("expected no members, got %v", members)
+	}
+
+	// Restored users get their policies and memberships back, once.
+	if err = sys.RestoreUser("alice"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := sys.GetUser("alice"); !ok {
+		t.Fatal("expected alice to be restored")
+	}
+	policies, err := sys.PolicyDBGet("alice", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(policies) != 1 || policies[0] != "tenant" {
+		t.Fatalf("expected the tenant policy, got %v", policies)
+	}
+	if members := groupMembers(); len(members) != 1 || members[0] != "alice" {
+		t.Fatalf("expected alice to be the only member, got %v", members)
+	}
+	if err = sys.RestoreUser("alice"); !errors.Is(err, errNoSuchUser) {
+		t.Fatalf("expected %v, got %v", errNoSuchUser, err)
+	}
+	if err = sys.RestoreUser("nobody"); !errors.Is(err, errNoSuchUser) {
+		t.Fatalf("expected %v, got %v", errNoSuchUser, err)
+	}
+
+	// Tombstones past the undo window are purged.
+	ctx := context.Background()
+	tombstones, err := loadUserTombstones(ctx, sys.store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bob := tombstones["bob"]
+	bob.DeletedAt = UTCNow().Add(-2 * time.Hour)
+	tombstones["bob"] = bob
+	if err = saveUserTombstones(ctx, sys.store, tombstones); err != nil {
+		t.Fatal(err)
+	}
+	if err = sys.purgeExpiredUserTombstones(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if tombstones, err = loadUserTombstones(ctx, sys.store); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := tombstones["bob"]; ok {
+		t.Fatal("expected the tombstone of bob to be purged")
+	}
+	if err = sys.RestoreUser("bob"); !errors.Is(err, errNoSuchUser) {
+		t.Fatalf("expected %v, got %v", errNoSuchUser, err)
+	}
+	if err = sys.RestoreUser("carol"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestIAMSetUserSecretKeyLength(t *testing.T) {
+	sys := newTestIAMSys(t)
+
+	var err error
+	if err = sys.CreateUser("alice", madmin.UserInfo{
+		SecretKey: "alicesecretkey",
+		Status:    madmin.AccountEnabled,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		secretKey string
+		expected  error
+	}{
+		{"", auth.ErrInvalidSecretKeyLength},
+		// One shorter than the minimum of 8 characters.
+		{strings.Repeat("a", 7), auth.ErrInvalidSecretKeyLength},
+		{strings.Repeat("b", 8), nil},
+		// Generated secret keys have 40 characters, chosen ones
+		// may be longer.
+		{strings.Repeat("c", 40), nil},
+		{strings.Repeat("d", 41), nil},
+	}
+
+	for i, testCase := range testCases {
+		err = sys.SetUserSecretKey("alice", testCase.secretKey)
+		if !errors.Is(err, testCase.expected) {
+			t.Fatalf("test %d: expected %v, got %v", i+1, testCase.expected, err)
+		}
+		if err != nil {
+			continue
+		}
+
+		// The new secret key is saved in the store.
+		if err = sys.store.loadAll(context.Background(), sys); err != nil {
+			t.Fatal(err)
+		}
+		cred, ok := sys.GetUser("alice")
+		if !ok || cred.SecretKey != testCase.secretKey {
+			t.Fatalf("test %d: expected the secret key of %d characters to be set", i+1, len(testCase.secretKey))
+		}
+	}
+}
+
+func TestIAMMemoryStoreTTL(t *testing.T) {
+	store := NewInMemoryIAMStore()
+	ctx := context.Background()
+
+	u := newUserIdentity(auth.Credentials{AccessKey: "alice", SecretKey: "alicesecretkey"})
+	if err := store.saveUserIdentity(ctx, "alice", regularUser, u); err != nil {
+		t.Fatal(err)
+	}
+	sts := newUserIdentity(auth.Credentials{AccessKey: "temp", SecretKey: "tempsecretkey", ParentUser: "alice"})
+	if err := store.saveUserIdentity(ctx, "temp", stsUser, sts, options{ttl: 3600}); err != nil {
+		t.Fatal(err)
+	}
+
+	path := getUserIdentityPath("temp", stsUser)
+	store.mu.Lock()
+	it := store.items[path]
+	store.mu.Unlock()
+	if it.expiry.IsZero() || it.expiry.After(UTCNow().Add(time.Hour)) {
+		t.Fatalf("expected the item to expire within an hour, got %v", it.expiry)
+	}
+	if _, err := store.getUserIdentity(ctx, "temp", stsUser); err != nil {
+		t.Fatal(err)
+	}
+
+	// Expired items are gone, others are kept.
+	store.mu.Lock()
+	it.expiry = UTCNow().Add(-time.Second)
+	store.items[path] = it
+	store.mu.Unlock()
+
+	var loaded UserIdentity
+	if err := store.loadIAMConfig(ctx, &loaded, path); !errors.Is(err, errConfigNotFound) {
+		t.Fatalf("expected %v, got %v", errConfigNotFound, err)
+	}
+	store.mu.Lock()
+	_, ok := store.items[path]
+	store.mu.Unlock()
+	if ok {
+		t.Fatal("expected the expired item to be removed")
+	}
+	if _, err := store.getUserIdentity(ctx, "alice", regularUser); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// blockedRWLocker - RWLocker held by someone else until ctx is done.
+type blockedRWLocker struct{}
+
+func (blockedRWLocker) GetLock(ctx context.Context, timeout *DynamicTimeout) (context.Context, error) {
+	<-ctx.Done()
+	return ctx, ctx.Err()
+}
+
+func (blockedRWLocker) Unlock() {}
+
+func (l blockedRWLocker) GetRLock(ctx context.Context, timeout *DynamicTimeout) (context.Context, error) {
+	return l.GetLock(ctx, timeout)
+}
+
+func (blockedRWLocker) RUnlock() {}
+
+func TestIAMStoreLockTimeout(t *testing.T) {
+	sys := newTestIAMSys(t)
+	sys.store = &IAMObjectStore{rwLock: blockedRWLocker{}}
+	sys.storeLockTimeout = NewDynamicTimeout(50*time.Millisecond, 50*time.Millisecond)
+
+	if err := sys.lockStore(); !errors.Is(err, errIAMLockTimeout) {
+		t.Fatalf("expected %v, got %v", errIAMLockTimeout, err)
+	}
+	if err := sys.rlockStore(); !errors.Is(err, errIAMLockTimeout) {
+		t.Fatalf("expected %v, got %v", errIAMLockTimeout, err)
+	}
+
+	// Write methods fail instead of waiting for the lock forever.
+	start := time.Now()
+	err := sys.CreateUser("alice", madmin.UserInfo{
+		SecretKey: "alicesecretkey",
+		Status:    madmin.AccountEnabled,
+	})
+	if !errors.Is(err, errIAMLockTimeout) {
+		t.Fatalf("expected %v, got %v", errIAMLockTimeout, err)
+	}
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Fatalf("expected CreateUser to give up after the lock timeout, took %v", elapsed)
+	}
+}
+
+func TestIAMRetryStoreCallers(t *testing.T) {
+	sys := newTestIAMSys(t)
+	flaky := &flakyIAMStore{
+		IAMStorageAPI: sys.store,
+		failures:      1,
+		err:           errErasureWriteQuorum,
+	}
+	store := newIAMRetryStore(flaky, 3)
+	store.backoff = time.Millisecond
+	store.maxBackoff = time.Millisecond
+	sys.store = store
+
+	// A transient failure of the backend does not surface to the
+	// caller.
+	if err := sys.CreateUser("alice", madmin.UserInfo{
+		SecretKey: "alicesecretkey",
+		Status:    madmin.AccountEnabled,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if flaky.attempts != 2 {
+		t.Fatalf("expected 2 attempts, got %v", flaky.attempts)
+	}
+	if _, ok := sys.GetUser("alice"); !ok {
+		t.Fatal("expected alice to be created")
+	}
+
+	// Once the retries are exhausted the error is returned and
+	// nothing changes in memory.
+	flaky.attempts = 0
+	flaky.failures = 10
+	if err := sys.SetUserStatus("alice", madmin.AccountDisabled); !errors.Is(err, errErasureWriteQuorum) {
+		t.Fatalf("expected %v, got %v", errErasureWriteQuorum, err)
+	}
+	if cred, _ := sys.GetUser("alice"); !cred.IsValid() {
+		t.Fatal("expected alice to stay enabled")
+	}
+}
+
+func TestIAMUserMFARequired(t *testing.T) {
+	sys := newTestIAMSys(t)
+	setTestPolicy(t, sys, "tenant", testTenantPolicy)
+
+	var err error
+	for _, user := range []string{"alice", "bob"} {
+		if err = sys.CreateUser(user, madmin.UserInfo{
+			SecretKey: user + "secretkey",
+			Status:    madmin.AccountEnabled,
+		}); err != nil {
+			t.Fatal(err)
+		}
+		if err = sys.PolicyDBSet(user, "tenant", false); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = sys.SetUserMFARequired("alice", true); err != nil {
+		t.Fatal(err)
+	}
+	// The requirement survives a reload.
+	if err = sys.store.loadAll(context.Background(), sys); err != nil {
+		t.Fatal(err)
+	}
+
+	args := func(account string, claims map[string]interface{}) iampolicy.Args {
+		return iampolicy.Args{
+			AccountName: account,
+			Action:      iampolicy.GetObjectAction,
+			BucketName:  "tenant",
+			ObjectName:  "file",
+			Claims:      claims,
+		}
+	}
+
+	testCases := []struct {
+		account string
+		claims  map[string]interface{}
+		allowed bool
+	}{
+		{"alice", nil, false},
+		{"alice", map[string]interface{}{iamAMRClaim: "pwd"}, false},
+		{"alice", map[string]interface{}{iamAMRClaim: iamMFAAMR}, true},
+		{"alice", map[string]interface{}{iamAMRClaim: []interface{}{"pwd", iamMFAAMR}}, true},
+		// Users without the requirement are not affected.
+		{"bob", nil, true},
+	}
+	for i, testCase := range testCases {
+		if allowed := sys.IsAllowed(args(testCase.account, testCase.claims)); allowed != testCase.allowed {
+			t.Fatalf("test %d: expected %v, got %v", i+1, testCase.allowed, allowed)
+		}
+	}
+
+	if err = sys.SetUserMFARequired("alice", false); err != nil {
+		t.Fatal(err)
+	}
+	if !sys.IsAllowed(args("alice", nil)) {
+		t.Fatal("expected alice to be allowed without MFA")
+	}
+
+	// Checking the requirement only takes the read lock, concurrent
+	// access checks do not wait on each other.
+	sys.RLock()
+	done := make(chan bool)
+	go func() {
+		done <- sys.mfaSatisfied(args("alice", nil))
+	}()
+	select {
+	case ok := <-done:
+		if !ok {
+			t.Error("expected alice to be allowed without MFA")
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("expected the MFA check not to wait for the read lock")
+	}
+	sys.RUnlock()
+}
+
+func TestIAMSTSRateLimit(t *testing.T) {
+	sys := newTestIAMSys(t)
+	sys.stsRateLimit = 2
+
+	now := UTCNow()
+	testCases := []struct {
+		parent  string
+		elapsed time.Duration
+		allowed bool
+	}{
+		// Up to the limit at once.
+		{"alice", 0, true},
+		{"alice", 0, true},
+		{"alice", 0, false},
+		// Other parents have their own limit.
+		{"bob", 0, true},
+		// One more credential every 30 seconds at 2 per minute.
+		{"alice", 20 * time.Second, false},
+		{"alice", 40 * time.Second, true},
+		{"alice", 40 * time.Second, false},
+	}
+	for i, testCase := range testCases {
+		sys.Lock()
+		allowed := sys.allowSTSCredential(testCase.parent, now.Add(testCase.elapsed))
+		sys.Unlock()
+		if allowed != testCase.allowed {
+			t.Fatalf("test %d: expected %v, got %v", i+1, testCase.allowed, allowed)
+		}
+	}
+
+	// SetTempUser refuses credentials past the limit.
+	cred := auth.Credentials{
+		AccessKey:  "tempaccesskey",
+		SecretKey:  "tempsecretkey",
+		Expiration: UTCNow().Add(time.Hour),
+		ParentUser: "alice",
+	}
+	if _, err := sys.SetTempUser(cred.AccessKey, cred, ""); !errors.Is(err, errSTSRateLimited) {
+		t.Fatalf("expected %v, got %v", errSTSRateLimited, err)
+	}
+
+	// Zero means unlimited.
+	sys.stsRateLimit = 0
+	sys.Lock()
+	allowed := sys.allowSTSCredential("alice", now)
+	sys.Unlock()
+	if !allowed {
+		t.Fatal("expected no limit")
+	}
+}
+
+func TestIAMPolicyUsageReadOnlyEvaluation(t *testing.T) {
+	sys := newTestIAMSys(t)
+	setTestPolicy(t, sys, "tenant", testTenantPolicy)
+	setTestPolicy(t, sys, "service", testTenantPolicy)
+
+	var err error
+	for _, user := range []string{"alice", "bob"} {
+		if err = sys.CreateUser(user, madmin.UserInfo{
+			SecretKey: user + "secretkey",
+			Status:    madmin.AccountEnabled,
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = sys.PolicyDBSet("alice", "tenant", false); err != nil {
+		t.Fatal(err)
+	}
+	if err = sys.PolicyDBSet("bob", "service", false); err != nil {
+		t.Fatal(err)
+	}
+	svc, err := sys.NewServiceAccount(context.Background(), "bob", nil, newServiceAccountOpts{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	args := iampolicy.Args{
+		AccountName: "alice",
+		Action:      iampolicy.GetObjectAction,
+		BucketName:  "tenant",
+		ObjectName:  "file",
+	}
+	svcArgs := args
+	svcArgs.AccountName = svc.AccessKey
+	svcArgs.Claims = map[string]interface{}{
+		parentClaim:            "bob",
+		iamPolicyClaimNameSA(): "inherited-policy",
+	}
+
+	notUsed := func(names ...string) {
+		t.Helper()
+		for _, name := range names {
+			if _, ok := sys.PolicyLastUsed(name); ok {
+				t.Fatalf("expected %s not to be marked as used", name)
+			}
+		}
+	}
+
+	// Explaining, simulating and probing access is read-only.
+	for _, a := range []iampolicy.Args{args, svcArgs} {
+		allowed, _, _, err := sys.ExplainAccess(a)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !allowed {
+			t.Fatalf("%s: expected the request to be allowed", a.AccountName)
+		}
+		if results := sys.IsAllowedBatch(a, []iampolicy.Args{a}); !results[0] {
+			t.Fatalf("%s: expected the variant to be allowed", a.AccountName)
+		}
+	}
+	p, err := iampolicy.ParseConfig(strings.NewReader(testTenantPolicy))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = sys.SimulatePolicy(*p, []iampolicy.Args{args}, "tenant"); err != nil {
+		t.Fatal(err)
+	}
+	notUsed("tenant", "service")
+
+	// Authorizing requests does mark them as used.
+	if !sys.IsAllowed(args) || !sys.IsAllowed(svcArgs) {
+		t.Fatal("expected the requests to be allowed")
+	}
+	for _, name := range []string{"tenant", "service"} {
+		if _, ok := sys.PolicyLastUsed(name); !ok {
+			t.Fatalf("expected %s to be marked as used", name)
+		}
+	}
+}
+
+func TestIAMDiffPolicies(t *testing.T) {
+	const conditionPolicy = `{
+  "Version": "2012-10-17",
+  "Statement": [
+    {
+      "Effect": "Allow",
+      "Action": ["s3:ListBucket"],
+      "Resource": ["arn:aws:s3:::tenant"],
+      "Condition": {
+        "StringEquals": {"s3:prefix": ["a", "b", "c", "d", "e", "f"]},
+        "IpAddress": {"aws:SourceIp": ["10.0.0.0/8", "192.168.0.0/16"]}
+      }
+    }
+  ]
+}`
+
+	sys := newTestIAMSys(t)
+	setTestPolicy(t, sys, "first", conditionPolicy)
+	setTestPolicy(t, sys, "second", conditionPolicy)
+	setTestPolicy(t, sys, "tenant", testTenantPolicy)
+
+	// Conditions with several values compare equal whatever the order
+	// their value sets are iterated in.
+	for i := 0; i < 20; i++ {
+		d, err := sys.DiffPolicies("first", "second")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !d.Empty() {
+			t.Fatalf("expected no differences, got %+v", d)
+		}
+	}
+
+	d, err := sys.DiffPolicies("first", "tenant")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(d.Added) != 1 || d.Added[0].Action != "s3:GetObject" || d.Added[0].Conditions != "" {
+		t.Fatalf("expected s3:GetObject to be added, got %+v", d.Added)
+	}
+	if len(d.Removed) != 1 || d.Removed[0].Action != "s3:ListBucket" || d.Removed[0].Conditions == "" {
+		t.Fatalf("expected the conditional s3:ListBucket to be removed, got %+v", d.Removed)
+	}
+
+	if _, err = sys.DiffPolicies("first", "missing"); !errors.Is(err, errNoSuchPolicy) {
+		t.Fatalf("expected %v, got %v", errNoSuchPolicy, err)
+	}
+}
+
+func TestIAMAttachPolicyConcurrent(t *testing.T) {
+	sys := newTestIAMSys(t)
+	policies := []string{"tenant", "p1", "p2", "p3", "p4", "p5", "p6"}
+	for _, name := range policies {
+		setTestPolicy(t, sys, name, testTenantPolicy)
+	}
+	var err error
+	if err = sys.CreateUser("alice", madmin.UserInfo{
+		SecretKey:  "alicesecretkey",
+		PolicyName: "tenant",
+		Status:     madmin.AccountEnabled,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Another server sharing the store, its cache goes stale as soon
+	// as the first server attaches a policy.
+	other := NewIAMSys()
+	other.InitStoreWith(testMemoryStore(t, sys))
+	if err = other.store.loadAll(context.Background(), other); err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	errs := make([]error, len(policies))
+	for i, name := range policies[1:] {
+		s := sys
+		if i%2 == 1 {
+			s = other
+		}
+		wg.Add(1)
+		go func(i int, s *IAMSys, name string) {
+			defer wg.Done()
+			errs[i] = s.AttachPolicy("alice", false, name)
+		}(i, s, name)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// No attach was lost, whichever server made it.
+	mp, err := sys.store.getMappedPolicy(context.Background(), "alice", regularUser, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := mp.policySet(); !got.Equals(set.CreateStringSet(policies...)) {
+		t.Fatalf("expected %v to be attached, got %v", policies, got.ToSlice())
+	}
+
+	// Detaching from a stale cache keeps the attaches of others.
+	if err = other.DetachPolicy("alice", false, "p1"); err != nil {
+		t.Fatal(err)
+	}
+	if mp, err = sys.store.getMappedPolicy(context.Background(), "alice", regularUser, false); err != nil {
+		t.Fatal(err)
+	}
+	if got := mp.policySet(); !got.Equals(set.CreateStringSet(policies...).Difference(set.CreateStringSet("p1"))) {
+		t.Fatalf("expected only p1 to be detached, got %v", got.ToSlice())
+	}
+}
+
+// expiredLoadIAMStore loads the given service account as it was before
+// it expired, as if it expired while Load was running.
+type expiredLoadIAMStore struct {
+	*IAMMemoryStore
+	expired UserIdentity
+}
+
+func (s expiredLoadIAMStore) loadUserIdentities(ctx context.Context, userType IAMUserType, m map[string]UserIdentity) error {
+	if userType == srvAccUser {
+		m[s.expired.Credentials.AccessKey] = s.expired
+		return nil
+	}
+	return s.IAMMemoryStore.loadUserIdentities(ctx, userType, m)
+}
+
+func TestIAMLoadPurgesExpiredServiceAccounts(t *testing.T) {
+	sys := newTestIAMSys(t)
+	setTestPolicy(t, sys, "tenant", testTenantPolicy)
+
+	ctx := context.Background()
+	var err error
+	if err = sys.CreateUser("alice", madmin.UserInfo{
+		SecretKey: "alicesecretkey",
+		Status:    madmin.AccountEnabled,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// A service account which expired since it was listed, with a
+	// policy mapping of its own.
+	store := testMemoryStore(t, sys)
+	u := newUserIdentity(auth.Credentials{
+		AccessKey:      "expiredsvc",
+		SecretKey:      "expiredsvcsecret",
+		ParentUser:     "alice",
+		Status:         "on",
+		Expiration:     UTCNow().Add(-time.Minute),
+		ServiceAccount: true,
+	})
+	if err = store.saveIAMConfig(ctx, u, getUserIdentityPath("expiredsvc", srvAccUser)); err != nil {
+		t.Fatal(err)
+	}
+	if err = store.saveMappedPolicy(ctx, "expiredsvc", srvAccUser, false, newMappedPolicy("tenant")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = sys.Load(ctx, expiredLoadIAMStore{IAMMemoryStore: store, expired: u}); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := sys.GetUser("expiredsvc"); ok {
+		t.Fatal("expected the expired service account not to be loaded")
+	}
+
+	// The identity and the policy mapping are deleted after Load.
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		store.mu.Lock()
+		_, identity := store.items[getUserIdentityPath("expiredsvc", srvAccUser)]
+		_, mapping := store.items[getMappedPolicyPath("expiredsvc", srvAccUser, false)]
+		store.mu.Unlock()
+		if !identity && !mapping {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the expired service account to be purged, identity %v, mapping %v", identity, mapping)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestIAMDeleteGroupForce(t *testing.T) {
+	sys := newTestIAMSys(t)
+	setTestPolicy(t, sys, "tenant", testTenantPolicy)
+
+	var err error
+	for _, user := range []string{"alice", "bob"} {
+		if err = sys.CreateUser(user, madmin.UserInfo{
+			SecretKey: user + "secretkey",
+			Status:    madmin.AccountEnabled,
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = sys.AddUsersToGroup("devs", []string{"alice", "bob"}); err != nil {
+		t.Fatal(err)
+	}
+	if err = sys.PolicyDBSet("devs", "tenant", true); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = sys.DeleteGroup("devs", false); err != errGroupNotEmpty {
+		t.Fatalf("expected %v, got %v", errGroupNotEmpty, err)
+	}
+	if err = sys.DeleteGroup("missing", true); err != errNoSuchGroup {
+		t.Fatalf("expected %v, got %v", errNoSuchGroup, err)
+	}
+
+	if err = sys.DeleteGroup("devs", true); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = sys.GetGroupDescription("devs"); err != errNoSuchGroup {
+		t.Fatalf("expected %v, got %v", errNoSuchGroup, err)
+	}
+	for _, user := range []string{"alice", "bob"} {
+		u, err := sys.GetUserInfo(user)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(u.MemberOf) != 0 {
+			t.Fatalf("expected %s not to be a member of any group, got %v", user, u.MemberOf)
+		}
+	}
+
+	// Both the group and its policy mapping are deleted from the store.
+	store := testMemoryStore(t, sys)
+	for _, p := range []string{getGroupInfoPath("devs"), getMappedPolicyPath("devs", regularUser, true)} {
+		store.mu.Lock()
+		_, ok := store.items[p]
+		store.mu.Unlock()
+		if ok {
+			t.Fatalf("expected %s to be deleted", p)
+		}
+	}
+	if err = sys.store.loadAll(context.Background(), sys); err != nil {
+		t.Fatal(err)
+	}
+	groups, err := sys.ListGroups()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(groups) != 0 {
+		t.Fatalf("expected no groups after a reload, got %v", groups)
+	}
+}
+
+func TestIAMRevokeAllSTS(t *testing.T) {
+	sys := newTestIAMSys(t)
+	setTestPolicy(t, sys, "tenant", testTenantPolicy)
+
+	ctx := context.Background()
+	var err error
+	for _, user := range []string{"alice", "bob"} {
+		if err = sys.CreateUser(user, madmin.UserInfo{
+			SecretKey: user + "secretkey",
+			Status:    madmin.AccountEnabled,
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, cred := range []auth.Credentials{
+		{AccessKey: "alicests1", ParentUser: "alice"},
+		{AccessKey: "alicests2", ParentUser: "alice"},
+		{AccessKey: "bobsts", ParentUser: "bob"},
+	} {
+		cred.SecretKey = cred.AccessKey + "secret"
+		cred.SessionToken = cred.AccessKey + "token"
+		cred.Expiration = UTCNow().Add(time.Hour)
+		cred.Status = "on"
+		if _, err = sys.SetTempUser(cred.AccessKey, cred, "tenant"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	stsSvc, err := sys.NewServiceAccount(ctx, "alicests1", nil, newServiceAccountOpts{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	aliceSvc, err := sys.NewServiceAccount(ctx, "alice", nil, newServiceAccountOpts{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = sys.RevokeAllSTS(""); err != errInvalidArgument {
+		t.Fatalf("expected %v, got %v", errInvalidArgument, err)
+	}
+
+	// The temporary credentials of alice and the service accounts they
+	// created are revoked, nothing else.
+	revoked, err := sys.RevokeAllSTS("alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if revoked != 3 {
+		t.Fatalf("expected 3 revoked credentials, got %d", revoked)
+	}
+	for _, accessKey := range []string{"alicests1", "alicests2", stsSvc.AccessKey} {
+		if _, ok := sys.GetUser(accessKey); ok {
+			t.Fatalf("expected %s to be revoked", accessKey)
+		}
+	}
+	for _, accessKey := range []string{"alice", "bob", "bobsts", aliceSvc.AccessKey} {
+		if _, ok := sys.GetUser(accessKey); !ok {
+			t.Fatalf("expected %s to be kept", accessKey)
+		}
+	}
+	store := testMemoryStore(t, sys)
+	for _, p := range []string{
+		getUserIdentityPath("alicests1", stsUser),
+		getMappedPolicyPath("alicests1", stsUser, false),
+		getUserIdentityPath(stsSvc.AccessKey, srvAccUser),
+	} {
+		store.mu.Lock()
+		_, ok := store.items[p]
+		store.mu.Unlock()
+		if ok {
+			t.Fatalf("expected %s to be deleted", p)
+		}
+	}
+
+	// The own service accounts of alice are only revoked on request.
+	if revoked, err = sys.RevokeAllSTSWithOpts(ctx, "alice", revokeSTSOpts{serviceAccounts: true}); err != nil {
+		t.Fatal(err)
+	}
+	if revoked != 1 {
+		t.Fatalf("expected 1 revoked credential, got %d", revoked)
+	}
+	if _, ok := sys.GetUser(aliceSvc.AccessKey); ok {
+		t.Fatalf("expected %s to be revoked", aliceSvc.AccessKey)
+	}
+}
+
+// failingGroupStore - fails saves of the group info of group with err.
+type failingGroupStore struct {
+	IAMStorageAPI
+	group string
+	err   error
+}
+
+func (s *failingGroupStore) saveGroupInfo(ctx context.Context, group string, gi GroupInfo) error {
+	if group == s.group {
+		return s.err
+	}
+	return s.IAMStorageAPI.saveGroupInfo(ctx, group, gi)
+}
+
+func TestIAMDeleteUsers(t *testing.T) {
+	sys := newTestIAMSys(t)
+	setTestPolicy(t, sys, "tenant", testTenantPolicy)
+
+	ctx := context.Background()
+	var err error
+	for _, user := range []string{"alice", "bob", "carol"} {
+		if err = sys.CreateUser(user, madmin.UserInfo{
+			SecretKey:  user + "secretkey",
+			PolicyName: "tenant",
+			Status:     madmin.AccountEnabled,
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = sys.AddUsersToGroup("devs", []string{"alice", "carol"}); err != nil {
+		t.Fatal(err)
+	}
+	if err = sys.AddUsersToGroup("ops", []string{"bob"}); err != nil {
+		t.Fatal(err)
+	}
+	svc, err := sys.NewServiceAccount(ctx, "alice", nil, newServiceAccountOpts{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sts := auth.Credentials{
+		AccessKey:    "alicests",
+		SecretKey:    "alicestssecret",
+		SessionToken: "aliceststoken",
+		Expiration:   UTCNow().Add(time.Hour),
+		ParentUser:   "alice",
+		Status:       "on",
+	}
+	if _, err = sys.SetTempUser(sts.AccessKey, sts, "tenant"); err != nil {
+		t.Fatal(err)
+	}
+
+	// bob can not be removed from ops and is kept, the others are
+	// deleted.
+	store := sys.store
+	sys.store = &failingGroupStore{IAMStorageAPI: store, group: "ops", err: errErasureWriteQuorum}
+	results, err := sys.DeleteUsers([]string{"alice", "bob", "alicests", "nobody"})
+	sys.store = store
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 4 || results["alice"] != nil || !errors.Is(results["bob"], errErasureWriteQuorum) ||
+		results["alicests"] != errNoSuchUser || results["nobody"] != errNoSuchUser {
+		t.Fatalf("unexpected results %v", results)
+	}
+
+	ms := testMemoryStore(t, sys)
+	for _, p := range []string{
+		getUserIdentityPath("alice", regularUser),
+		getMappedPolicyPath("alice", regularUser, false),
+		getUserIdentityPath(svc.AccessKey, srvAccUser),
+		getUserIdentityPath(sts.AccessKey, stsUser),
+		getMappedPolicyPath(sts.AccessKey, stsUser, false),
+	} {
+		ms.mu.Lock()
+		_, ok := ms.items[p]
+		ms.mu.Unlock()
+		if ok {
+			t.Fatalf("expected %s to be deleted", p)
+		}
+	}
+
+	// Reloading shows what was deleted from the store.
+	if err = sys.store.loadAll(ctx, sys); err != nil {
+		t.Fatal(err)
+	}
+	for _, accessKey := range []string{"alice", svc.AccessKey, sts.AccessKey} {
+		if _, ok := sys.GetUser(accessKey); ok {
+			t.Fatalf("expected %s to be deleted", accessKey)
+		}
+	}
+	for _, accessKey := range []string{"bob", "carol"} {
+		if _, ok := sys.GetUser(accessKey); !ok {
+			t.Fatalf("expected %s to be kept", accessKey)
+		}
+	}
+	if _, err = sys.PolicyDBGet("alice", false); err == nil {
+		t.Fatal("expected the policy mapping of alice to be deleted")
+	}
+	for group, members := range map[string][]string{"devs": {"carol"}, "ops": {"bob"}} {
+		gd, err := sys.GetGroupDescription(group)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(gd.Members) != len(members) || gd.Members[0] != members[0] {
+			t.Fatalf("expected %s to have members %v, got %v", group, members, gd.Members)
+		}
+	}
+}
+
+func TestIAMSetGroupMembers(t *testing.T) {
+	sys := newTestIAMSys(t)
+
+	var err error
+	for _, user := range []string{"alice", "bob", "carol"} {
+		if err = sys.CreateUser(user, madmin.UserInfo{
+			SecretKey: user + "secretkey",
+			Status:    madmin.AccountEnabled,
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	sts := auth.Credentials{
+		AccessKey:    "alicests",
+		SecretKey:    "alicestssecret",
+		SessionToken: "aliceststoken",
+		Expiration:   UTCNow().Add(time.Hour),
+		ParentUser:   "alice",
+		Status:       "on",
+	}
+	if _, err = sys.SetTempUser(sts.AccessKey, sts, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	members := func() set.StringSet {
+		t.Helper()
+		gd, err := sys.GetGroupDescription("devs")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return set.CreateStringSet(gd.Members...)
+	}
+	memberOf := func(user string) []string {
+		t.Helper()
+		u, err := sys.GetUserInfo(user)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return u.MemberOf
+	}
+
+	// The group is created enabled.
+	if err = sys.SetGroupMembers("devs", []string{"alice", "bob"}); err != nil {
+		t.Fatal(err)
+	}
+	gd, err := sys.GetGroupDescription("devs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gd.Status != statusEnabled {
+		t.Fatalf("expected the group to be enabled, got %s", gd.Status)
+	}
+	if got := members(); !got.Equals(set.CreateStringSet("alice", "bob")) {
+		t.Fatalf("expected alice and bob, got %v", got)
+	}
+
+	// Invalid members leave the group unchanged.
+	if err = sys.SetGroupMembers("devs", []string{"carol", "nobody"}); err != errNoSuchUser {
+		t.Fatalf("expected %v, got %v", errNoSuchUser, err)
+	}
+	if err = sys.SetGroupMembers("devs", []string{"carol", sts.AccessKey}); err != errIAMActionNotAllowed {
+		t.Fatalf("expected %v, got %v", errIAMActionNotAllowed, err)
+	}
+	if got := members(); !got.Equals(set.CreateStringSet("alice", "bob")) {
+		t.Fatalf("expected alice and bob, got %v", got)
+	}
+
+	// Replacing the members keeps the status of the group and drops
+	// the membership of departed members.
+	if err = sys.SetGroupStatus("devs", false); err != nil {
+		t.Fatal(err)
+	}
+	if err = sys.SetGroupMembers("devs", []string{"bob", "carol"}); err != nil {
+		t.Fatal(err)
+	}
+	if err = sys.store.loadAll(context.Background(), sys); err != nil {
+		t.Fatal(err)
+	}
+	if gd, err = sys.GetGroupDescription("devs"); err != nil {
+		t.Fatal(err)
+	}
+	if gd.Status != statusDisabled {
+		t.Fatalf("expected the group to stay disabled, got %s", gd.Status)
+	}
+	if got := members(); !got.Equals(set.CreateStringSet("bob", "carol")) {
+		t.Fatalf("expected bob and carol, got %v", got)
+	}
+	if got := memberOf("alice"); len(got) != 0 {
+		t.Fatalf("expected alice not to be a member of any group, got %v", got)
+	}
+
+	// An empty list leaves the group empty.
+	if err = sys.SetGroupMembers("devs", nil); err != nil {
+		t.Fatal(err)
+	}
+	if got := members(); !got.IsEmpty() {
+		t.Fatalf("expected no members, got %v", got)
+	}
+	for _, user := range []string{"bob", "carol"} {
+		if got := memberOf(user); len(got) != 0 {
+			t.Fatalf("expected %s not to be a member of any group, got %v", user, got)
+		}
+	}
+}
+
+func TestIAMRepairOrphanedMappings(t *testing.T) {
+	sys := newTestIAMSys(t)
+	setTestPolicy(t, sys, "tenant", testTenantPolicy)
+
+	ctx := context.Background()
+	var err error
+	if err = sys.CreateUser("alice", madmin.UserInfo{
+		SecretKey:  "alicesecretkey",
+		PolicyName: "tenant",
+		Status:     madmin.AccountEnabled,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err = sys.SetGroupMembers("devs", nil); err != nil {
+		t.Fatal(err)
+	}
+	if err = sys.PolicyDBSet("devs", "tenant", true); err != nil {
+		t.Fatal(err)
+	}
+	sts := auth.Credentials{
+		AccessKey:    "alicests",
+		SecretKey:    "alicestssecret",
+		SessionToken: "aliceststoken",
+		Expiration:   UTCNow().Add(time.Hour),
+		ParentUser:   "alice",
+		Status:       "on",
+	}
+	if _, err = sys.SetTempUser(sts.AccessKey, sts, "tenant"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Mappings left behind by users and groups deleted elsewhere.
+	store := testMemoryStore(t, sys)
+	orphans := []string{
+		getMappedPolicyPath("ghost", regularUser, false),
+		getMappedPolicyPath("ghoststs", stsUser, false),
+		getMappedPolicyPath("ghosts", regularUser, true),
+	}
+	for _, m := range []struct {
+		name     string
+		userType IAMUserType
+		isGroup  bool
+	}{
+		{"ghost", regularUser, false},
+		{"ghoststs", stsUser, false},
+		{"ghosts", regularUser, true},
+	} {
+		if err = store.saveMappedPolicy(ctx, m.name, m.userType, m.isGroup, newMappedPolicy("tenant")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = sys.store.loadAll(ctx, sys); err != nil {
+		t.Fatal(err)
+	}
+
+	removed, err := sys.RepairOrphanedMappings(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if removed != 3 {
+		t.Fatalf("expected 3 removed mappings, got %d", removed)
+	}
+	for _, p := range orphans {
+		store.mu.Lock()
+		_, ok := store.items[p]
+		store.mu.Unlock()
+		if ok {
+			t.Fatalf("expected %s to be deleted", p)
+		}
+	}
+
+	// The mappings of existing users, temporary users and groups are
+	// kept.
+	for _, m := range []struct {
+		name    string
+		isGroup bool
+	}{
+		{"alice", false},
+		{sts.AccessKey, false},
+		{"devs", true},
+	} {
+		policies, err := sys.PolicyDBGet(m.name, m.isGroup)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(policies) != 1 || policies[0] != "tenant" {
+			t.Fatalf("expected %s to be mapped to tenant, got %v", m.name, policies)
+		}
+	}
+
+	if removed, err = sys.RepairOrphanedMappings(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if removed != 0 {
+		t.Fatalf("expected nothing to be removed, got %d", removed)
+	}
+}
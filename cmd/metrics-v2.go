@@ -55,6 +55,7 @@ const (
 	diskSubsystem             MetricSubsystem = "disk"
 	fileDescriptorSubsystem   MetricSubsystem = "file_descriptor"
 	goRoutines                MetricSubsystem = "go_routine"
+	iamSubsystem              MetricSubsystem = "iam"
 	ioSubsystem               MetricSubsystem = "io"
 	nodesSubsystem            MetricSubsystem = "nodes"
 	objectsSubsystem          MetricSubsystem = "objects"
@@ -89,10 +90,13 @@ const (
 	onlineTotal    MetricName = "online_total"
 	openTotal      MetricName = "open_total"
 	readTotal      MetricName = "read_total"
+	reloadsTotal   MetricName = "reloads_total"
 	timestampTotal MetricName = "timestamp_total"
 	writeTotal     MetricName = "write_total"
 	total          MetricName = "total"
 
+	policyEvaluationsTotal MetricName = "policy_evaluations_total"
+
 	failedCount   MetricName = "failed_count"
 	failedBytes   MetricName = "failed_bytes"
 	freeBytes     MetricName = "free_bytes"
@@ -242,6 +246,7 @@ func GetGeneratorsForPeer() []MetricsGenerator {
 		getCacheMetrics,
 		getGoMetrics,
 		getHTTPMetrics,
+		getIAMMetrics,
 		getLocalStorageMetrics,
 		getMinioProcMetrics,
 		getMinioVersionMetrics,
@@ -257,6 +262,7 @@ func GetSingleNodeGenerators() []MetricsGenerator {
 		getNodeHealthMetrics,
 		getCacheMetrics,
 		getHTTPMetrics,
+		getIAMMetrics,
 		getNetworkMetrics,
 		getMinioVersionMetrics,
 		getS3TTFBMetric,
@@ -820,6 +826,42 @@ func getMinIOProcessUptimeMD() MetricDescription {
 		Type:      gaugeMetric,
 	}
 }
+func getIAMCacheHitsTotalMD() MetricDescription {
+	return MetricDescription{
+		Namespace: nodeMetricNamespace,
+		Subsystem: iamSubsystem,
+		Name:      hitsTotal,
+		Help:      "Total number of IAM user lookups served from memory",
+		Type:      counterMetric,
+	}
+}
+func getIAMCacheMissedTotalMD() MetricDescription {
+	return MetricDescription{
+		Namespace: nodeMetricNamespace,
+		Subsystem: iamSubsystem,
+		Name:      missedTotal,
+		Help:      "Total number of IAM user lookups which triggered a load from the store",
+		Type:      counterMetric,
+	}
+}
+func getIAMPolicyEvaluationsTotalMD() MetricDescription {
+	return MetricDescription{
+		Namespace: nodeMetricNamespace,
+		Subsystem: iamSubsystem,
+		Name:      policyEvaluationsTotal,
+		Help:      "Total number of IAM policy evaluations",
+		Type:      counterMetric,
+	}
+}
+func getIAMReloadsTotalMD() MetricDescription {
+	return MetricDescription{
+		Namespace: nodeMetricNamespace,
+		Subsystem: iamSubsystem,
+		Name:      reloadsTotal,
+		Help:      "Total number of full reloads of the IAM sub-system",
+		Type:      counterMetric,
+	}
+}
 func getMinioProcMetrics() MetricsGroup {
 	return MetricsGroup{
 		id:         "MinioProcMetrics",
@@ -1084,6 +1126,38 @@ func getObjectsScanned(seq *healSequence) (m []Metric) {
 	}
 	return
 }
+func getIAMMetrics() MetricsGroup {
+	return MetricsGroup{
+		id:         "IAMMetrics",
+		cachedRead: cachedRead,
+		read: func(ctx context.Context) (metrics []Metric) {
+			// Service not initialized yet
+			if globalIAMSys == nil {
+				return
+			}
+			stats := &globalIAMSys.stats
+			metrics = make([]Metric, 0, 4)
+			metrics = append(metrics, Metric{
+				Description: getIAMCacheHitsTotalMD(),
+				Value:       float64(stats.getCacheHits()),
+			})
+			metrics = append(metrics, Metric{
+				Description: getIAMCacheMissedTotalMD(),
+				Value:       float64(stats.getCacheMisses()),
+			})
+			metrics = append(metrics, Metric{
+				Description: getIAMPolicyEvaluationsTotalMD(),
+				Value:       float64(stats.getPolicyEvaluations()),
+			})
+			metrics = append(metrics, Metric{
+				Description: getIAMReloadsTotalMD(),
+				Value:       float64(stats.getReloads()),
+			})
+			return
+		},
+	}
+}
+
 func getCacheMetrics() MetricsGroup {
 	return MetricsGroup{
 		id:         "CacheMetrics",
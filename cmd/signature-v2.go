@@ -83,7 +83,9 @@ func doesPolicySignatureV2Match(formValues http.Header) (auth.Credentials, APIEr
 	}
 	policy := formValues.Get("Policy")
 	signature := formValues.Get(xhttp.AmzSignatureV2)
-	if !compareSignatureV2(signature, calculateSignatureV2(policy, cred.SecretKey)) {
+	if _, ok := matchSecretKey(cred, func(secretKey string) bool {
+		return compareSignatureV2(signature, calculateSignatureV2(policy, secretKey))
+	}); !ok {
 		return cred, ErrSignatureDoesNotMatch
 	}
 	return cred, ErrNone
@@ -173,8 +175,12 @@ func doesPresignV2SignatureMatch(r *http.Request) APIErrorCode {
 		return ErrInvalidRequest
 	}
 
-	expectedSignature := preSignatureV2(cred, r.Method, encodedResource, strings.Join(filteredQueries, "&"), r.Header, expires)
-	if !compareSignatureV2(gotSignature, expectedSignature) {
+	if _, ok := matchSecretKey(cred, func(secretKey string) bool {
+		cred := cred
+		cred.SecretKey = secretKey
+		expectedSignature := preSignatureV2(cred, r.Method, encodedResource, strings.Join(filteredQueries, "&"), r.Header, expires)
+		return compareSignatureV2(gotSignature, expectedSignature)
+	}); !ok {
 		return ErrSignatureDoesNotMatch
 	}
 
@@ -272,8 +278,12 @@ func doesSignV2Match(r *http.Request) APIErrorCode {
 		return ErrSignatureDoesNotMatch
 	}
 	v2Auth = v2Auth[len(prefix):]
-	expectedAuth := signatureV2(cred, r.Method, encodedResource, strings.Join(unescapedQueries, "&"), r.Header)
-	if !compareSignatureV2(v2Auth, expectedAuth) {
+	if _, ok := matchSecretKey(cred, func(secretKey string) bool {
+		cred := cred
+		cred.SecretKey = secretKey
+		expectedAuth := signatureV2(cred, r.Method, encodedResource, strings.Join(unescapedQueries, "&"), r.Header)
+		return compareSignatureV2(v2Auth, expectedAuth)
+	}); !ok {
 		return ErrSignatureDoesNotMatch
 	}
 	return ErrNone
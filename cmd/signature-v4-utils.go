@@ -124,8 +124,11 @@ func checkKeyValid(accessKey string) (auth.Credentials, bool, APIErrorCode) {
 	var cred = globalActiveCred
 	if cred.AccessKey != accessKey {
 		// Check if the access key is part of users credentials.
-		var ok bool
-		if cred, ok = globalIAMSys.GetUser(accessKey); !ok {
+		var err error
+		if cred, err = globalIAMSys.GetUserWithErr(GlobalContext, accessKey); err != nil {
+			if err == errIAMTemporarilyUnavailable {
+				return cred, false, ErrSlowDown
+			}
 			return cred, false, ErrInvalidAccessKeyID
 		}
 		owner = false
@@ -133,6 +136,29 @@ func checkKeyValid(accessKey string) (auth.Credentials, bool, APIErrorCode) {
 	return cred, owner, ErrNone
 }
 
+// matchSecretKey - returns the secret key of the given credentials for
+// which match succeeds. The previous secret key of a user is tried as
+// well while a secret key rotation is in progress, and so are its
+// additional secret keys.
+func matchSecretKey(cred auth.Credentials, match func(secretKey string) bool) (string, bool) {
+	if match(cred.SecretKey) {
+		return cred.SecretKey, true
+	}
+	if secretKey, ok := globalIAMSys.GetPreviousSecretKey(cred.AccessKey); ok && match(secretKey) {
+		return secretKey, true
+	}
+
+	// Try every additional secret key, so that the time taken does
+	// not tell which one matched.
+	var matched string
+	for _, secretKey := range globalIAMSys.GetAdditionalSecretKeys(cred.AccessKey) {
+		if match(secretKey) && matched == "" {
+			matched = secretKey
+		}
+	}
+	return matched, matched != ""
+}
+
 // sumHMAC calculate hmac between two input byte array.
 func sumHMAC(key []byte, data []byte) []byte {
 	hash := hmac.New(sha256.New, key)
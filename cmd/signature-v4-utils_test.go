@@ -17,10 +17,15 @@
 package cmd
 
 import (
+	"bytes"
+	"context"
+	"io/ioutil"
 	"net/http"
 	"testing"
+	"time"
 
 	xhttp "github.com/minio/minio/cmd/http"
+	"github.com/minio/minio/pkg/madmin"
 )
 
 // TestSkipContentSha256Cksum - Test validate the logic which decides whether
@@ -272,3 +277,125 @@ func TestGetContentSha256Cksum(t *testing.T) {
 		}
 	}
 }
+
+// TestMatchSecretKey - Test that requests signed with any of the secret
+// keys of a user are accepted, for every signature type.
+func TestMatchSecretKey(t *testing.T) {
+	oldIAMSys := globalIAMSys
+	defer func() { globalIAMSys = oldIAMSys }()
+	globalIAMSys = newTestIAMSys(t)
+
+	const (
+		accessKey  = "alice"
+		primary    = "aliceprimarysecret"
+		previous   = "alicepreviousecret"
+		additional = "aliceadditionalsecret"
+		second     = "alicesecondadditionalsecret"
+		unknown    = "aliceunknownsecret"
+		objectURL  = "http://127.0.0.1:9000/bucket/object"
+	)
+
+	var err error
+	if err = globalIAMSys.CreateUser(accessKey, madmin.UserInfo{
+		SecretKey: previous,
+		Status:    madmin.AccountEnabled,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err = globalIAMSys.RotateUserSecretKey(accessKey, primary, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	for _, secretKey := range []string{additional, second} {
+		if err = globalIAMSys.SetUserSecretKeyWithOpts(accessKey, secretKey, setUserSecretKeyOpts{appendKey: true}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	signers := []struct {
+		name  string
+		check func(secretKey string) (APIErrorCode, error)
+	}{
+		{"V2", func(secretKey string) (APIErrorCode, error) {
+			req, err := newTestSignedRequestV2(http.MethodGet, objectURL, 0, nil, accessKey, secretKey, nil)
+			if err != nil {
+				return ErrNone, err
+			}
+			req.RequestURI = req.URL.RequestURI()
+			return doesSignV2Match(req), nil
+		}},
+		{"V2 presigned", func(secretKey string) (APIErrorCode, error) {
+			req, err := newTestRequest(http.MethodGet, objectURL, 0, nil)
+			if err != nil {
+				return ErrNone, err
+			}
+			if err = preSignV2(req, accessKey, secretKey, UTCNow().Unix()+60); err != nil {
+				return ErrNone, err
+			}
+			req.RequestURI = req.URL.RequestURI()
+			return doesPresignV2SignatureMatch(req), nil
+		}},
+		{"V4", func(secretKey string) (APIErrorCode, error) {
+			req, err := newTestSignedRequestV4(http.MethodGet, objectURL, 0, nil, accessKey, secretKey, nil)
+			if err != nil {
+				return ErrNone, err
+			}
+			return doesSignatureMatch(getContentSha256Cksum(req, serviceS3), req, globalServerRegion, serviceS3), nil
+		}},
+		{"V4 presigned", func(secretKey string) (APIErrorCode, error) {
+			req, err := newTestRequest(http.MethodGet, objectURL, 0, nil)
+			if err != nil {
+				return ErrNone, err
+			}
+			if err = preSignV4(req, accessKey, secretKey, 60); err != nil {
+				return ErrNone, err
+			}
+			return doesPresignedSignatureMatch(getContentSha256Cksum(req, serviceS3), req, globalServerRegion, serviceS3), nil
+		}},
+		{"V4 streaming", func(secretKey string) (APIErrorCode, error) {
+			data := []byte("hello world")
+			req, err := newTestStreamingSignedRequest(http.MethodPut, objectURL, int64(len(data)), 64*1024, bytes.NewReader(data), accessKey, secretKey)
+			if err != nil {
+				return ErrNone, err
+			}
+			rc, errCode := newSignV4ChunkedReader(req)
+			if errCode != ErrNone {
+				return errCode, nil
+			}
+			_, err = ioutil.ReadAll(rc)
+			return toAPIErrorCode(context.Background(), err), nil
+		}},
+	}
+
+	check := func(secretKey string, expected APIErrorCode) {
+		t.Helper()
+		for _, signer := range signers {
+			errCode, err := signer.check(secretKey)
+			if err != nil {
+				t.Fatalf("%s: unable to sign request with %s: %v", signer.name, secretKey, err)
+			}
+			if errCode != expected {
+				t.Fatalf("%s: request signed with %s: expected %s, got %s", signer.name, secretKey, niceError(expected), niceError(errCode))
+			}
+		}
+	}
+
+	// Previous secret key within the grace window.
+	check(primary, ErrNone)
+	check(previous, ErrNone)
+	check(additional, ErrNone)
+	check(second, ErrNone)
+	check(unknown, ErrSignatureDoesNotMatch)
+
+	// Grace window is over.
+	globalIAMSys.Lock()
+	r := globalIAMSys.iamSecretRotations[accessKey]
+	r.expiry = UTCNow().Add(-time.Second)
+	globalIAMSys.iamSecretRotations[accessKey] = r
+	globalIAMSys.Unlock()
+
+	check(primary, ErrNone)
+	check(previous, ErrSignatureDoesNotMatch)
+	check(additional, ErrNone)
+	check(second, ErrNone)
+	check(unknown, ErrSignatureDoesNotMatch)
+}
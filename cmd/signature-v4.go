@@ -185,14 +185,16 @@ func doesPolicySignatureV4Match(formValues http.Header) (auth.Credentials, APIEr
 		return cred, s3Err
 	}
 
-	// Get signing key.
-	signingKey := getSigningKey(cred.SecretKey, credHeader.scope.date, credHeader.scope.region, serviceS3)
+	// Verify signature.
+	if _, ok := matchSecretKey(cred, func(secretKey string) bool {
+		// Get signing key.
+		signingKey := getSigningKey(secretKey, credHeader.scope.date, credHeader.scope.region, serviceS3)
 
-	// Get signature.
-	newSignature := getSignature(signingKey, formValues.Get("Policy"))
+		// Get signature.
+		newSignature := getSignature(signingKey, formValues.Get("Policy"))
 
-	// Verify signature.
-	if !compareSignatureV4(newSignature, formValues.Get(xhttp.AmzSignature)) {
+		return compareSignatureV4(newSignature, formValues.Get(xhttp.AmzSignature))
+	}); !ok {
 		return cred, ErrSignatureDoesNotMatch
 	}
 
@@ -312,15 +314,17 @@ func doesPresignedSignatureMatch(hashedPayload string, r *http.Request, region s
 	// Get string to sign from canonical request.
 	presignedStringToSign := getStringToSign(presignedCanonicalReq, t, pSignValues.Credential.getScope())
 
-	// Get hmac presigned signing key.
-	presignedSigningKey := getSigningKey(cred.SecretKey, pSignValues.Credential.scope.date,
-		pSignValues.Credential.scope.region, stype)
+	// Verify signature.
+	if _, ok := matchSecretKey(cred, func(secretKey string) bool {
+		// Get hmac presigned signing key.
+		presignedSigningKey := getSigningKey(secretKey, pSignValues.Credential.scope.date,
+			pSignValues.Credential.scope.region, stype)
 
-	// Get new signature.
-	newSignature := getSignature(presignedSigningKey, presignedStringToSign)
+		// Get new signature.
+		newSignature := getSignature(presignedSigningKey, presignedStringToSign)
 
-	// Verify signature.
-	if !compareSignatureV4(req.URL.Query().Get(xhttp.AmzSignature), newSignature) {
+		return compareSignatureV4(req.URL.Query().Get(xhttp.AmzSignature), newSignature)
+	}); !ok {
 		return ErrSignatureDoesNotMatch
 	}
 	return ErrNone
@@ -376,15 +380,17 @@ func doesSignatureMatch(hashedPayload string, r *http.Request, region string, st
 	// Get string to sign from canonical request.
 	stringToSign := getStringToSign(canonicalRequest, t, signV4Values.Credential.getScope())
 
-	// Get hmac signing key.
-	signingKey := getSigningKey(cred.SecretKey, signV4Values.Credential.scope.date,
-		signV4Values.Credential.scope.region, stype)
+	// Verify if signature match.
+	if _, ok := matchSecretKey(cred, func(secretKey string) bool {
+		// Get hmac signing key.
+		signingKey := getSigningKey(secretKey, signV4Values.Credential.scope.date,
+			signV4Values.Credential.scope.region, stype)
 
-	// Calculate signature.
-	newSignature := getSignature(signingKey, stringToSign)
+		// Calculate signature.
+		newSignature := getSignature(signingKey, stringToSign)
 
-	// Verify if signature match.
-	if !compareSignatureV4(newSignature, signV4Values.Signature) {
+		return compareSignatureV4(newSignature, signV4Values.Signature)
+	}); !ok {
 		return ErrSignatureDoesNotMatch
 	}
 
@@ -124,21 +124,32 @@ func calculateSeedSignature(r *http.Request) (cred auth.Credentials, signature s
 	// Get string to sign from canonical request.
 	stringToSign := getStringToSign(canonicalRequest, date, signV4Values.Credential.getScope())
 
-	// Get hmac signing key.
-	signingKey := getSigningKey(cred.SecretKey, signV4Values.Credential.scope.date, region, serviceS3)
-
-	// Calculate signature.
-	newSignature := getSignature(signingKey, stringToSign)
-
 	// Verify if signature match.
-	if !compareSignatureV4(newSignature, signV4Values.Signature) {
+	secretKey, ok := matchSecretKey(cred, func(secretKey string) bool {
+		return compareSignatureV4(getSeedSignature(secretKey, signV4Values, region, stringToSign), signV4Values.Signature)
+	})
+	if !ok {
 		return cred, "", "", time.Time{}, ErrSignatureDoesNotMatch
 	}
 
+	// Chunks are signed with the same secret key as the seed.
+	cred.SecretKey = secretKey
+	newSignature := getSeedSignature(secretKey, signV4Values, region, stringToSign)
+
 	// Return caculated signature.
 	return cred, newSignature, region, date, ErrNone
 }
 
+// getSeedSignature - returns the seed signature of stringToSign for
+// the given secret key.
+func getSeedSignature(secretKey string, signV4Values signValues, region, stringToSign string) string {
+	// Get hmac signing key.
+	signingKey := getSigningKey(secretKey, signV4Values.Credential.scope.date, region, serviceS3)
+
+	// Calculate signature.
+	return getSignature(signingKey, stringToSign)
+}
+
 const maxLineLength = 4 * humanize.KiByte // assumed <= bufio.defaultBufSize 4KiB
 
 // lineTooLong is generated as chunk header is bigger than 4KiB.
@@ -21,8 +21,12 @@ import (
 	"bytes"
 	"fmt"
 	"io"
+	"io/ioutil"
+	"net/http"
 	"strings"
 	"testing"
+
+	"github.com/minio/minio/pkg/madmin"
 )
 
 // Test read chunk line.
@@ -194,3 +198,46 @@ func TestParseHexUint(t *testing.T) {
 		}
 	}
 }
+
+// Test streaming requests signed with one of several additional
+// secret keys, every chunk is verified with the matched key.
+func TestStreamingSignatureAdditionalSecretKeys(t *testing.T) {
+	oldIAMSys := globalIAMSys
+	defer func() { globalIAMSys = oldIAMSys }()
+	globalIAMSys = newTestIAMSys(t)
+
+	const accessKey = "alice"
+	var err error
+	if err = globalIAMSys.CreateUser(accessKey, madmin.UserInfo{
+		SecretKey: "aliceprimarysecret",
+		Status:    madmin.AccountEnabled,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	secretKeys := []string{"alicefirstadditional", "alicesecondadditional", "alicethirdadditional"}
+	for _, secretKey := range secretKeys {
+		if err = globalIAMSys.SetUserSecretKeyWithOpts(accessKey, secretKey, setUserSecretKeyOpts{appendKey: true}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	data := bytes.Repeat([]byte("a"), 100*1024)
+	for _, secretKey := range secretKeys {
+		req, err := newTestStreamingSignedRequest(http.MethodPut, "http://127.0.0.1:9000/bucket/object",
+			int64(len(data)), 64*1024, bytes.NewReader(data), accessKey, secretKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rc, errCode := newSignV4ChunkedReader(req)
+		if errCode != ErrNone {
+			t.Fatalf("%s: expected the seed signature to match, got %s", secretKey, niceError(errCode))
+		}
+		got, err := ioutil.ReadAll(rc)
+		if err != nil {
+			t.Fatalf("%s: expected the chunk signatures to match, got %v", secretKey, err)
+		}
+		if !bytes.Equal(got, data) {
+			t.Fatalf("%s: expected the object data to be read", secretKey)
+		}
+	}
+}
@@ -94,6 +94,7 @@ const (
 	ErrSTSMalformedPolicyDocument
 	ErrSTSNotInitialized
 	ErrSTSInternalError
+	ErrSTSTooManyRequests
 )
 
 type stsErrorCodeMap map[STSErrorCode]STSError
@@ -154,4 +155,9 @@ var stsErrCodes = stsErrorCodeMap{
 		Description:    "We encountered an internal error generating credentials, please try again.",
 		HTTPStatusCode: http.StatusInternalServerError,
 	},
+	ErrSTSTooManyRequests: {
+		Code:           "Throttling",
+		Description:    "Too many temporary credentials were requested, please slow down.",
+		HTTPStatusCode: http.StatusTooManyRequests,
+	},
 }
@@ -60,6 +60,10 @@ const (
 	// JWT claim to check the parent user
 	parentClaim = "parent"
 
+	// JWT claim restricting a service account to a subset of the
+	// policies of its parent user, comma separated.
+	saInheritedPoliciesClaim = "sa-inherited-policies"
+
 	// LDAP claim keys
 	ldapUser = "ldapUser"
 )
@@ -235,8 +239,8 @@ func (sts *stsAPIHandlers) AssumeRole(w http.ResponseWriter, r *http.Request) {
 	cred.ParentUser = user.AccessKey
 
 	// Set the newly generated credentials.
-	if err = globalIAMSys.SetTempUser(cred.AccessKey, cred, policyName); err != nil {
-		writeSTSErrorResponse(ctx, w, true, ErrSTSInternalError, err)
+	if cred.Expiration, err = globalIAMSys.SetTempUser(cred.AccessKey, cred, policyName); err != nil {
+		writeSTSErrorResponse(ctx, w, true, setTempUserErrCode(err), err)
 		return
 	}
 
@@ -376,8 +380,8 @@ func (sts *stsAPIHandlers) AssumeRoleWithSSO(w http.ResponseWriter, r *http.Requ
 	}
 
 	// Set the newly generated credentials.
-	if err = globalIAMSys.SetTempUser(cred.AccessKey, cred, policyName); err != nil {
-		writeSTSErrorResponse(ctx, w, true, ErrSTSInternalError, err)
+	if cred.Expiration, err = globalIAMSys.SetTempUser(cred.AccessKey, cred, policyName); err != nil {
+		writeSTSErrorResponse(ctx, w, true, setTempUserErrCode(err), err)
 		return
 	}
 
@@ -534,8 +538,8 @@ func (sts *stsAPIHandlers) AssumeRoleWithLDAPIdentity(w http.ResponseWriter, r *
 	// Set the newly generated credentials, policyName is empty on purpose
 	// LDAP policies are applied automatically using their ldapUser, ldapGroups
 	// mapping.
-	if err = globalIAMSys.SetTempUser(cred.AccessKey, cred, ""); err != nil {
-		writeSTSErrorResponse(ctx, w, true, ErrSTSInternalError, err)
+	if cred.Expiration, err = globalIAMSys.SetTempUser(cred.AccessKey, cred, ""); err != nil {
+		writeSTSErrorResponse(ctx, w, true, setTempUserErrCode(err), err)
 		return
 	}
 
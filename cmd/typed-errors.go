@@ -77,6 +77,16 @@ var errInvalidDecompressedSize = errors.New("Invalid Decompressed Size")
 // error returned in IAM subsystem when user doesn't exist.
 var errNoSuchUser = errors.New("Specified user does not exist")
 
+// error returned in IAM subsystem when the secret key of a user
+// doesn't match.
+var errInvalidSecret = errors.New("Specified secret key does not match")
+
+// error returned in IAM subsystem when credentials have expired.
+var errExpired = errors.New("Specified credentials have expired")
+
+// error returned in IAM subsystem when a user is disabled.
+var errAccountDisabled = errors.New("Specified user is disabled")
+
 // error returned when service account is not found
 var errNoSuchServiceAccount = errors.New("Specified service account does not exist")
 
@@ -87,12 +97,63 @@ var errNoSuchGroup = errors.New("Specified group does not exist")
 // deleted.
 var errGroupNotEmpty = errors.New("Specified group is not empty - cannot remove it")
 
+// error returned in IAM subsystem when a policy was updated by someone
+// else since the expected version was read.
+var errPolicyVersionConflict = errors.New("Specified canned policy was modified concurrently")
+
+// error returned in IAM subsystem when removing the policy mapping of a
+// user would leave it without any policy.
+var errLastPolicyMapping = errors.New("Specified user would be left without any policy")
+
+// error returned in IAM subsystem when temporary credentials would
+// replace other live credentials with the same access key.
+var errSTSAccessKeyConflict = errors.New("Specified access key is already in use by other credentials")
+
+// error returned in IAM subsystem when a canned policy has more
+// statements than allowed.
+var errPolicyTooManyStatements = errors.New("Specified canned policy has too many statements")
+
+// error returned in IAM subsystem when a parent user creates STS
+// credentials faster than allowed.
+var errSTSRateLimited = errors.New("Too many temporary credentials were requested for this user, please slow down")
+
+// error returned in IAM subsystem when a policy to delete is still
+// mapped to users or groups.
+var errPolicyInUse = errors.New("Specified canned policy is still in use")
+
+// error returned in IAM subsystem when a user already owns as many
+// buckets as its quota allows.
+var errUserBucketQuotaExceeded = errors.New("Specified user already owns as many buckets as its quota allows")
+
 // error returned in IAM subsystem when policy doesn't exist.
 var errNoSuchPolicy = errors.New("Specified canned policy does not exist")
 
 // error returned in IAM subsystem when an external users systems is configured.
 var errIAMActionNotAllowed = errors.New("Specified IAM action is not allowed with LDAP configuration")
 
+// error returned in IAM subsystem when an imported entry already
+// exists and overwriting was not requested.
+var errIAMEntryExists = errors.New("Specified IAM entry already exists")
+
+// error returned in IAM subsystem when the IAM store lock could not be
+// acquired in time.
+var errIAMLockTimeout = errors.New("Timed out waiting for the IAM store lock, please try again")
+
+// error returned in IAM subsystem by write methods on read-only nodes.
+var errIAMReadOnly = errors.New("IAM sub-system is read-only on this server")
+
+// error returned in IAM subsystem when reverse lookups of secret keys
+// are not enabled.
+var errIAMSecretLookupDisabled = errors.New("Secret key lookups are disabled, set MINIO_IAM_SECRET_LOOKUP=on to enable")
+
+// error returned by the IAM store when an IAM config item did not change
+// since it was last saved or loaded.
+var errIAMConfigNotModified = errors.New("IAM config item not modified")
+
+// error returned in IAM subsystem when credentials could not be loaded
+// because the IAM store is temporarily unavailable.
+var errIAMTemporarilyUnavailable = errors.New("IAM store is temporarily unavailable, please try again")
+
 // error returned in IAM subsystem when IAM sub-system is still being initialized.
 var errIAMNotInitialized = errors.New("IAM sub-system is being initialized, please try again")
 
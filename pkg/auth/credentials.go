@@ -100,6 +100,10 @@ type Credentials struct {
 	Status       string    `xml:"-" json:"status,omitempty"`
 	ParentUser   string    `xml:"-" json:"parentUser,omitempty"`
 	Groups       []string  `xml:"-" json:"groups,omitempty"`
+
+	// ServiceAccount is set for service accounts created with an
+	// expiration, which would otherwise look like temporary credentials.
+	ServiceAccount bool `xml:"-" json:"serviceAccount,omitempty"`
 }
 
 func (cred Credentials) String() string {
@@ -129,12 +133,18 @@ func (cred Credentials) IsExpired() bool {
 
 // IsTemp - returns whether credential is temporary or not.
 func (cred Credentials) IsTemp() bool {
+	if cred.ServiceAccount {
+		return false
+	}
 	return cred.SessionToken != "" && !cred.Expiration.IsZero() && !cred.Expiration.Equal(timeSentinel)
 }
 
 // IsServiceAccount - returns whether credential is a service account or not
 func (cred Credentials) IsServiceAccount() bool {
-	return cred.ParentUser != "" && (cred.Expiration.IsZero() || cred.Expiration.Equal(timeSentinel))
+	if cred.ParentUser == "" {
+		return false
+	}
+	return cred.ServiceAccount || cred.Expiration.IsZero() || cred.Expiration.Equal(timeSentinel)
 }
 
 // IsValid - returns whether credential is valid or not.
@@ -18,6 +18,7 @@ package auth
 
 import (
 	"encoding/json"
+	"strings"
 	"testing"
 	"time"
 )
@@ -80,6 +81,11 @@ func TestIsSecretKeyValid(t *testing.T) {
 		{alphaNumericTable[:secretKeyMinLen], true},
 		{alphaNumericTable[:secretKeyMinLen+1], true},
 		{alphaNumericTable[:secretKeyMinLen-1], false},
+		{"", false},
+		// There is no maximum length enforcement for secret keys.
+		{strings.Repeat("a", secretKeyMaxLen-1), true},
+		{strings.Repeat("a", secretKeyMaxLen), true},
+		{strings.Repeat("a", secretKeyMaxLen+1), true},
 	}
 
 	for i, testCase := range testCases {
@@ -179,3 +185,30 @@ func TestCredentialsEqual(t *testing.T) {
 		}
 	}
 }
+
+func TestCredentialsUserType(t *testing.T) {
+	expiry := time.Now().UTC().Add(time.Hour)
+	testCases := []struct {
+		cred             Credentials
+		isTemp           bool
+		isServiceAccount bool
+	}{
+		// Regular user.
+		{Credentials{AccessKey: "myuser", SecretKey: "mypassword", Expiration: timeSentinel}, false, false},
+		// Temporary credentials.
+		{Credentials{ParentUser: "myuser", SessionToken: "token", Expiration: expiry}, true, false},
+		// Service account without expiration.
+		{Credentials{ParentUser: "myuser", SessionToken: "token", Expiration: timeSentinel}, false, true},
+		// Service account with expiration.
+		{Credentials{ParentUser: "myuser", SessionToken: "token", Expiration: expiry, ServiceAccount: true}, false, true},
+	}
+
+	for i, testCase := range testCases {
+		if isTemp := testCase.cred.IsTemp(); isTemp != testCase.isTemp {
+			t.Fatalf("test %v: expected IsTemp: %v, got: %v", i+1, testCase.isTemp, isTemp)
+		}
+		if isSA := testCase.cred.IsServiceAccount(); isSA != testCase.isServiceAccount {
+			t.Fatalf("test %v: expected IsServiceAccount: %v, got: %v", i+1, testCase.isServiceAccount, isSA)
+		}
+	}
+}
@@ -138,6 +138,23 @@ func (iamp Policy) IsEmpty() bool {
 	return len(iamp.Statements) == 0
 }
 
+// Equals - checks whether two policies have the same ID, version and
+// statements in the same order.
+func (iamp Policy) Equals(p Policy) bool {
+	if iamp.ID != p.ID || iamp.Version != p.Version {
+		return false
+	}
+	if len(iamp.Statements) != len(p.Statements) {
+		return false
+	}
+	for i, st := range iamp.Statements {
+		if st.SID != p.Statements[i].SID || !st.Equals(p.Statements[i]) {
+			return false
+		}
+	}
+	return true
+}
+
 // isValid - checks if Policy is valid or not.
 func (iamp Policy) isValid() error {
 	if iamp.Version != DefaultVersion && iamp.Version != "" {
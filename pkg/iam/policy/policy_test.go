@@ -260,6 +260,49 @@ func TestPolicyIsEmpty(t *testing.T) {
 	}
 }
 
+func TestPolicyEquals(t *testing.T) {
+	newPolicy := func(actions ...Action) Policy {
+		return Policy{
+			Version: DefaultVersion,
+			Statements: []Statement{
+				NewStatement(
+					policy.Allow,
+					NewActionSet(actions...),
+					NewResourceSet(NewResource("mybucket", "/myobject*"), NewResource("yourbucket", "*")),
+					condition.NewFunctions(),
+				),
+			},
+		}
+	}
+
+	case1Policy := newPolicy(GetObjectAction, PutObjectAction, ListBucketAction)
+	case2Policy := newPolicy(ListBucketAction, PutObjectAction, GetObjectAction)
+	case3Policy := newPolicy(GetObjectAction, PutObjectAction)
+	case4Policy := newPolicy(GetObjectAction, PutObjectAction, ListBucketAction)
+	case4Policy.ID = "MyPolicyForMyBucket"
+	case5Policy := newPolicy(GetObjectAction, PutObjectAction, ListBucketAction)
+	case5Policy.Statements = append(case5Policy.Statements, case5Policy.Statements[0])
+
+	testCases := []struct {
+		policy         Policy
+		expectedResult bool
+	}{
+		{case1Policy, true},
+		{case2Policy, true},
+		{case3Policy, false},
+		{case4Policy, false},
+		{case5Policy, false},
+	}
+
+	for i, testCase := range testCases {
+		result := case1Policy.Equals(testCase.policy)
+
+		if result != testCase.expectedResult {
+			t.Fatalf("case %v: expected: %v, got: %v\n", i+1, testCase.expectedResult, result)
+		}
+	}
+}
+
 func TestPolicyIsValid(t *testing.T) {
 	case1Policy := Policy{
 		Version: DefaultVersion,
@@ -67,7 +67,10 @@ type GroupDesc struct {
 	Name    string   `json:"name"`
 	Status  string   `json:"status"`
 	Members []string `json:"members"`
-	Policy  string   `json:"policy"`
+	// Groups nested in this group, whose members are members
+	// of this group as well.
+	Groups []string `json:"groups,omitempty"`
+	Policy string   `json:"policy"`
 }
 
 // GetGroupDescription - fetches information on a group.
@@ -96,6 +96,19 @@ type UserInfo struct {
 	PolicyName string        `json:"policyName,omitempty"`
 	Status     AccountStatus `json:"status"`
 	MemberOf   []string      `json:"memberOf,omitempty"`
+
+	// Only set for temporary users and service accounts.
+	ParentUser     string `json:"parentUser,omitempty"`
+	EmbeddedPolicy bool   `json:"embeddedPolicy,omitempty"`
+}
+
+// IAMStats carries the number of IAM objects known to the server.
+type IAMStats struct {
+	Users           int `json:"users"`
+	STSUsers        int `json:"stsUsers"`
+	ServiceAccounts int `json:"serviceAccounts"`
+	Groups          int `json:"groups"`
+	Policies        int `json:"policies"`
 }
 
 // RemoveUser - remove a user.
@@ -420,6 +433,16 @@ type InfoServiceAccountResp struct {
 	Policy        string `json:"policy"`
 }
 
+// ServiceAccountInfo describes a service account without its secrets
+type ServiceAccountInfo struct {
+	ParentUser    string `json:"parentUser"`
+	AccessKey     string `json:"accessKey"`
+	AccountStatus string `json:"accountStatus"`
+	ImpliedPolicy bool   `json:"impliedPolicy"`
+
+	Tags map[string]string `json:"tags,omitempty"`
+}
+
 // InfoServiceAccount - returns the info of service account belonging to the specified user
 func (adm *AdminClient) InfoServiceAccount(ctx context.Context, accessKey string) (InfoServiceAccountResp, error) {
 	queryValues := url.Values{}